@@ -0,0 +1,71 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutsideRoot is returned when an output path isn't within
+// ExtractOptions.AllowedRoot
+var ErrOutsideRoot = errors.New("output path is outside the allowed root")
+
+// checkAllowedRoot fails with ErrOutsideRoot unless outputPath, with its
+// symlinks resolved, is within root
+func checkAllowedRoot(root, outputPath string) error {
+	resolvedRoot, err := resolvePath(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve allowed root: %w", err)
+	}
+	resolved, err := resolvePath(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output path: %w", err)
+	}
+	rel, err := filepath.Rel(resolvedRoot, resolved)
+	if err != nil || !filepath.IsLocal(rel) {
+		return fmt.Errorf("%w: %s resolves to %s, which is not under %s", ErrOutsideRoot, outputPath, resolved, resolvedRoot)
+	}
+	return nil
+}
+
+// resolvePath returns the absolute path that name refers to, resolving the
+// symlinks of the part of it that exists. Missing parts are appended as they
+// are, but a symlink to a missing file is followed, as creating the file
+// would. The path isn't cleaned before resolving, since a ".." after a
+// symlink leaves the link's target rather than the link.
+func resolvePath(name string) (string, error) {
+	if !filepath.IsAbs(name) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		name = wd + string(filepath.Separator) + name
+	}
+	resolved, err := filepath.EvalSymlinks(name)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	dir, base := filepath.Split(strings.TrimRight(name, string(filepath.Separator)))
+	if base == "" {
+		// A missing volume, as nothing else is left
+		return filepath.Clean(name), nil
+	}
+	if target, err := os.Readlink(dir + base); err == nil {
+		if !filepath.IsAbs(target) {
+			target = dir + target
+		}
+		return resolvePath(target)
+	}
+	parent, err := resolvePath(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, base), nil
+}
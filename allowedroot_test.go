@@ -0,0 +1,92 @@
+package tarix
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckAllowedRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(root, "sub"), filepath.Join(outside, "deep")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	for link, target := range map[string]string{
+		filepath.Join(root, "escape"):   "../outside",
+		filepath.Join(root, "evil.txt"): "../outside/evil.txt",
+		filepath.Join(root, "jump"):     filepath.Join(outside, "deep"),
+		filepath.Join(root, "inner"):    "sub",
+		filepath.Join(dir, "rootlink"):  "root",
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		root, output string
+		allowed      bool
+	}{
+		{root, filepath.Join(root, "a.txt"), true},
+		{root, filepath.Join(root, "sub", "a.txt"), true},
+		{root, filepath.Join(root, "missing", "dir", "a.txt"), true},
+		{root, filepath.Join(root, "inner", "a.txt"), true},
+		{root, filepath.Join(root, "sub", "..", "a.txt"), true},
+		{root + "/", filepath.Join(root, "a.txt"), true},
+		{filepath.Join(dir, "rootlink"), filepath.Join(root, "a.txt"), true},
+		{root, filepath.Join(dir, "rootlink", "a.txt"), true},
+		{root, filepath.Join(outside, "a.txt"), false},
+		{root, root + "/../outside/a.txt", false},
+		{root, filepath.Join(root, "escape", "a.txt"), false},
+		{root, filepath.Join(root, "escape", "missing", "a.txt"), false},
+		// Creating the file would follow the link out of the root
+		{root, filepath.Join(root, "evil.txt"), false},
+		// ".." leaves the target of the link, not the link
+		{root, root + "/jump/../a.txt", false},
+		{root, root + "-sibling/a.txt", false},
+	} {
+		err := checkAllowedRoot(tc.root, tc.output)
+		switch {
+		case tc.allowed && err != nil:
+			t.Errorf("Expected %s to be allowed under %s, got %v", tc.output, tc.root, err)
+		case !tc.allowed && !errors.Is(err, ErrOutsideRoot):
+			t.Errorf("Expected ErrOutsideRoot for %s under %s, got %v", tc.output, tc.root, err)
+		}
+	}
+}
+
+func TestExtractAllowedRoot(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "a"})
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{root, outside} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	opts := ExtractOptions{AllowedRoot: root}
+
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "a.txt", filepath.Join(root, "a.txt"), opts); err != nil {
+		t.Fatalf("Failed to extract within the allowed root: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "a.txt")); err != nil || string(data) != "a" {
+		t.Errorf("Unexpected output: %q, %v", data, err)
+	}
+
+	err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "a.txt", filepath.Join(root, "escape", "a.txt"), opts)
+	if !errors.Is(err, ErrOutsideRoot) {
+		t.Fatalf("Expected ErrOutsideRoot, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "a.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected nothing written outside the root, got %v", err)
+	}
+}
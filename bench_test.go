@@ -0,0 +1,121 @@
+package tarix
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// benchShape is a synthetic archive of many small files, where per-entry
+// overhead dominates
+var benchShape = TarShape{Files: 10000, FileSize: 1024, FilesPerDir: 100, Seed: 1}
+
+// writeBenchTar writes a synthetic TAR of shape and its index to a temporary
+// directory, returning their paths and the paths of the files in the TAR
+func writeBenchTar(b *testing.B, shape TarShape) (string, string, []string) {
+	b.Helper()
+
+	tarFilePath := filepath.Join(b.TempDir(), "bench.tar")
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		b.Fatalf("Failed to create TAR: %v", err)
+	}
+	paths, err := WriteSyntheticTar(tarFile, shape)
+	tarFile.Close()
+	if err != nil {
+		b.Fatalf("Failed to write synthetic TAR: %v", err)
+	}
+
+	tarIndexPath := tarFilePath + ".index.json"
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		b.Fatalf("Failed to create TAR index: %v", err)
+	}
+	return tarFilePath, tarIndexPath, paths
+}
+
+func BenchmarkCreateTarIndex(b *testing.B) {
+	tarFilePath, tarIndexPath, _ := writeBenchTar(b, benchShape)
+	info, err := os.Stat(tarFilePath)
+	if err != nil {
+		b.Fatalf("Failed to stat TAR: %v", err)
+	}
+
+	b.SetBytes(info.Size())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+			b.Fatalf("Failed to create TAR index: %v", err)
+		}
+	}
+}
+
+func BenchmarkReadTarIndex(b *testing.B) {
+	_, tarIndexPath, _ := writeBenchTar(b, benchShape)
+
+	for _, bm := range []struct {
+		name string
+		opts LoadOptions
+	}{
+		{"map", LoadOptions{}},
+		{"sorted", LoadOptions{Sorted: true}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ReadTarIndexWithOptions(tarIndexPath, bm.opts); err != nil {
+					b.Fatalf("Failed to read index: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExtractBytesOfFile(b *testing.B) {
+	tarFilePath, tarIndexPath, paths := writeBenchTar(b, benchShape)
+
+	for _, bm := range []struct {
+		name string
+		opts HandleOptions
+	}{
+		{"pread", HandleOptions{}},
+		{"mmap", HandleOptions{UseMmap: true}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			th, err := NewTarixHandleWithOptions(tarFilePath, tarIndexPath, bm.opts)
+			if err != nil {
+				b.Fatalf("Failed to open handle: %v", err)
+			}
+			defer th.Close()
+
+			b.SetBytes(benchShape.FileSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Stride through the archive so lookups don't hit neighbouring entries
+				p := paths[(i*7919)%len(paths)]
+				if _, err := th.ExtractBytesOfFile(p); err != nil {
+					b.Fatalf("Failed to extract %s: %v", p, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExtractManyConcurrency(b *testing.B) {
+	// Fewer, larger files than benchShape, so writing them dominates
+	shape := TarShape{Files: 200, FileSize: 256 * 1024, FilesPerDir: 50, Seed: 1}
+	tarFilePath, tarIndexPath, paths := writeBenchTar(b, shape)
+
+	for _, concurrency := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			b.SetBytes(shape.FileSize * int64(shape.Files))
+			for i := 0; i < b.N; i++ {
+				outputDir := b.TempDir()
+				if err := ExtractMany(tarFilePath, tarIndexPath, paths, outputDir, ExtractOptions{Concurrency: concurrency}); err != nil {
+					b.Fatalf("Failed to extract: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package tarix
+
+import (
+	"archive/tar"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// defaultCheckpointEvery is how many files are indexed between checkpoints
+// when resuming without IndexOptions.CheckpointEvery
+const defaultCheckpointEvery = 1000
+
+// checkpoint records how far an interrupted CreateTarIndexWithOptions got.
+// The files indexed up to Offset are in the partial index, whose first
+// PartialSize bytes were flushed when the checkpoint was taken.
+type checkpoint struct {
+	TarSize     int64      `json:"tar_size"`
+	Offset      int64      `json:"offset"`
+	Files       int        `json:"files"`
+	Entries     int64      `json:"entries"`
+	PartialSize int64      `json:"partial_size"`
+	Formats     tar.Format `json:"formats"`
+}
+
+// checkpointPath returns where the checkpoint of the index at indexPath is kept
+func checkpointPath(indexPath string) string {
+	return indexPath + ".checkpoint"
+}
+
+// partialPath returns where the partial index of the index at indexPath is kept
+func partialPath(indexPath string) string {
+	return indexPath + ".partial"
+}
+
+// readCheckpoint loads the checkpoint left for indexPath
+func readCheckpoint(indexPath string) (*checkpoint, error) {
+	data, err := os.ReadFile(checkpointPath(indexPath))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no checkpoint to resume from for %s: %w", indexPath, err)
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	cp := &checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// checkpointer appends indexed files to the partial index and periodically
+// saves a checkpoint
+type checkpointer struct {
+	indexPath string
+	every     int
+	tarSize   int64
+	file      *os.File
+	cw        *countingWriter
+	writer    *csv.Writer
+	pending   int
+}
+
+// newCheckpointer starts a partial index for indexPath, or when resuming from
+// cp, cuts the partial index back to the checkpoint and loads its files into index
+func newCheckpointer(indexPath string, every int, tarSize int64, cp *checkpoint, index *TarIndex) (*checkpointer, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if cp != nil {
+		flags = os.O_WRONLY
+	} else if err := os.Remove(checkpointPath(indexPath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// A checkpoint of an earlier run doesn't match the new partial index
+		return nil, fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	file, err := os.OpenFile(partialPath(indexPath), flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open partial index: %w", err)
+	}
+
+	c := &checkpointer{indexPath: indexPath, every: every, tarSize: tarSize, file: file}
+	if cp != nil {
+		// Rows written after the checkpoint may be incomplete
+		if err := file.Truncate(cp.PartialSize); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to truncate partial index: %w", err)
+		}
+		if err := loadPartial(partialPath(indexPath), index); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if len(index.Files) != cp.Files {
+			file.Close()
+			return nil, fmt.Errorf("partial index has %d files, checkpoint expects %d", len(index.Files), cp.Files)
+		}
+		if _, err := file.Seek(cp.PartialSize, io.SeekStart); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to seek partial index: %w", err)
+		}
+	}
+	c.cw = &countingWriter{w: file}
+	if cp != nil {
+		c.cw.n = cp.PartialSize
+	}
+	c.writer = csv.NewWriter(c.cw)
+	return c, nil
+}
+
+// loadPartial reads the rows of a partial index into index
+func loadPartial(partial string, index *TarIndex) error {
+	file, err := os.Open(partial)
+	if err != nil {
+		return fmt.Errorf("failed to open partial index: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	// Partial indexes of older versions have no MIME type, non-extractable or
+	// sparse field
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read partial index: %w", err)
+		}
+		if err := unescapeFields(record); err != nil {
+			return err
+		}
+		if len(record) < 7 || len(record) > 10 {
+			return fmt.Errorf("failed to read partial index: record has %d fields", len(record))
+		}
+		start, err := parseInt64(record[1])
+		if err != nil {
+			return fmt.Errorf("invalid start value: %w", err)
+		}
+		size, err := parseInt64(record[2])
+		if err != nil {
+			return fmt.Errorf("invalid size value: %w", err)
+		}
+		compressed, err := strconv.ParseBool(record[5])
+		if err != nil {
+			return fmt.Errorf("invalid compressed value: %w", err)
+		}
+		headerBlocks, err := strconv.Atoi(record[6])
+		if err != nil {
+			return fmt.Errorf("invalid headers value: %w", err)
+		}
+		fileInfo := FileIndex{Start: start, Size: size, Path: record[3], ContentHash: record[4], Compressed: compressed, HeaderBlocks: headerBlocks}
+		if len(record) >= 8 {
+			fileInfo.MIMEType = record[7]
+		}
+		if len(record) >= 9 {
+			if fileInfo.NonExtractable, err = strconv.ParseBool(record[8]); err != nil {
+				return fmt.Errorf("invalid nonextractable value: %w", err)
+			}
+		}
+		if len(record) == 10 {
+			if fileInfo.Sparse, err = strconv.ParseBool(record[9]); err != nil {
+				return fmt.Errorf("invalid sparse value: %w", err)
+			}
+		}
+		index.Files[record[0]] = fileInfo
+	}
+}
+
+// add appends the indexed file of p, and saves a checkpoint at p.offset, where
+// the next entry starts, every c.every files
+func (c *checkpointer) add(fileInfo FileIndex, p pendingFile) error {
+	record := []string{p.key, strconv.FormatInt(fileInfo.Start, 10), strconv.FormatInt(fileInfo.Size, 10), fileInfo.Path, fileInfo.ContentHash, strconv.FormatBool(fileInfo.Compressed), strconv.Itoa(fileInfo.HeaderBlocks), fileInfo.MIMEType, strconv.FormatBool(fileInfo.NonExtractable), strconv.FormatBool(fileInfo.Sparse)}
+	if err := c.writer.Write(escapeFields(record)); err != nil {
+		return fmt.Errorf("failed to write partial index: %w", err)
+	}
+	c.pending++
+	if c.pending < c.every {
+		return nil
+	}
+	c.pending = 0
+
+	c.writer.Flush()
+	if err := c.writer.Error(); err != nil {
+		return fmt.Errorf("failed to write partial index: %w", err)
+	}
+	if err := c.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync partial index: %w", err)
+	}
+
+	data, err := json.Marshal(checkpoint{
+		TarSize:     c.tarSize,
+		Offset:      p.offset,
+		Files:       p.files,
+		Entries:     p.entries,
+		PartialSize: c.cw.n,
+		Formats:     p.formats,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	// Replace the checkpoint atomically, so an interruption leaves the old one
+	tmpPath := checkpointPath(c.indexPath) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmpPath, checkpointPath(c.indexPath)); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// finish removes the partial index and the checkpoint once the index is complete
+func (c *checkpointer) finish() error {
+	c.file.Close()
+	if err := os.Remove(partialPath(c.indexPath)); err != nil {
+		return fmt.Errorf("failed to remove partial index: %w", err)
+	}
+	if err := os.Remove(checkpointPath(c.indexPath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// close closes the partial index, keeping it for a later resume
+func (c *checkpointer) close() {
+	c.file.Close()
+}
@@ -0,0 +1,81 @@
+package tarix
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResumeTarIndex(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "resume.tar")
+	files := map[string]string{}
+	for i := 0; i < 7; i++ {
+		files[fmt.Sprintf("file%d.txt", i)] = fmt.Sprintf("content %d", i)
+	}
+	writeTestTar(t, tarFilePath, files)
+
+	// Interrupt indexing after the fifth file, two files past the last checkpoint
+	tarIndexPath := filepath.Join(dir, "resume.tar.index.json")
+	opts := IndexOptions{CheckpointEvery: 3, ContentHash: true}
+	interrupted := opts
+	interrupted.Progress = func(p Progress) {
+		if p.FilesDone == 5 {
+			panic("interrupted")
+		}
+	}
+	func() {
+		defer func() { recover() }()
+		CreateTarIndexWithOptions(tarFilePath, tarIndexPath, interrupted)
+	}()
+
+	if _, err := os.Stat(tarIndexPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected no index after the interruption, got %v", err)
+	}
+	cp, err := readCheckpoint(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read checkpoint: %v", err)
+	}
+	if cp.Files != 3 {
+		t.Errorf("Expected a checkpoint after 3 files, got %d", cp.Files)
+	}
+
+	if err := ResumeTarIndex(tarFilePath, tarIndexPath, opts); err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+	for _, leftover := range []string{checkpointPath(tarIndexPath), partialPath(tarIndexPath)} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", leftover, err)
+		}
+	}
+
+	// The resumed index must match one built in a single run
+	fullIndexPath := filepath.Join(dir, "full.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, fullIndexPath, IndexOptions{ContentHash: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	resumed, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read resumed index: %v", err)
+	}
+	full, err := ReadTarIndex(fullIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read full index: %v", err)
+	}
+	if len(resumed.Files) != len(full.Files) {
+		t.Fatalf("Expected %d files, got %d", len(full.Files), len(resumed.Files))
+	}
+	for key, fi := range full.Files {
+		if resumed.Files[key] != fi {
+			t.Errorf("Entry %s differs: resumed %+v, full %+v", key, resumed.Files[key], fi)
+		}
+	}
+	if resumed.EntryCount != full.EntryCount || resumed.ArchiveSize != full.ArchiveSize {
+		t.Errorf("Expected %d entries in %d bytes, got %d in %d", full.EntryCount, full.ArchiveSize, resumed.EntryCount, resumed.ArchiveSize)
+	}
+
+	if err := ResumeTarIndex(tarFilePath, tarIndexPath, opts); err == nil {
+		t.Error("Expected error resuming without a checkpoint")
+	}
+}
@@ -1,50 +1,261 @@
 package main
 
 import (
+	"archive/tar"
+	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/t0mk/tarix"
 )
 
 func main() {
+	// A leading -quiet silences informational output; errors still go to stderr
+	var info io.Writer = os.Stdout
+	logLevel := slog.LevelInfo
+	if len(os.Args) > 1 && (os.Args[1] == "-quiet" || os.Args[1] == "--quiet") {
+		info = io.Discard
+		logLevel = slog.LevelWarn
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	// Library messages go to stderr, so they don't mix with file data on stdout
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
 	// Command line flags for Index command
 	indexCmd := flag.NewFlagSet("index", flag.ExitOnError)
 	indexTarPath := indexCmd.String("tar", "", "TAR file to index")
 	indexOutputPath := indexCmd.String("output", "", "Output index file (default: <tar>.index.json)")
+	indexInclude := indexCmd.String("include", "", "Comma-separated glob patterns of files to index (default: all)")
+	indexExclude := indexCmd.String("exclude", "", "Comma-separated glob patterns of files to skip")
+	indexRoot := indexCmd.String("root", "", "Leading directory to strip from archive paths before hashing")
+	indexChecksum := indexCmd.Bool("checksum", false, "Record a SHA-256 of each file's content (reads all data)")
+	indexMIME := indexCmd.Bool("mime", false, "Record each file's MIME type, sniffed from its first 512 bytes (reads the start of every file)")
+	indexDedup := indexCmd.Bool("dedup", false, "Point files with identical content at a single copy (implies -checksum)")
+	indexKeys := indexCmd.String("keys", "md5", "Key scheme of the index: 'md5' (truncated MD5 of the path), 'sha256' or 'path'")
+	indexPaths := indexCmd.Bool("paths", false, "Store file paths in the index so listing doesn't need the TAR")
+	indexPathPrefixes := indexCmd.Bool("path-prefixes", false, "Store directories shared by several paths once, shrinking indexes of deep trees (implies -paths)")
+	indexNormalize := indexCmd.String("normalize", "", "Unicode form to put paths in before keying them, 'nfc' (default) or 'nfd', so composed and decomposed names find the same file, or 'none' to key them as they are")
+	indexOccurrences := indexCmd.Bool("occurrences", false, "Key files by path and occurrence (path#0, path#1, ...) to reach every copy of paths stored more than once")
+	indexConcatenated := indexCmd.Bool("concatenated", false, "Keep indexing past end-of-archive markers, for TARs concatenated with cat")
+	indexSkipBad := indexCmd.Bool("skip-bad", false, "Skip unreadable entries instead of aborting, producing a partial index")
+	indexHashWorkers := indexCmd.Int("hash-workers", 0, "Goroutines computing checksums with -checksum or -dedup (default: one per CPU)")
+	indexCheckpoint := indexCmd.Int("checkpoint", 0, "Save a checkpoint every N files so an interrupted run can be resumed with -resume")
+	indexResume := indexCmd.Bool("resume", false, "Continue an interrupted run from its last checkpoint (pass the same flags)")
+	indexByOffset := indexCmd.Bool("by-offset", false, "Write index rows in archive order instead of key order, for sequential extraction by tools reading the CSV")
+	indexMaxEntries := indexCmd.Int64("max-entries", 0, "Abort if the archive has more than N entries, to bound memory on untrusted archives (default: unlimited)")
+	indexDelimiter := indexCmd.String("delimiter", ",", "Field delimiter of the index file ('tab' or '\\t' for tab)")
+	indexVerbose := indexCmd.Bool("verbose", false, "Print each file as it's indexed with its offset and size")
 
 	// Command line flags for Extract command
 	extractCmd := flag.NewFlagSet("extract", flag.ExitOnError)
 	extractTarPath := extractCmd.String("tar", "", "TAR file to extract from")
 	extractIndexPath := extractCmd.String("index", "", "Index file for the TAR")
-	extractFile := extractCmd.String("file", "", "File path to extract from the TAR")
-	extractOutput := extractCmd.String("output", "", "Output file (default: extracted in current dir, '-' for stdout)")
+	var extractFiles stringList
+	extractCmd.Var(&extractFiles, "file", "File path to extract from the TAR; repeat to extract several files under -output-dir")
+	extractOutput := extractCmd.String("output", "", "Output file of a single -file (default: the file path under the current dir, '-' for stdout)")
+	extractOutputDir := extractCmd.String("output-dir", ".", "Directory to extract several files into, recreating their paths")
+	extractFlatten := extractCmd.Bool("flatten", false, "Default the output to the file's base name instead of its path")
+	extractRoot := extractCmd.String("root", "", "Archive directory the file path is relative to (default: the index's -root)")
+	extractVerify := extractCmd.Bool("verify", false, "Check the TAR header at the indexed offset before extracting")
+	extractBufferSize := extractCmd.Int("buffer-size", 0, "Size in bytes of the copy buffer (default: 32KB)")
+	extractNoClobber := extractCmd.Bool("no-clobber", false, "Leave output files that already exist untouched instead of overwriting them")
+	extractNoLock := extractCmd.Bool("no-lock", false, "Don't take a shared lock on the TAR, for filesystems without flock support")
+	extractSparse := extractCmd.Bool("sparse", false, "Leave holes in sparse files instead of writing their zeros")
+	extractNoSpecial := extractCmd.Bool("no-special", false, "Fail if the output is an existing FIFO or device instead of writing to it")
+	extractAutoReindex := extractCmd.Bool("auto-reindex", false, "If the index is stale, index the TAR again and retry once")
+	extractReplaceIndex := extractCmd.Bool("replace-index", false, "With -auto-reindex, write the new index over the stale one")
+	extractReadPattern := extractCmd.String("read-pattern", "", "Hint how the TAR is read to the OS, 'sequential' or 'random', where posix_fadvise is available (default: no hint)")
+	extractAllowedRoot := extractCmd.String("allowed-root", "", "Refuse a -output that isn't within this directory once its symlinks are resolved")
+
+	// Command line flags for ExtractAll command
+	extractallCmd := flag.NewFlagSet("extractall", flag.ExitOnError)
+	extractallTarPath := extractallCmd.String("tar", "", "TAR file to extract from")
+	extractallIndexPath := extractallCmd.String("index", "", "Index file for the TAR")
+	extractallOutputDir := extractallCmd.String("output-dir", ".", "Directory to extract files into")
+	extractallRoot := extractallCmd.String("root", "", "Archive directory to extract files relative to (default: the index's -root)")
+	extractallBufferSize := extractallCmd.Int("buffer-size", 0, "Size in bytes of the copy buffer (default: 32KB)")
+	extractallNoClobber := extractallCmd.Bool("no-clobber", false, "Leave output files that already exist untouched instead of overwriting them")
+	extractallSparse := extractallCmd.Bool("sparse", false, "Leave holes in sparse files instead of writing their zeros")
+	extractallPreserveOwner := extractallCmd.Bool("preserve-owner", false, "Give files the numeric owners stored in the TAR, like tar --same-owner (needs root; otherwise files stay owned by the current user)")
+	extractallConcurrency := extractallCmd.Int("concurrency", 0, "Number of files to write at once (default: GOMAXPROCS)")
+	extractallReadPattern := extractallCmd.String("read-pattern", "", "Hint how the TAR is read to the OS, 'sequential' or 'random', where posix_fadvise is available (default: no hint)")
+
+	// Command line flags for Explode command
+	explodeCmd := flag.NewFlagSet("explode", flag.ExitOnError)
+	explodeTarPath := explodeCmd.String("tar", "", "TAR file to recreate")
+	explodeIndexPath := explodeCmd.String("index", "", "Index file for the TAR")
+	explodeOutputDir := explodeCmd.String("output-dir", ".", "Directory to recreate the archive in")
+	explodeRoot := explodeCmd.String("root", "", "Archive directory to recreate relative to (default: the index's -root)")
+	explodeNoClobber := explodeCmd.Bool("no-clobber", false, "Leave output files that already exist untouched instead of overwriting them")
+	explodeSparse := explodeCmd.Bool("sparse", false, "Leave holes in sparse files instead of writing their zeros")
+	explodePreserveOwner := explodeCmd.Bool("preserve-owner", false, "Give files the numeric owners stored in the TAR, like tar --same-owner (needs root; otherwise files stay owned by the current user)")
+	explodeUnsafeLinks := explodeCmd.Bool("unsafe-links", false, "Recreate symbolic links with absolute targets or targets outside of -output-dir, for trusted archives")
+
+	// Command line flags for Extract-top command
+	extractTopCmd := flag.NewFlagSet("extract-top", flag.ExitOnError)
+	extractTopTarPath := extractTopCmd.String("tar", "", "TAR file to extract from")
+	extractTopIndexPath := extractTopCmd.String("index", "", "Index file for the TAR")
+	extractTopN := extractTopCmd.Int("n", 10, "Number of files to extract, largest first")
+	extractTopOutputDir := extractTopCmd.String("output-dir", ".", "Directory to extract files into")
+	extractTopNoClobber := extractTopCmd.Bool("no-clobber", false, "Leave output files that already exist untouched instead of overwriting them")
+	extractTopConcurrency := extractTopCmd.Int("concurrency", 0, "Number of files to write at once (default: GOMAXPROCS)")
 
 	printfrompathCmd := flag.NewFlagSet("printfrompath", flag.ExitOnError)
 	printfrompathTarPath := printfrompathCmd.String("tar", "", "TAR file to extract from")
-	printfrompathIndexPath := printfrompathCmd.String("index", "", "Index file for the TAR")
+	printfrompathIndexPath := printfrompathCmd.String("index", "", "Index file for the TAR (default: none, scan the TAR for -file)")
 	printfrompathFilePath := printfrompathCmd.String("file", "", "File path to extract from the TAR")
+	printfrompathKey := printfrompathCmd.String("key", "", "Index key to extract (alternative to -file)")
+	printfrompathRoot := printfrompathCmd.String("root", "", "Archive directory the file path is relative to")
+	printfrompathOccurrence := printfrompathCmd.Int("occurrence", -1, "Occurrence of the file to print, from 0, for indexes built with -occurrences")
+	printfrompathVerify := printfrompathCmd.Bool("verify", false, "Check the TAR header at the indexed offset before reading")
+	printfrompathNoLock := printfrompathCmd.Bool("no-lock", false, "Don't take a shared lock on the TAR, for filesystems without flock support")
+	printfrompathPartial := printfrompathCmd.Bool("partial", false, "Print what is left of a file the TAR was truncated in, then report the truncation")
+
+	// Command line flags for Cat command
+	catCmd := flag.NewFlagSet("cat", flag.ExitOnError)
+	catTarPath := catCmd.String("tar", "", "TAR file to read from")
+	catIndexPath := catCmd.String("index", "", "Index file for the TAR")
+	catFiles := catCmd.String("files", "", "Comma-separated file paths to print, in order")
+	catSeparator := catCmd.String("separator", "", "Printed between files; Go escapes like \\n are interpreted")
+	catRoot := catCmd.String("root", "", "Archive directory the file paths are relative to")
+	catVerify := catCmd.Bool("verify", false, "Check the TAR header at the indexed offset before reading")
+	catNoLock := catCmd.Bool("no-lock", false, "Don't take a shared lock on the TAR, for filesystems without flock support")
+
+	// Command line flags for Filter command
+	filterCmd := flag.NewFlagSet("filter", flag.ExitOnError)
+	filterTarPath := filterCmd.String("tar", "", "TAR file to copy files from")
+	filterIndexPath := filterCmd.String("index", "", "Index file for the TAR")
+	filterFiles := filterCmd.String("files", "", "Comma-separated file paths to copy")
+	filterOutputPath := filterCmd.String("output", "", "TAR file to write ('-' for stdout)")
+
+	// Command line flags for Merge command
+	mergeCmd := flag.NewFlagSet("merge", flag.ExitOnError)
+	mergeIndexPaths := mergeCmd.String("index", "", "Comma-separated index files, in the order the TARs were concatenated")
+	mergeTarPaths := mergeCmd.String("tar", "", "Comma-separated TAR files matching -index, used to compute offsets")
+	mergeSizes := mergeCmd.String("sizes", "", "Comma-separated TAR sizes in bytes, alternative to -tar")
+	mergeOutputPath := mergeCmd.String("output", "", "Output index file for the concatenated TAR")
 
 	// Command line flags for List command
 	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
 	listIndexPath := listCmd.String("index", "", "Index file to list")
+	listTarPath := listCmd.String("tar", "", "TAR file to scan for original paths (slower)")
+	listMinSize := listCmd.String("min-size", "", "List only files of at least this size, like 10MB or 1GiB")
+	listMaxSize := listCmd.String("max-size", "", "List only files of at most this size, like 10MB or 1GiB")
+	listJSON := listCmd.Bool("json", false, "Print the files as a JSON array of {path, key, start, size}")
+
+	// Command line flags for Stats command
+	statsCmd := flag.NewFlagSet("stats", flag.ExitOnError)
+	statsIndexPath := statsCmd.String("index", "", "Index file to summarize")
+	statsTop := statsCmd.Int("top", 10, "Number of extensions to show, by total size (needs an index built with -paths)")
+	statsCoverage := statsCmd.Bool("coverage", false, "Report how much of the TAR the index covers and list the byte ranges between indexed files")
+	statsTarPath := statsCmd.String("tar", "", "TAR file whose size -coverage is computed against (default: the size recorded in the index)")
+
+	// Command line flags for Compact command
+	compactCmd := flag.NewFlagSet("compact", flag.ExitOnError)
+	compactIndexPath := compactCmd.String("index", "", "Index file to rewrite without duplicate rows and rows past the end of the archive")
+
+	// Command line flags for Convert-index command
+	convertIndexCmd := flag.NewFlagSet("convert-index", flag.ExitOnError)
+	convertIndexIn := convertIndexCmd.String("in", "", "Index file to convert, CSV or JSON")
+	convertIndexOut := convertIndexCmd.String("out", "", "Output index file, in JSON for a CSV -in and in CSV for a JSON one")
+
+	// Command line flags for Dups command
+	dupsCmd := flag.NewFlagSet("dups", flag.ExitOnError)
+	dupsIndexPath := dupsCmd.String("index", "", "Index file to find files with identical content in (built with -checksum or -dedup)")
+
+	// Command line flags for Contains command
+	containsCmd := flag.NewFlagSet("contains", flag.ExitOnError)
+	containsIndexPath := containsCmd.String("index", "", "Index file to look the file up in")
+	containsFile := containsCmd.String("file", "", "File path to look up")
+	containsVerbose := containsCmd.Bool("v", false, "Print whether the file is in the index")
+
+	// Command line flags for Offset command
+	offsetCmd := flag.NewFlagSet("offset", flag.ExitOnError)
+	offsetIndexPath := offsetCmd.String("index", "", "Index file to look the file up in")
+	offsetFile := offsetCmd.String("file", "", "File path to look up")
+	offsetJSON := offsetCmd.Bool("json", false, "Print {path, offset, size} as JSON")
+
+	// Command line flags for Diff command
+	diffCmd := flag.NewFlagSet("diff", flag.ExitOnError)
+	diffOldPath := diffCmd.String("old", "", "Index of the old archive")
+	diffNewPath := diffCmd.String("new", "", "Index of the new archive")
+
+	compareIndexCmd := flag.NewFlagSet("compare-index", flag.ExitOnError)
+	compareIndexCSV := compareIndexCmd.String("csv", "", "CSV index of the archive")
+	compareIndexJSON := compareIndexCmd.String("json", "", "JSON index of the same archive")
+
+	// Command line flags for Collisions command
+	collisionsCmd := flag.NewFlagSet("collisions", flag.ExitOnError)
+	collisionsTarPath := collisionsCmd.String("tar", "", "TAR file whose paths to check")
+	collisionsHash := collisionsCmd.String("hash", "md5", "Hash of the paths: 'md5' or 'sha256'")
+	collisionsKeyLen := collisionsCmd.Int("keylen", tarix.HashLen, "Hex characters the keys are truncated to (0: the full digest)")
+
+	// Command line flags for Serve command
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	serveTarPath := serveCmd.String("tar", "", "TAR file to serve files from")
+	serveIndexPath := serveCmd.String("index", "", "Index file for the TAR")
+	serveAddr := serveCmd.String("addr", "localhost:8080", "Address to listen on")
+	serveRoot := serveCmd.String("root", "", "Archive directory URL paths are relative to")
+	serveAccessLog := serveCmd.Bool("access-log", false, "Log the path, bytes served and status of each request")
+
+	// Command line flags for Migrate command
+	migrateCmd := flag.NewFlagSet("migrate", flag.ExitOnError)
+	migrateTarPath := migrateCmd.String("tar", "", "TAR file the index was built from, read for the paths of its keys")
+	migrateIndexPath := migrateCmd.String("index", "", "Index file to migrate")
+	migrateOutputPath := migrateCmd.String("output", "", "Output index file")
+	migrateKeys := migrateCmd.String("keys", "sha256", "Key scheme of the new index: 'md5', 'sha256' or 'path'")
+
+	// Command line flags for Verify command
+	verifyCmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	verifyTarPath := verifyCmd.String("tar", "", "TAR file to check")
+	verifyIndexPath := verifyCmd.String("index", "", "Index file to check the TAR against")
+	verifyDeep := verifyCmd.Bool("deep", false, "Also re-hash the content of files with stored checksums (reads all their data)")
 
 	// Check if command line arguments were provided
 	if len(os.Args) < 2 {
-		fmt.Println("Expected 'index', 'extract', 'printfrompath' or 'list' command")
-		fmt.Println("Usage:")
-		fmt.Println("  index -tar <tar-file> -output <index-file>")
-		fmt.Println("  extract -tar <tar-file> -index <index-file> -file <file-path> -output <output-file>")
-		fmt.Println("  list -index <index-file>")
-		fmt.Println("  printfrompath -tar <tar-file> -index <index-file> -file <file-path>")
+		fmt.Println("Expected 'index', 'extract', 'extractall', 'explode', 'extract-top', 'printfrompath', 'cat', 'filter', 'merge', 'list', 'contains', 'offset', 'stats', 'dups', 'compact', 'convert-index', 'diff', 'compare-index', 'verify', 'migrate', 'serve' or 'collisions' command")
+		fmt.Println("Usage: tarix [-quiet] <command> [flags]")
+		fmt.Println("  index -tar <tar-file> -output <index-file> [-include <globs>] [-exclude <globs>] [-root <dir>]")
+		fmt.Println("  extract -tar <tar-file> -index <index-file> -file <file-path> [-file <file-path> ... -output-dir <dir>] [-output <output-file>] [-flatten] [-no-clobber] [-sparse] [-no-special]")
+		fmt.Println("  extractall -tar <tar-file> -index <index-file> -output-dir <dir> [-no-clobber] [-sparse] [-preserve-owner] [-concurrency <n>]")
+		fmt.Println("  explode -tar <tar-file> -index <index-file> -output-dir <dir> [-no-clobber] [-sparse] [-preserve-owner] [-unsafe-links]")
+		fmt.Println("  extract-top -tar <tar-file> -index <index-file> [-n <n>] -output-dir <dir> [-no-clobber] [-concurrency <n>]")
+		fmt.Println("  filter -tar <tar-file> -index <index-file> -files <file-paths> -output <tar-file>")
+		fmt.Println("  merge -index <index-files> -tar <tar-files>|-sizes <sizes> -output <index-file>")
+		fmt.Println("  list -index <index-file> [-tar <tar-file>] [-min-size <size>] [-max-size <size>] [-json]")
+		fmt.Println("  contains -index <index-file> -file <file-path> [-v]")
+		fmt.Println("  offset -index <index-file> -file <file-path> [-json]")
+		fmt.Println("  stats -index <index-file> [-top <n>] [-coverage [-tar <tar-file>]]")
+		fmt.Println("  dups -index <index-file>")
+		fmt.Println("  compact -index <index-file>")
+		fmt.Println("  convert-index -in <index-file> -out <index-file>")
+		fmt.Println("  diff -old <index-file> -new <index-file>")
+		fmt.Println("  compare-index -csv <index-file> -json <index-file>")
+		fmt.Println("  verify -tar <tar-file> -index <index-file> [-deep]")
+		fmt.Println("  migrate -tar <tar-file> -index <index-file> -output <index-file> [-keys <scheme>]")
+		fmt.Println("  serve -tar <tar-file> -index <index-file> [-addr <host:port>] [-root <dir>] [-access-log]")
+		fmt.Println("  collisions -tar <tar-file> [-hash <md5|sha256>] [-keylen <n>]")
+		fmt.Println("  printfrompath -tar <tar-file> [-index <index-file>] -file <file-path>|-key <key> [-partial]")
+		fmt.Println("  cat -tar <tar-file> -index <index-file> -files <file-paths> [-separator <s>]")
+		fmt.Println("-tar and -index default to $TARIX_TAR and $TARIX_INDEX (not for merge)")
 		os.Exit(1)
 	}
 
 	switch os.Args[1] {
 	case "index":
 		indexCmd.Parse(os.Args[2:])
+		flagsFromEnv(indexCmd, os.Getenv, "tar")
 		if *indexTarPath == "" {
 			fmt.Println("TAR file is required")
 			indexCmd.PrintDefaults()
@@ -57,29 +268,112 @@ func main() {
 			outputPath = *indexTarPath + ".index.json"
 		}
 
-		err := tarix.CreateTarIndex(*indexTarPath, outputPath)
+		delimiter, err := parseDelimiter(*indexDelimiter)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
+		normalize, err := tarix.ParseNormForm(*indexNormalize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		opts := tarix.IndexOptions{
+			Include:         splitList(*indexInclude),
+			Exclude:         splitList(*indexExclude),
+			Progress:        progressBar(info, "Indexing"),
+			Delimiter:       delimiter,
+			Root:            *indexRoot,
+			ContentHash:     *indexChecksum,
+			MIMETypes:       *indexMIME,
+			Dedup:           *indexDedup,
+			StorePaths:      *indexPaths || *indexPathPrefixes,
+			PathPrefixes:    *indexPathPrefixes,
+			KeyScheme:       tarix.KeyScheme{Name: *indexKeys},
+			Logger:          logger,
+			CheckpointEvery: *indexCheckpoint,
+			Concatenated:    *indexConcatenated,
+			HashWorkers:     *indexHashWorkers,
+			Occurrences:     *indexOccurrences,
+			MaxEntries:      *indexMaxEntries,
+			ByOffset:        *indexByOffset,
+			Normalize:       normalize,
+			Verbose:         *indexVerbose,
+		}
+		if *indexVerbose {
+			// The listing replaces the progress bar, which would overwrite it
+			opts.Log = info
+			opts.Progress = nil
+		}
+		var skipped []int64
+		if *indexSkipBad {
+			opts.SkipBadHeaders = true
+			opts.OnSkip = func(offset int64, _ error) { skipped = append(skipped, offset) }
+		}
+		if *indexResume {
+			err = tarix.ResumeTarIndex(*indexTarPath, outputPath, opts)
+		} else {
+			err = tarix.CreateTarIndexWithOptions(*indexTarPath, outputPath, opts)
+		}
+		fmt.Fprintln(info)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(skipped) > 0 {
+			fmt.Fprintf(info, "Skipped %d unreadable regions at offsets %v\n", len(skipped), skipped)
+		}
+
 	case "printfrompath":
 		printfrompathCmd.Parse(os.Args[2:])
-		if *printfrompathTarPath == "" || *printfrompathIndexPath == "" || *printfrompathFilePath == "" {
-			fmt.Println("TAR file, index file, and file to extract are required")
+		flagsFromEnv(printfrompathCmd, os.Getenv, "tar", "index")
+		if *printfrompathTarPath == "" || (*printfrompathFilePath == "") == (*printfrompathKey == "") {
+			fmt.Println("TAR file and exactly one of file or key to extract are required")
 			printfrompathCmd.PrintDefaults()
 			os.Exit(1)
 		}
 
-		tarixHandle, err := tarix.NewTarixHandle(*printfrompathTarPath, *printfrompathIndexPath)
+		// Without an index, scan the TAR up to the file
+		if *printfrompathIndexPath == "" {
+			if *printfrompathKey != "" || *printfrompathOccurrence >= 0 {
+				fmt.Println("An index file is required with -key or -occurrence")
+				os.Exit(1)
+			}
+			bs, err := tarix.ExtractFromTarDirect(*printfrompathTarPath, filepath.Join(*printfrompathRoot, *printfrompathFilePath))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(bs))
+			break
+		}
+
+		tarixHandle, err := tarix.NewTarixHandleWithOptions(*printfrompathTarPath, *printfrompathIndexPath, tarix.HandleOptions{NoLock: *printfrompathNoLock, Logger: logger})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		defer tarixHandle.TarFile.Close()
+		defer tarixHandle.Close()
+		tarixHandle.Verify = *printfrompathVerify
+		tarixHandle.Root = *printfrompathRoot
+		tarixHandle.Partial = *printfrompathPartial
 
 		// Extract file data as bytes
-		bs, err := tarixHandle.ExtractBytesOfFile(*printfrompathFilePath)
+		var bs []byte
+		switch {
+		case *printfrompathKey != "":
+			bs, err = tarixHandle.ExtractBytesByKey(*printfrompathKey)
+		case *printfrompathOccurrence >= 0:
+			bs, err = tarixHandle.ExtractOccurrence(*printfrompathFilePath, *printfrompathOccurrence)
+		default:
+			bs, err = tarixHandle.ExtractBytesOfFile(*printfrompathFilePath)
+		}
+		if err != nil && bs != nil {
+			// With -partial, what could be read comes with the truncation
+			fmt.Println(string(bs))
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -87,43 +381,776 @@ func main() {
 
 		fmt.Println(string(bs))
 
+	case "cat":
+		catCmd.Parse(os.Args[2:])
+		flagsFromEnv(catCmd, os.Getenv, "tar", "index")
+		files := splitList(*catFiles)
+		if *catTarPath == "" || *catIndexPath == "" || len(files) == 0 {
+			fmt.Println("TAR file, index file, and files to print are required")
+			catCmd.PrintDefaults()
+			os.Exit(1)
+		}
+		separator, err := strconv.Unquote(`"` + *catSeparator + `"`)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid separator %q\n", *catSeparator)
+			os.Exit(1)
+		}
+
+		tarixHandle, err := tarix.NewTarixHandleWithOptions(*catTarPath, *catIndexPath, tarix.HandleOptions{NoLock: *catNoLock, Logger: logger})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer tarixHandle.Close()
+		tarixHandle.Verify = *catVerify
+		tarixHandle.Root = *catRoot
+
+		// Fail before printing anything if a file is not in the index
+		for _, file := range files {
+			if _, _, err := tarixHandle.Locate(file); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", file, err)
+				os.Exit(1)
+			}
+		}
+
+		out := bufio.NewWriter(os.Stdout)
+		for i, file := range files {
+			if i > 0 {
+				out.WriteString(separator)
+			}
+			if _, err := tarixHandle.WriteFileTo(file, out); err != nil {
+				out.Flush()
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", file, err)
+				os.Exit(1)
+			}
+		}
+		if err := out.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 	case "extract":
 		extractCmd.Parse(os.Args[2:])
-		if *extractTarPath == "" || *extractIndexPath == "" || *extractFile == "" {
+		flagsFromEnv(extractCmd, os.Getenv, "tar", "index")
+		if *extractTarPath == "" || *extractIndexPath == "" || len(extractFiles) == 0 {
 			fmt.Println("TAR file, index file, and file to extract are required")
 			extractCmd.PrintDefaults()
 			os.Exit(1)
 		}
 
-		// Default output path if not specified
+		readPattern, err := tarix.ParseReadPattern(*extractReadPattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts := tarix.ExtractOptions{ReadPattern: readPattern, Logger: logger, Verify: *extractVerify, Root: *extractRoot, BufferSize: *extractBufferSize, NoLock: *extractNoLock, RestoreSparse: *extractSparse, NoSpecialFiles: *extractNoSpecial, AutoReindex: *extractAutoReindex, ReplaceStaleIndex: *extractReplaceIndex, AllowedRoot: *extractAllowedRoot}
+		if *extractNoClobber {
+			opts.Overwrite = tarix.OverwriteSkip
+		}
+
+		// Several files keep their paths under the output directory
+		if len(extractFiles) > 1 {
+			if *extractOutput != "" || *extractFlatten {
+				fmt.Fprintln(os.Stderr, "Error: -output and -flatten need exactly one -file, use -output-dir for several")
+				os.Exit(1)
+			}
+			if err := tarix.ExtractMany(*extractTarPath, *extractIndexPath, extractFiles, *extractOutputDir, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			break
+		}
+		extractFile := extractFiles[0]
+
+		// Default output path if not specified: the file's path relative to the current dir
 		outputPath := *extractOutput
 		if outputPath == "" {
-			outputPath = filepath.Base(*extractFile)
+			if *extractFlatten {
+				outputPath = filepath.Base(extractFile)
+			} else {
+				outputPath = filepath.Clean(extractFile)
+				if !filepath.IsLocal(outputPath) {
+					fmt.Fprintf(os.Stderr, "Error: %s is not a relative path below the current dir, use -output or -flatten\n", extractFile)
+					os.Exit(1)
+				}
+				opts.CreateDirs = true
+			}
+		}
+
+		err = tarix.ExtractFileFromTarWithOptions(*extractTarPath, *extractIndexPath, extractFile, outputPath, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "extractall":
+		extractallCmd.Parse(os.Args[2:])
+		flagsFromEnv(extractallCmd, os.Getenv, "tar", "index")
+		if *extractallTarPath == "" || *extractallIndexPath == "" {
+			fmt.Println("TAR file and index file are required")
+			extractallCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		readPattern, err := tarix.ParseReadPattern(*extractallReadPattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts := tarix.ExtractOptions{ReadPattern: readPattern, Progress: progressBar(info, "Extracting"), Root: *extractallRoot, Logger: logger, BufferSize: *extractallBufferSize, RestoreSparse: *extractallSparse, PreserveOwner: *extractallPreserveOwner, Concurrency: *extractallConcurrency}
+		if *extractallNoClobber {
+			opts.Overwrite = tarix.OverwriteSkip
+		}
+		err = tarix.ExtractAllWithOptions(*extractallTarPath, *extractallIndexPath, *extractallOutputDir, opts)
+		fmt.Fprintln(info)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 
-		err := tarix.ExtractFileFromTar(*extractTarPath, *extractIndexPath, *extractFile, outputPath)
+	case "explode":
+		explodeCmd.Parse(os.Args[2:])
+		flagsFromEnv(explodeCmd, os.Getenv, "tar", "index")
+		if *explodeTarPath == "" || *explodeIndexPath == "" {
+			fmt.Println("TAR file and index file are required")
+			explodeCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		opts := tarix.ExtractOptions{Progress: progressBar(info, "Extracting"), Root: *explodeRoot, Logger: logger, RestoreSparse: *explodeSparse, PreserveOwner: *explodePreserveOwner, UnsafeLinks: *explodeUnsafeLinks}
+		if *explodeNoClobber {
+			opts.Overwrite = tarix.OverwriteSkip
+		}
+		summary, err := tarix.Explode(*explodeTarPath, *explodeIndexPath, *explodeOutputDir, opts)
+		fmt.Fprintln(info)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		fmt.Fprintf(info, "Recreated %d files (%d bytes), %d directories, %d symlinks and %d hard links in %s\n",
+			summary.Files, summary.Bytes, summary.Dirs, summary.Symlinks, summary.HardLinks, *explodeOutputDir)
+		if summary.NotIndexed > 0 {
+			fmt.Fprintf(info, "Left out %d files not in the index\n", summary.NotIndexed)
+		}
+		// Skipped entries are warnings, so they are shown even with -quiet
+		if len(summary.Skipped) > 0 {
+			fmt.Fprintf(os.Stderr, "Skipped %d entries:\n", len(summary.Skipped))
+			for _, entry := range summary.Skipped {
+				fmt.Fprintf(os.Stderr, "- %s: %s\n", entry.Name, entry.Reason)
+			}
+		}
+
+	case "extract-top":
+		extractTopCmd.Parse(os.Args[2:])
+		flagsFromEnv(extractTopCmd, os.Getenv, "tar", "index")
+		if *extractTopTarPath == "" || *extractTopIndexPath == "" {
+			fmt.Println("TAR file and index file are required")
+			extractTopCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		entries, err := tarix.LargestFiles(*extractTopIndexPath, *extractTopN, tarix.ListOptions{TarPath: *extractTopTarPath})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, entry := range entries {
+			if entry.Path == "" {
+				fmt.Fprintf(os.Stderr, "Error: no path known for key %s in %s, rebuild the index with -paths\n", entry.Key, *extractTopTarPath)
+				os.Exit(1)
+			}
+		}
+		for _, entry := range entries {
+			fmt.Printf("- %s (%d bytes)\n", entry.Path, entry.Size)
+		}
+
+		opts := tarix.ExtractOptions{Progress: progressBar(info, "Extracting"), Logger: logger, Concurrency: *extractTopConcurrency}
+		if *extractTopNoClobber {
+			opts.Overwrite = tarix.OverwriteSkip
+		}
+		err = tarix.ExtractEntries(*extractTopTarPath, *extractTopIndexPath, entries, *extractTopOutputDir, opts)
+		fmt.Fprintln(info)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "filter":
+		filterCmd.Parse(os.Args[2:])
+		flagsFromEnv(filterCmd, os.Getenv, "tar", "index")
+		files := splitList(*filterFiles)
+		if *filterTarPath == "" || *filterIndexPath == "" || len(files) == 0 || *filterOutputPath == "" {
+			fmt.Println("TAR file, index file, files and output file are required")
+			filterCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		if *filterOutputPath == "-" {
+			out := bufio.NewWriter(os.Stdout)
+			err := tarix.StreamSubsetTar(out, *filterTarPath, *filterIndexPath, files)
+			if err == nil {
+				err = out.Flush()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			break
+		}
+		if err := tarix.FilterTar(*filterTarPath, *filterIndexPath, *filterOutputPath, files); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(info, "Copied %d files to %s\n", len(files), *filterOutputPath)
+
+	case "merge":
+		mergeCmd.Parse(os.Args[2:])
+		indexPaths := splitList(*mergeIndexPaths)
+		if len(indexPaths) == 0 || *mergeOutputPath == "" || (*mergeTarPaths == "") == (*mergeSizes == "") {
+			fmt.Println("Index files, output file, and exactly one of TAR files or sizes are required")
+			mergeCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		sizes, err := archiveSizes(splitList(*mergeTarPaths), splitList(*mergeSizes))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		// The size of the last archive is not needed to place its entries
+		if len(sizes) < len(indexPaths)-1 || len(sizes) > len(indexPaths) {
+			fmt.Fprintf(os.Stderr, "Error: expected a TAR or size for each index\n")
+			os.Exit(1)
+		}
+
+		var parts []*tarix.TarIndex
+		var offset int64
+		for i, indexPath := range indexPaths {
+			index, err := tarix.ReadTarIndex(indexPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			shifted, err := tarix.MergeIndexes(offset, index)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			parts = append(parts, shifted)
+			if i < len(sizes) {
+				offset += sizes[i]
+			}
+		}
+
+		merged, err := tarix.MergeIndexes(0, parts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := tarix.WriteTarIndex(merged, *mergeOutputPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(info, "Merged %d indexes with %d files into %s\n", len(indexPaths), merged.Len(), *mergeOutputPath)
 
 	case "list":
 		listCmd.Parse(os.Args[2:])
+		flagsFromEnv(listCmd, os.Getenv, "index")
 		if *listIndexPath == "" {
 			fmt.Println("Index file is required")
 			listCmd.PrintDefaults()
 			os.Exit(1)
 		}
 
-		err := tarix.ListFilesInTar(*listIndexPath)
+		opts := tarix.ListOptions{TarPath: *listTarPath}
+		var err error
+		if *listMinSize != "" {
+			if opts.MinSize, err = tarix.ParseSize(*listMinSize); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if *listMaxSize != "" {
+			if opts.MaxSize, err = tarix.ParseSize(*listMaxSize); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if *listJSON {
+			var entries []tarix.ListEntry
+			entries, err = tarix.ListFilesWithOptions(*listIndexPath, opts)
+			if err == nil {
+				err = tarix.WriteListJSON(os.Stdout, entries)
+			}
+		} else {
+			err = tarix.ListFilesInTarWithOptions(*listIndexPath, opts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "dups":
+		dupsCmd.Parse(os.Args[2:])
+		flagsFromEnv(dupsCmd, os.Getenv, "index")
+		if *dupsIndexPath == "" {
+			fmt.Println("Index file is required")
+			dupsCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		index, err := tarix.ReadTarIndex(*dupsIndexPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		dups, reclaimable, err := index.Duplicates()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		groups := make([][]string, 0, len(dups))
+		for _, paths := range dups {
+			groups = append(groups, paths)
+		}
+		sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+		for _, group := range groups {
+			fmt.Printf("- %s\n", strings.Join(group, ", "))
+		}
+		fmt.Printf("%d groups of files with identical content, %d bytes reclaimable\n", len(groups), reclaimable)
+
+	case "compact":
+		compactCmd.Parse(os.Args[2:])
+		flagsFromEnv(compactCmd, os.Getenv, "index")
+		if *compactIndexPath == "" {
+			fmt.Println("Index file is required")
+			compactCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		removed, err := tarix.CompactIndex(*compactIndexPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(info, "Removed %d rows from %s\n", removed, *compactIndexPath)
+
+	case "convert-index":
+		convertIndexCmd.Parse(os.Args[2:])
+		if *convertIndexIn == "" || *convertIndexOut == "" {
+			fmt.Println("Input and output index files are required")
+			convertIndexCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		n, toJSON, err := tarix.ConvertIndex(*convertIndexIn, *convertIndexOut)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		format := "CSV"
+		if toJSON {
+			format = "JSON"
+		}
+		fmt.Fprintf(info, "Converted %d entries to %s in %s\n", n, format, *convertIndexOut)
+
+	case "stats":
+		statsCmd.Parse(os.Args[2:])
+		flagsFromEnv(statsCmd, os.Getenv, "tar", "index")
+		if *statsIndexPath == "" {
+			fmt.Println("Index file is required")
+			statsCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		index, err := tarix.ReadTarIndex(*statsIndexPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		stats := index.Stats()
+		format := "unknown"
+		if stats.Format != tar.FormatUnknown {
+			format = stats.Format.String()
+		}
+		fmt.Printf("Format: %s\n", format)
+		fmt.Printf("Files: %d\n", stats.Files)
+		fmt.Printf("Content size: %d bytes\n", stats.ContentSize)
+
+		if len(stats.Extensions) > 0 && *statsTop > 0 {
+			fmt.Println("\nTop extensions:")
+			for i, es := range stats.Extensions {
+				if i == *statsTop {
+					break
+				}
+				ext := es.Ext
+				if ext == "" {
+					ext = "(none)"
+				}
+				fmt.Printf("- %s: %d files, %d bytes\n", ext, es.Count, es.TotalSize)
+			}
+		}
+
+		if *statsCoverage {
+			var archiveSize int64
+			if *statsTarPath != "" {
+				fi, err := os.Stat(*statsTarPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				archiveSize = fi.Size()
+			}
+			coverage := index.Coverage(archiveSize)
+			if coverage.ArchiveSize == 0 {
+				fmt.Fprintln(os.Stderr, "Error: the index doesn't record the archive size, pass -tar")
+				os.Exit(1)
+			}
+			fmt.Printf("\nCoverage: %d of %d bytes (%.2f%%), %d bytes in %d gaps\n",
+				coverage.ContentBytes, coverage.ArchiveSize, coverage.Percent(), coverage.GapBytes(), len(coverage.Gaps))
+			for _, gap := range coverage.Gaps {
+				fmt.Printf("- %d-%d: %d bytes\n", gap.Offset, gap.Offset+gap.Size, gap.Size)
+			}
+		}
+
+	case "contains":
+		containsCmd.Parse(os.Args[2:])
+		flagsFromEnv(containsCmd, os.Getenv, "index")
+		if *containsIndexPath == "" || *containsFile == "" {
+			fmt.Println("Index file and file path are required")
+			containsCmd.PrintDefaults()
+			os.Exit(2)
+		}
+
+		index, err := tarix.ReadTarIndex(*containsIndexPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+
+		// Like grep(1), exit with 0 if found, 1 if not and 2 on errors
+		found := index.Contains(*containsFile)
+		if *containsVerbose {
+			if found {
+				fmt.Printf("%s is in the index\n", *containsFile)
+			} else {
+				fmt.Printf("%s is not in the index\n", *containsFile)
+			}
+		}
+		if !found {
+			os.Exit(1)
+		}
+
+	case "offset":
+		offsetCmd.Parse(os.Args[2:])
+		flagsFromEnv(offsetCmd, os.Getenv, "index")
+		if *offsetIndexPath == "" || *offsetFile == "" {
+			fmt.Println("Index file and file path are required")
+			offsetCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		index, err := tarix.ReadTarIndex(*offsetIndexPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		dataRange, err := index.DataRange(*offsetFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *offsetJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(dataRange)
+		} else {
+			_, err = fmt.Printf("%d %d\n", dataRange.Offset, dataRange.Size)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "diff":
+		diffCmd.Parse(os.Args[2:])
+		if *diffOldPath == "" || *diffNewPath == "" {
+			fmt.Println("Old and new index files are required")
+			diffCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		added, removed, changed, err := tarix.DiffIndexes(*diffOldPath, *diffNewPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		for _, group := range []struct {
+			name  string
+			files []string
+		}{{"Added", added}, {"Removed", removed}, {"Changed", changed}} {
+			fmt.Printf("%s (%d):\n", group.name, len(group.files))
+			for _, name := range group.files {
+				fmt.Printf("- %s\n", name)
+			}
+		}
+
+		// Like diff(1), exit with 1 if the indexes differ
+		if len(added)+len(removed)+len(changed) > 0 {
+			os.Exit(1)
+		}
+
+	case "compare-index":
+		compareIndexCmd.Parse(os.Args[2:])
+		if *compareIndexCSV == "" || *compareIndexJSON == "" {
+			fmt.Println("CSV and JSON index files are required")
+			compareIndexCmd.PrintDefaults()
+			os.Exit(2)
+		}
+
+		comparison, err := tarix.CompareCSVToJSON(*compareIndexCSV, *compareIndexJSON)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		for _, m := range comparison.Metadata {
+			fmt.Printf("%s: CSV %q, JSON %q\n", m.Field, m.A, m.B)
+		}
+		for _, key := range comparison.OnlyInA {
+			fmt.Printf("%s: only in CSV\n", key)
+		}
+		for _, key := range comparison.OnlyInB {
+			fmt.Printf("%s: only in JSON\n", key)
+		}
+		for _, m := range comparison.Entries {
+			fmt.Printf("%s %s: CSV %q, JSON %q\n", m.Key, m.Field, m.A, m.B)
+		}
+
+		// Exit with 1 if the indexes differ and 2 on errors, like diff(1)
+		if !comparison.Equal() {
+			os.Exit(1)
+		}
+		fmt.Fprintln(info, "Indexes match")
+
+	case "collisions":
+		collisionsCmd.Parse(os.Args[2:])
+		flagsFromEnv(collisionsCmd, os.Getenv, "tar")
+		if *collisionsTarPath == "" {
+			fmt.Println("TAR file is required")
+			collisionsCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		report, err := tarix.CheckCollisions(*collisionsTarPath, *collisionsHash, *collisionsKeyLen)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%d paths, %d colliding groups with %s keys of %d characters\n", report.Paths, len(report.Collisions), *collisionsHash, report.KeyLen)
+		for _, group := range report.Collisions {
+			fmt.Printf("- %s\n", strings.Join(group, ", "))
+		}
+		if report.MinKeyLen > 0 {
+			fmt.Printf("Keys of %d or more characters avoid collisions in this archive\n", report.MinKeyLen)
+		}
+		if len(report.Collisions) > 0 {
+			os.Exit(1)
+		}
+
+	case "serve":
+		serveCmd.Parse(os.Args[2:])
+		flagsFromEnv(serveCmd, os.Getenv, "tar", "index")
+		if *serveTarPath == "" || *serveIndexPath == "" {
+			fmt.Println("TAR file and index file are required")
+			serveCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		tarixHandle, err := tarix.NewTarixHandleWithOptions(*serveTarPath, *serveIndexPath, tarix.HandleOptions{Logger: logger})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		defer tarixHandle.Close()
+		tarixHandle.Root = *serveRoot
+
+		var handlerOpts tarix.HandlerOptions
+		if *serveAccessLog {
+			handlerOpts.AccessLog = func(path string, bytesServed int64, status int) {
+				logger.Info("served", "path", path, "bytes", bytesServed, "status", status)
+			}
+		}
+
+		fmt.Fprintf(info, "Serving %s on http://%s/\n", *serveTarPath, *serveAddr)
+		if err := http.ListenAndServe(*serveAddr, tarix.HandlerWithOptions(tarixHandle, handlerOpts)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "migrate":
+		migrateCmd.Parse(os.Args[2:])
+		flagsFromEnv(migrateCmd, os.Getenv, "tar", "index")
+		if *migrateTarPath == "" || *migrateIndexPath == "" || *migrateOutputPath == "" {
+			fmt.Println("TAR file, index file and output index file are required")
+			migrateCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		if err := tarix.MigrateIndexHash(*migrateTarPath, *migrateIndexPath, *migrateOutputPath, tarix.KeyScheme{Name: *migrateKeys}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(info, "Migrated %s to %s keys in %s\n", *migrateIndexPath, *migrateKeys, *migrateOutputPath)
+
+	case "verify":
+		verifyCmd.Parse(os.Args[2:])
+		flagsFromEnv(verifyCmd, os.Getenv, "tar", "index")
+		if *verifyTarPath == "" || *verifyIndexPath == "" {
+			fmt.Println("TAR file and index file are required")
+			verifyCmd.PrintDefaults()
+			os.Exit(1)
+		}
+
+		result, err := tarix.VerifyIndex(*verifyTarPath, *verifyIndexPath, tarix.VerifyOptions{Deep: *verifyDeep})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		for _, file := range result.Files {
+			name := file.Path
+			if name == "" {
+				name = file.Key
+			}
+			if file.Err != nil {
+				fmt.Printf("FAIL %s: %v\n", name, file.Err)
+			} else {
+				fmt.Fprintf(info, "ok   %s\n", name)
+			}
+		}
+		fmt.Printf("Verified %d files: %d passed, %d failed, %d content checksums checked\n", len(result.Files), result.Passed, result.Failed, result.Hashed)
+		if !result.OK() {
+			os.Exit(1)
+		}
 
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
-		fmt.Println("Expected 'index', 'extract', 'printfrompath' or 'list'")
+		fmt.Println("Expected 'index', 'extract', 'extractall', 'explode', 'extract-top', 'printfrompath', 'cat', 'filter', 'merge', 'list', 'contains', 'offset', 'stats', 'dups', 'compact', 'convert-index', 'diff', 'compare-index', 'verify', 'migrate', 'serve' or 'collisions'")
 		os.Exit(1)
 	}
 }
+
+// envFlags are the environment variables flags not given on the command line
+// fall back to
+var envFlags = map[string]string{
+	"tar":   "TARIX_TAR",
+	"index": "TARIX_INDEX",
+}
+
+// flagsFromEnv sets the named flags of fs that weren't given on the command
+// line from their variables in envFlags, if set, so flags take precedence
+func flagsFromEnv(fs *flag.FlagSet, getenv func(string) string, names ...string) {
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for _, name := range names {
+		if value := getenv(envFlags[name]); value != "" && !given[name] {
+			fs.Set(name, value)
+		}
+	}
+}
+
+// stringList is a flag collecting the values of each time it is given
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// archiveSizes returns the byte sizes of tarPaths, or parses sizes when no TARs are given
+func archiveSizes(tarPaths, sizes []string) ([]int64, error) {
+	var result []int64
+	for _, tarPath := range tarPaths {
+		info, err := os.Stat(tarPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file info: %w", err)
+		}
+		result = append(result, info.Size())
+	}
+	for _, size := range sizes {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid size %q", size)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// parseDelimiter parses the -delimiter flag, a single character or a tab alias
+func parseDelimiter(value string) (rune, error) {
+	switch value {
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	if utf8.RuneCountInString(value) != 1 {
+		return 0, fmt.Errorf("delimiter must be a single character, got %q", value)
+	}
+	r, _ := utf8.DecodeRuneInString(value)
+	return r, nil
+}
+
+// progressBar returns a ProgressFunc rendering a bar with an ETA based on the
+// throughput so far to w. It redraws only when the percentage changes, at
+// most five times a second.
+func progressBar(w io.Writer, label string) tarix.ProgressFunc {
+	const width = 30
+	start := time.Now()
+	lastPercent := int64(-1)
+	return tarix.ThrottleProgress(func(p tarix.Progress) {
+		if p.BytesTotal <= 0 {
+			return
+		}
+		percent := p.BytesDone * 100 / p.BytesTotal
+		if percent == lastPercent {
+			return
+		}
+		lastPercent = percent
+
+		eta := "?"
+		if p.BytesDone > 0 {
+			elapsed := time.Since(start)
+			remaining := time.Duration(float64(elapsed) * float64(p.BytesTotal-p.BytesDone) / float64(p.BytesDone))
+			eta = remaining.Round(time.Second).String()
+		}
+
+		files := ""
+		if p.FilesTotal > 0 {
+			files = fmt.Sprintf(" %d/%d files", p.FilesDone, p.FilesTotal)
+		}
+
+		filled := int(percent) * width / 100
+		fmt.Fprintf(w, "\r%s [%s%s] %3d%%%s ETA %s ", label, strings.Repeat("=", filled), strings.Repeat(" ", width-filled), percent, files, eta)
+	}, 200*time.Millisecond)
+}
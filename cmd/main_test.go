@@ -0,0 +1,238 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command instead of the tests when runTarix re-executes
+// the test binary
+func TestMain(m *testing.M) {
+	if os.Getenv("TARIX_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runTarix runs the command with args, returning its output and exit code
+func runTarix(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "TARIX_TEST_MAIN=1")
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("Failed to run tarix %v: %v", args, err)
+	}
+	return out.String(), errOut.String(), code
+}
+
+// writeTar writes files to a TAR at path, in the order given
+func writeTar(t *testing.T, path string, files ...[2]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file[0], Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(file[1]))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(file[1])); err != nil {
+			t.Fatalf("Failed to write data: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+}
+
+func TestCat(t *testing.T) {
+	dir := t.TempDir()
+	tarPath := filepath.Join(dir, "cat.tar")
+	writeTar(t, tarPath, [2]string{"a.txt", "alpha"}, [2]string{"dir/b.txt", "beta"})
+	indexPath := tarPath + ".index"
+	if _, stderr, code := runTarix(t, "index", "-tar", tarPath, "-output", indexPath); code != 0 {
+		t.Fatalf("Failed to index TAR: %s", stderr)
+	}
+
+	// Files are printed in the order given, with the separator between them
+	stdout, stderr, code := runTarix(t, "cat", "-tar", tarPath, "-index", indexPath, "-files", "dir/b.txt,a.txt", "-separator", `\n--\n`)
+	if code != 0 || stdout != "beta\n--\nalpha" {
+		t.Errorf("Unexpected output %q with exit code %d: %s", stdout, code, stderr)
+	}
+
+	// Paths are relative to -root
+	stdout, stderr, code = runTarix(t, "cat", "-tar", tarPath, "-index", indexPath, "-files", "b.txt", "-root", "dir", "-verify")
+	if code != 0 || stdout != "beta" {
+		t.Errorf("Unexpected output %q with exit code %d: %s", stdout, code, stderr)
+	}
+
+	// A missing file fails before anything is printed
+	stdout, stderr, code = runTarix(t, "cat", "-tar", tarPath, "-index", indexPath, "-files", "a.txt,missing.txt")
+	if code != 1 || stdout != "" || !strings.Contains(stderr, "missing.txt") {
+		t.Errorf("Expected exit code 1 and no output for a missing file, got %d, %q, %q", code, stdout, stderr)
+	}
+
+	// The index and files are required
+	if _, _, code := runTarix(t, "cat", "-tar", tarPath, "-index", indexPath); code != 1 {
+		t.Errorf("Expected exit code 1 without -files, got %d", code)
+	}
+}
+
+func TestFlagsFromEnv(t *testing.T) {
+	env := map[string]string{"TARIX_TAR": "env.tar", "TARIX_INDEX": "env.idx"}
+	for _, tc := range []struct {
+		name       string
+		args       []string
+		env        map[string]string
+		tar, index string
+	}{
+		{"env only", nil, env, "env.tar", "env.idx"},
+		{"flags only", []string{"-tar", "a.tar", "-index", "a.idx"}, nil, "a.tar", "a.idx"},
+		{"flags over env", []string{"-tar", "a.tar"}, env, "a.tar", "env.idx"},
+		{"neither", nil, nil, "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			tarPath := fs.String("tar", "", "")
+			indexPath := fs.String("index", "", "")
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("Failed to parse flags: %v", err)
+			}
+			flagsFromEnv(fs, func(name string) string { return tc.env[name] }, "tar", "index")
+			if *tarPath != tc.tar || *indexPath != tc.index {
+				t.Errorf("Expected -tar %q -index %q, got %q %q", tc.tar, tc.index, *tarPath, *indexPath)
+			}
+		})
+	}
+}
+
+func TestStringList(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var files stringList
+	fs.Var(&files, "file", "")
+	if err := fs.Parse([]string{"-file", "a.txt", "-file", "dir/b,c.txt"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if len(files) != 2 || files[0] != "a.txt" || files[1] != "dir/b,c.txt" {
+		t.Errorf("Expected both values in order, got %q", files)
+	}
+}
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	tarPath := filepath.Join(dir, "stats.tar")
+	writeTar(t, tarPath, [2]string{"a.txt", "alpha"}, [2]string{"b.bin", "0123456789ab"}, [2]string{"c.txt", "gamma"})
+	indexPath := tarPath + ".index"
+	if _, stderr, code := runTarix(t, "index", "-tar", tarPath, "-output", indexPath, "-paths"); code != 0 {
+		t.Fatalf("Failed to index TAR: %s", stderr)
+	}
+
+	stdout, stderr, code := runTarix(t, "stats", "-index", indexPath, "-top", "1")
+	if code != 0 {
+		t.Fatalf("Failed to run stats: %s", stderr)
+	}
+	for _, line := range []string{"Format: USTAR\n", "Files: 3\n", "Content size: 22 bytes\n", "- .bin: 1 files, 12 bytes\n"} {
+		if !strings.Contains(stdout, line) {
+			t.Errorf("Expected %q in output:\n%s", line, stdout)
+		}
+	}
+	if strings.Contains(stdout, ".txt") {
+		t.Errorf("Expected only the top extension with -top 1:\n%s", stdout)
+	}
+}
+
+func TestStatsCoverage(t *testing.T) {
+	dir := t.TempDir()
+	tarPath := filepath.Join(dir, "stats.tar")
+	writeTar(t, tarPath, [2]string{"a.txt", "alpha"}, [2]string{"b.bin", "0123456789ab"}, [2]string{"c.txt", "gamma"})
+	indexPath := tarPath + ".index"
+	if _, stderr, code := runTarix(t, "index", "-tar", tarPath, "-output", indexPath, "-include", "*.txt"); code != 0 {
+		t.Fatalf("Failed to index TAR: %s", stderr)
+	}
+
+	stdout, stderr, code := runTarix(t, "stats", "-index", indexPath, "-coverage")
+	if code != 0 {
+		t.Fatalf("Failed to run stats: %s", stderr)
+	}
+	// b.bin and the trailer are left out
+	for _, line := range []string{"Coverage: 10 of 4096 bytes (0.24%), 2048 bytes in 2 gaps\n", "- 1024-2048: 1024 bytes\n", "- 3072-4096: 1024 bytes\n"} {
+		if !strings.Contains(stdout, line) {
+			t.Errorf("Expected %q in output:\n%s", line, stdout)
+		}
+	}
+}
+
+func TestExtractTopNoPath(t *testing.T) {
+	dir := t.TempDir()
+	tarPath := filepath.Join(dir, "top.tar")
+	writeTar(t, tarPath, [2]string{"a.txt", "alpha"})
+	indexPath := tarPath + ".index"
+	if _, stderr, code := runTarix(t, "index", "-tar", tarPath, "-output", indexPath); code != 0 {
+		t.Fatalf("Failed to index TAR: %s", stderr)
+	}
+	// A TAR other than the indexed one has no path for the key
+	otherPath := filepath.Join(dir, "other.tar")
+	writeTar(t, otherPath, [2]string{"b.txt", "beta"})
+
+	outputDir := filepath.Join(dir, "out")
+	stdout, stderr, code := runTarix(t, "extract-top", "-tar", otherPath, "-index", indexPath, "-output-dir", outputDir)
+	if code != 1 || !strings.Contains(stderr, "no path known for key") {
+		t.Errorf("Expected a missing path error, got exit code %d: %s", code, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("Expected nothing listed, got %q", stdout)
+	}
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing extracted, got %v", err)
+	}
+}
+
+func TestExplodeQuiet(t *testing.T) {
+	dir := t.TempDir()
+	tarPath := filepath.Join(dir, "explode.tar")
+	f, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Name: "a.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 5}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	tw.Write([]byte("alpha"))
+	if err := tw.WriteHeader(&tar.Header{Name: "escape", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+	f.Close()
+	indexPath := tarPath + ".index"
+	if _, stderr, code := runTarix(t, "index", "-tar", tarPath, "-output", indexPath); code != 0 {
+		t.Fatalf("Failed to index TAR: %s", stderr)
+	}
+
+	// The summary is left out, but the skipped link is still reported
+	stdout, stderr, code := runTarix(t, "-quiet", "explode", "-tar", tarPath, "-index", indexPath, "-output-dir", filepath.Join(dir, "out"))
+	if code != 0 || stdout != "" {
+		t.Errorf("Expected no output with exit code 0, got %q with %d: %s", stdout, code, stderr)
+	}
+	if !strings.Contains(stderr, "Skipped 1 entries") || !strings.Contains(stderr, "- escape: ") {
+		t.Errorf("Expected the skipped link on stderr, got %q", stderr)
+	}
+}
@@ -0,0 +1,117 @@
+package tarix
+
+import (
+	"archive/tar"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+)
+
+// collisionHashes are the hash functions CheckCollisions can audit keys of
+var collisionHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha256": sha256.New,
+}
+
+// CollisionReport is the result of CheckCollisions
+type CollisionReport struct {
+	Paths  int // Number of distinct file paths in the archive
+	KeyLen int // Number of hex characters keys were truncated to
+	// Collisions holds the groups of paths sharing a key, each sorted, sorted
+	// by their first path
+	Collisions [][]string
+	// MinKeyLen is the shortest key length giving every path its own key,
+	// or 0 if some full digests collide
+	MinKeyLen int
+}
+
+// CheckCollisions hashes the normalized path of every file in the TAR with
+// algo ("md5" or "sha256"), truncates the hex digests to keyLen characters
+// like the default key scheme does with HashLen, and reports the paths that
+// would share a key. A keyLen of zero or less uses the full digest. Paths
+// stored more than once count once. Only headers are read.
+func CheckCollisions(tarPath, algo string, keyLen int) (*CollisionReport, error) {
+	newHash, ok := collisionHashes[algo]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash %q, expected md5 or sha256", algo)
+	}
+	digestLen := hex.EncodedLen(newHash().Size())
+	if keyLen <= 0 {
+		keyLen = digestLen
+	}
+	if keyLen > digestLen {
+		return nil, fmt.Errorf("key length %d is longer than the %d characters of a %s digest", keyLen, digestLen, algo)
+	}
+
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar file: %w", err)
+	}
+	defer file.Close()
+
+	digests := map[string]string{}
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading tar header: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeGNUSparse {
+			continue
+		}
+		cleanFilePath := normalizePath(header.Name, "")
+		h := newHash()
+		h.Write([]byte(cleanFilePath))
+		digests[cleanFilePath] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	report := &CollisionReport{Paths: len(digests), KeyLen: keyLen}
+	groups := map[string][]string{}
+	for cleanFilePath, digest := range digests {
+		groups[digest[:keyLen]] = append(groups[digest[:keyLen]], cleanFilePath)
+	}
+	for _, paths := range groups {
+		if len(paths) > 1 {
+			sort.Strings(paths)
+			report.Collisions = append(report.Collisions, paths)
+		}
+	}
+	sort.Slice(report.Collisions, func(i, j int) bool {
+		return report.Collisions[i][0] < report.Collisions[j][0]
+	})
+
+	report.MinKeyLen = minUniqueLen(digests)
+	return report, nil
+}
+
+// minUniqueLen returns the shortest prefix length that tells all the digests
+// apart, or 0 if some are equal. In sorted order, the longest common prefix
+// of any two digests is that of two neighbours.
+func minUniqueLen(digests map[string]string) int {
+	sorted := make([]string, 0, len(digests))
+	for _, digest := range digests {
+		sorted = append(sorted, digest)
+	}
+	sort.Strings(sorted)
+
+	minLen := 1
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return 0
+		}
+		common := 0
+		for sorted[i][common] == sorted[i-1][common] {
+			common++
+		}
+		minLen = max(minLen, common+1)
+	}
+	return minLen
+}
@@ -0,0 +1,53 @@
+package tarix
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckCollisions(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 200; i++ {
+		files[fmt.Sprintf("dir/file%03d.txt", i)] = "x"
+	}
+	tarFilePath := filepath.Join(t.TempDir(), "collisions.tar")
+	writeTestTar(t, tarFilePath, files)
+
+	// 200 paths can't get distinct keys of one hex character
+	report, err := CheckCollisions(tarFilePath, "md5", 1)
+	if err != nil {
+		t.Fatalf("Failed to check collisions: %v", err)
+	}
+	if report.Paths != 200 || len(report.Collisions) == 0 {
+		t.Fatalf("Expected collisions among 200 paths, got %+v", report)
+	}
+	for _, group := range report.Collisions {
+		for _, p := range group[1:] {
+			if hashFilePath(p)[:1] != hashFilePath(group[0])[:1] {
+				t.Errorf("Expected %s and %s to share a key", p, group[0])
+			}
+		}
+	}
+
+	// MinKeyLen is the shortest length without collisions
+	if report.MinKeyLen < 2 {
+		t.Fatalf("Expected a minimum key length of at least 2, got %d", report.MinKeyLen)
+	}
+	if report, _ := CheckCollisions(tarFilePath, "md5", report.MinKeyLen); len(report.Collisions) != 0 {
+		t.Errorf("Expected no collisions at the minimum key length, got %v", report.Collisions)
+	}
+	if report, _ := CheckCollisions(tarFilePath, "md5", report.MinKeyLen-1); len(report.Collisions) == 0 {
+		t.Errorf("Expected collisions below the minimum key length")
+	}
+
+	if report, err := CheckCollisions(tarFilePath, "sha256", HashLen); err != nil || len(report.Collisions) != 0 || report.KeyLen != HashLen {
+		t.Errorf("Expected no sha256 collisions, got %+v, %v", report, err)
+	}
+	if _, err := CheckCollisions(tarFilePath, "crc32", 8); err == nil {
+		t.Errorf("Expected an error for an unknown hash")
+	}
+	if _, err := CheckCollisions(tarFilePath, "md5", 33); err == nil {
+		t.Errorf("Expected an error for a key longer than the digest")
+	}
+}
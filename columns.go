@@ -0,0 +1,39 @@
+package tarix
+
+import "fmt"
+
+// requiredColumns are the columns every index has. Optional ones (path,
+// checksum, compressed, mime, nonextractable, sparse) are written only when some
+// entry has a value.
+var requiredColumns = []string{"key", "start", "size"}
+
+// indexColumns maps the column names of an index header to their positions
+type indexColumns map[string]int
+
+// parseColumns reads an index header. Columns may come in any order, and
+// unknown ones are ignored so indexes written by newer versions still load.
+func parseColumns(header []string) (indexColumns, error) {
+	columns := indexColumns{}
+	for i, name := range header {
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("unexpected CSV header: duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("unexpected CSV header: missing column %q in %v", name, header)
+		}
+	}
+	return columns, nil
+}
+
+// get returns the value of the named column in record, or "" if the index
+// doesn't have the column
+func (c indexColumns) get(record []string, name string) string {
+	i, ok := c[name]
+	if !ok {
+		return ""
+	}
+	return record[i]
+}
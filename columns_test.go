@@ -0,0 +1,52 @@
+package tarix
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadTarIndexNamedColumns(t *testing.T) {
+	dir := t.TempDir()
+	keyA, keyB := hashFilePath("a.txt"), hashFilePath("b.txt")
+
+	tests := []struct {
+		name  string
+		index string
+	}{
+		{"legacy", "key,start,size\n" + keyA + ",0,5\n" + keyB + ",1024,7\n"},
+		{"reordered", "size,path,key,start\n5,a.txt," + keyA + ",0\n7,b.txt," + keyB + ",1024\n"},
+		// Columns added by newer versions are ignored
+		{"unknown columns", "key,mode,start,size,mtime\n" + keyA + ",644,0,5,1700000000\n" + keyB + ",600,1024,7,1700000001\n"},
+		{"tab delimited", "start\tkey\tsize\n0\t" + keyA + "\t5\n1024\t" + keyB + "\t7\n"},
+	}
+	for _, tt := range tests {
+		indexPath := filepath.Join(dir, "columns.index")
+		if err := os.WriteFile(indexPath, []byte(tt.index), 0644); err != nil {
+			t.Fatalf("Failed to write index file: %v", err)
+		}
+		index, err := ReadTarIndex(indexPath)
+		if err != nil {
+			t.Errorf("%s: failed to read index: %v", tt.name, err)
+			continue
+		}
+		a, b := index.Files[keyA], index.Files[keyB]
+		if len(index.Files) != 2 || a.Start != 0 || a.Size != 5 || b.Start != 1024 || b.Size != 7 {
+			t.Errorf("%s: unexpected entries %+v", tt.name, index.Files)
+		}
+		if tt.name == "reordered" && (a.Path != "a.txt" || b.Path != "b.txt") {
+			t.Errorf("%s: expected paths, got %+v", tt.name, index.Files)
+		}
+	}
+
+	for _, header := range []string{"key,start\n", "key,start,size,key\n"} {
+		indexPath := filepath.Join(dir, "bad.index")
+		if err := os.WriteFile(indexPath, []byte(header), 0644); err != nil {
+			t.Fatalf("Failed to write index file: %v", err)
+		}
+		if _, err := ReadTarIndex(indexPath); err == nil || !strings.Contains(err.Error(), "unexpected CSV header") {
+			t.Errorf("Expected header error for %q, got %v", header, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package tarix
+
+// CompactIndex rewrites the index at indexPath without stale rows, as left
+// by tools appending rows to an index to update it: of rows with the same
+// key only the last is kept, as when loading the index, and rows starting
+// past the end of the archive, if its size is recorded, are dropped. Its
+// checksum isn't verified, since appended rows invalidate it, and a new one
+// is written. The index keeps its delimiter, path prefixes and row order. It
+// returns the number of rows removed.
+func CompactIndex(indexPath string) (int, error) {
+	sc, err := OpenIndexScanner(indexPath, LoadOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer sc.Close()
+	sc.lenient = true
+
+	files := map[string]FileIndex{}
+	rows := 0
+	byKey, byStart := true, true
+	var lastKey string
+	var lastStart int64
+	for sc.Scan() {
+		key, fileInfo := sc.Entry()
+		if rows > 0 {
+			byKey = byKey && key >= lastKey
+			byStart = byStart && fileInfo.Start >= lastStart
+		}
+		lastKey, lastStart = key, fileInfo.Start
+		files[key] = fileInfo
+		rows++
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	index, err := sc.Index()
+	if err != nil {
+		return 0, err
+	}
+	index.Files = files
+	// The index is replaced by renaming over it, which Windows refuses for
+	// open files
+	sc.Close()
+
+	enc := indexEncoding{
+		delimiter:    sc.reader.Comma,
+		byOffset:     byStart && !byKey,
+		pathPrefixes: len(sc.prefixes) > 0,
+	}
+	if err := writeTarIndex(index, indexPath, enc); err != nil {
+		return 0, err
+	}
+	return rows - len(files) + sc.pastEnd, nil
+}
@@ -0,0 +1,89 @@
+package tarix
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCompactIndex(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "compact.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "aaa", "b.txt": "bbbb", "c.txt": "c"})
+
+	for _, tc := range []struct {
+		name string
+		opts IndexOptions
+	}{
+		{"default", IndexOptions{}},
+		{"tab by offset", IndexOptions{Delimiter: '\t', ByOffset: true}},
+		{"path prefixes", IndexOptions{StorePaths: true, PathPrefixes: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			indexPath := filepath.Join(t.TempDir(), "compact.tar.index.json")
+			if err := CreateTarIndexWithOptions(tarFilePath, indexPath, tc.opts); err != nil {
+				t.Fatalf("Failed to create index: %v", err)
+			}
+			clean, err := os.ReadFile(indexPath)
+			if err != nil {
+				t.Fatalf("Failed to read index: %v", err)
+			}
+
+			// A clean index is rewritten as it is
+			if removed, err := CompactIndex(indexPath); err != nil || removed != 0 {
+				t.Fatalf("Expected no rows removed, got %d, %v", removed, err)
+			}
+			if compacted, _ := os.ReadFile(indexPath); !bytes.Equal(compacted, clean) {
+				t.Errorf("Expected a clean index to be unchanged, got:\n%s\nwant:\n%s", compacted, clean)
+			}
+
+			// Append a stale row for a.txt and a row past the end, as a tool
+			// updating the index in place would
+			index, err := ReadTarIndex(indexPath)
+			if err != nil {
+				t.Fatalf("Failed to read index: %v", err)
+			}
+			a := index.Files[index.Key("a.txt")]
+			delimiter := ","
+			if tc.opts.Delimiter != 0 {
+				delimiter = string(tc.opts.Delimiter)
+			}
+			header, _, _ := strings.Cut(string(clean), "\n")
+			columns := len(strings.Split(header, delimiter))
+			row := func(key string, start, size int64) []byte {
+				fields := append([]string{key, strconv.FormatInt(start, 10), strconv.FormatInt(size, 10)}, make([]string, columns-3)...)
+				return []byte(strings.Join(fields, delimiter) + "\n")
+			}
+			appended := append(append([]byte{}, clean...), row(index.Key("a.txt"), a.Start, 2)...)
+			appended = append(appended, row(index.Key("gone.txt"), index.ArchiveSize+512, 5)...)
+			if err := os.WriteFile(indexPath, appended, 0644); err != nil {
+				t.Fatalf("Failed to write index: %v", err)
+			}
+
+			removed, err := CompactIndex(indexPath)
+			if err != nil {
+				t.Fatalf("Failed to compact index: %v", err)
+			}
+			if removed != 2 {
+				t.Errorf("Expected 2 rows removed, got %d", removed)
+			}
+			compacted, err := ReadTarIndex(indexPath)
+			if err != nil {
+				t.Fatalf("Failed to read compacted index: %v", err)
+			}
+			if compacted.Len() != 3 || compacted.Contains("gone.txt") {
+				t.Errorf("Unexpected entries %v", compacted.Keys())
+			}
+			// The last row of a key wins
+			if fi, _ := compacted.Get(compacted.Key("a.txt")); fi.Start != a.Start || fi.Size != 2 {
+				t.Errorf("Expected the appended row of a.txt, got %+v", fi)
+			}
+			if fi, _ := compacted.Get(compacted.Key("b.txt")); tc.opts.StorePaths && fi.Path != "b.txt" {
+				t.Errorf("Expected the path of b.txt to be kept, got %+v", fi)
+			}
+		})
+	}
+}
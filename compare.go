@@ -0,0 +1,92 @@
+package tarix
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// FieldMismatch is a field with different values in two indexes. Key is
+// empty for fields of the indexes themselves, like the archive size.
+type FieldMismatch struct {
+	Key   string
+	Field string
+	A, B  string
+}
+
+// IndexComparison lists how two indexes of the same archive differ
+type IndexComparison struct {
+	// Metadata holds the differing fields of the indexes themselves
+	Metadata []FieldMismatch
+	// OnlyInA and OnlyInB hold the keys of entries missing from the other index
+	OnlyInA, OnlyInB []string
+	// Entries holds the differing fields of entries in both indexes
+	Entries []FieldMismatch
+}
+
+// Equal reports whether the indexes describe the archive the same way
+func (c *IndexComparison) Equal() bool {
+	return len(c.Metadata) == 0 && len(c.OnlyInA) == 0 && len(c.OnlyInB) == 0 && len(c.Entries) == 0
+}
+
+// CompareIndexes compares every field of a and b and of each of their
+// entries, e.g. to check that indexes of the same archive written in
+// different formats agree. Keys are listed in order.
+func CompareIndexes(a, b *TarIndex) *IndexComparison {
+	c := &IndexComparison{}
+	metadata := func(field string, va, vb string) {
+		if va != vb {
+			c.Metadata = append(c.Metadata, FieldMismatch{Field: field, A: va, B: vb})
+		}
+	}
+	metadata("format", a.Format.String(), b.Format.String())
+	metadata("keys", a.KeyScheme, b.KeyScheme)
+	metadata("root", a.Root, b.Root)
+	metadata("trailer", strconv.FormatBool(a.Trailer), strconv.FormatBool(b.Trailer))
+	metadata("archive", a.ArchiveName, b.ArchiveName)
+	metadata("size", strconv.FormatInt(a.ArchiveSize, 10), strconv.FormatInt(b.ArchiveSize, 10))
+	metadata("entries", strconv.FormatInt(a.EntryCount, 10), strconv.FormatInt(b.EntryCount, 10))
+	metadata("occurrences", strconv.FormatBool(a.Occurrences), strconv.FormatBool(b.Occurrences))
+	metadata("normalize", string(a.Normalize), string(b.Normalize))
+
+	a.eachSorted(func(key string, fa FileIndex) {
+		fb, ok := b.lookup(key)
+		if !ok {
+			c.OnlyInA = append(c.OnlyInA, key)
+			return
+		}
+		field := func(field string, va, vb string) {
+			if va != vb {
+				c.Entries = append(c.Entries, FieldMismatch{Key: key, Field: field, A: va, B: vb})
+			}
+		}
+		field("start", strconv.FormatInt(fa.Start, 10), strconv.FormatInt(fb.Start, 10))
+		field("size", strconv.FormatInt(fa.Size, 10), strconv.FormatInt(fb.Size, 10))
+		field("path", fa.Path, fb.Path)
+		field("checksum", fa.ContentHash, fb.ContentHash)
+		field("compressed", strconv.FormatBool(fa.Compressed), strconv.FormatBool(fb.Compressed))
+		field("headers", strconv.Itoa(fa.HeaderBlocks), strconv.Itoa(fb.HeaderBlocks))
+		field("mime", fa.MIMEType, fb.MIMEType)
+		field("nonextractable", strconv.FormatBool(fa.NonExtractable), strconv.FormatBool(fb.NonExtractable))
+		field("sparse", strconv.FormatBool(fa.Sparse), strconv.FormatBool(fb.Sparse))
+	})
+	b.eachSorted(func(key string, _ FileIndex) {
+		if _, ok := a.lookup(key); !ok {
+			c.OnlyInB = append(c.OnlyInB, key)
+		}
+	})
+	return c
+}
+
+// CompareCSVToJSON loads the CSV index at csvPath and the JSON index at
+// jsonPath and compares them with CompareIndexes, the CSV one being a
+func CompareCSVToJSON(csvPath, jsonPath string) (*IndexComparison, error) {
+	csvIndex, err := ReadTarIndex(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV index: %w", err)
+	}
+	jsonIndex, err := ReadTarIndexJSON(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON index: %w", err)
+	}
+	return CompareIndexes(csvIndex, jsonIndex), nil
+}
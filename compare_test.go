@@ -0,0 +1,86 @@
+package tarix
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompareCSVToJSON(t *testing.T) {
+	tarFilePath, _ := createIndexedTar(t, map[string]string{
+		"a.txt": "alpha",
+		"b.txt": "beta",
+		"c.txt": "gamma",
+	})
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "index.csv")
+	if err := CreateTarIndexWithOptions(tarFilePath, csvPath, IndexOptions{StorePaths: true, ContentHash: true}); err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	index, err := ReadTarIndexWithOptions(csvPath, LoadOptions{Sorted: true})
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	jsonPath := filepath.Join(dir, "index.json")
+	if err := WriteTarIndexJSON(index, jsonPath); err != nil {
+		t.Fatalf("Failed to write JSON index: %v", err)
+	}
+	comparison, err := CompareCSVToJSON(csvPath, jsonPath)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if !comparison.Equal() {
+		t.Errorf("Expected the JSON index to match, got %+v", comparison)
+	}
+
+	// Lookups work on the JSON index too
+	jsonIndex, err := ReadTarIndexJSON(jsonPath)
+	if err != nil {
+		t.Fatalf("Failed to read JSON index: %v", err)
+	}
+	if !jsonIndex.Contains("b.txt") {
+		t.Error("Expected b.txt in the JSON index")
+	}
+
+	// Drift between the writers shows up field by field
+	keyA, keyB, keyC := index.Key("a.txt"), index.Key("b.txt"), index.Key("c.txt")
+	a, _ := jsonIndex.Get(keyA)
+	a.Size++
+	a.Path = "moved.txt"
+	jsonIndex.Files[keyA] = a
+	delete(jsonIndex.Files, keyB)
+	jsonIndex.Files["extra"] = FileIndex{Start: 0, Size: 1}
+	jsonIndex.Root = "data"
+	if err := WriteTarIndexJSON(jsonIndex, jsonPath); err != nil {
+		t.Fatalf("Failed to write JSON index: %v", err)
+	}
+	comparison, err = CompareCSVToJSON(csvPath, jsonPath)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	want := &IndexComparison{
+		Metadata: []FieldMismatch{{Field: "root", A: "", B: "data"}},
+		OnlyInA:  []string{keyB},
+		OnlyInB:  []string{"extra"},
+		Entries: []FieldMismatch{
+			{Key: keyA, Field: "size", A: "5", B: "6"},
+			{Key: keyA, Field: "path", A: "a.txt", B: "moved.txt"},
+		},
+	}
+	if comparison.Equal() || !reflect.DeepEqual(comparison, want) {
+		t.Errorf("Unexpected comparison\n got %+v\nwant %+v", comparison, want)
+	}
+	if _, ok := jsonIndex.Get(keyC); !ok {
+		t.Error("Expected c.txt to be kept")
+	}
+
+	// Corrupt JSON indexes are rejected
+	if err := os.WriteFile(jsonPath, []byte(`{"files": {"x": {"start": -1, "size": 1}}}`), 0644); err != nil {
+		t.Fatalf("Failed to write JSON index: %v", err)
+	}
+	if _, err := CompareCSVToJSON(csvPath, jsonPath); err == nil {
+		t.Error("Expected a negative start to be rejected")
+	}
+}
@@ -0,0 +1,263 @@
+package tarix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ConvertIndex writes the index at inPath to outPath in the other format:
+// a CSV index as JSON, in the format of WriteTarIndexJSON, and a JSON index
+// as CSV. Entries are converted one at a time, so memory use doesn't grow
+// with their number. It returns the number of entries converted and
+// whether the index was converted to JSON.
+func ConvertIndex(inPath, outPath string) (n int, toJSON bool, err error) {
+	isJSON, err := isJSONIndex(inPath)
+	if err != nil {
+		return 0, false, err
+	}
+	if isJSON {
+		n, err = ConvertJSONIndexToCSV(inPath, outPath)
+		return n, false, err
+	}
+	n, err = ConvertCSVIndexToJSON(inPath, outPath)
+	return n, true, err
+}
+
+// isJSONIndex reports whether the index at indexPath is a JSON one, which
+// starts with "{" where a CSV one starts with its header
+func isJSONIndex(indexPath string) (bool, error) {
+	file, err := os.Open(indexPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to open index file: %w", err)
+	}
+	defer file.Close()
+	br := bufio.NewReader(file)
+	skipBOM(br)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read index file: %w", err)
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b == '{', nil
+	}
+}
+
+// ConvertCSVIndexToJSON writes the CSV index at csvPath to jsonPath in the
+// format of WriteTarIndexJSON, streaming the entries from the CSV straight
+// into the JSON "files" object. It returns the number of entries.
+func ConvertCSVIndexToJSON(csvPath, jsonPath string) (int, error) {
+	sc, err := OpenIndexScanner(csvPath, LoadOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer sc.Close()
+
+	n := 0
+	err = writeIndexFile(jsonPath, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		bw.WriteString(`{"files":{`)
+		for sc.Scan() {
+			key, fileInfo := sc.Entry()
+			keyJSON, err := json.Marshal(key)
+			if err != nil {
+				return fmt.Errorf("failed to write index file: %w", err)
+			}
+			entryJSON, err := json.Marshal(fileInfo)
+			if err != nil {
+				return fmt.Errorf("failed to write index file: %w", err)
+			}
+			if n > 0 {
+				bw.WriteByte(',')
+			}
+			bw.WriteString("\n  ")
+			bw.Write(keyJSON)
+			bw.WriteByte(':')
+			bw.Write(entryJSON)
+			n++
+		}
+		if err := sc.Err(); err != nil {
+			return err
+		}
+
+		// The metadata is complete at the end of the index. Its JSON has the
+		// files first, which were written above.
+		meta := *sc.index
+		meta.Files = nil
+		metaJSON, err := json.Marshal(&meta)
+		if err != nil {
+			return fmt.Errorf("failed to write index file: %w", err)
+		}
+		rest, ok := bytes.CutPrefix(metaJSON, []byte(`{"files":null`))
+		if !ok {
+			return fmt.Errorf("failed to write index file: unexpected metadata %s", metaJSON)
+		}
+		bw.WriteString("\n}")
+		bw.Write(rest)
+		bw.WriteByte('\n')
+		if err := bw.Flush(); err != nil {
+			return fmt.Errorf("failed to write index file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ConvertJSONIndexToCSV writes the JSON index at jsonPath, as written by
+// WriteTarIndexJSON, to csvPath in CSV format. The columns and metadata
+// rows of the CSV precede its entries but depend on all of the JSON, so it
+// is read twice, once for them and once for the entries. Entries keep
+// their order in the JSON. It returns the number of entries.
+func ConvertJSONIndexToCSV(jsonPath, csvPath string) (int, error) {
+	file, err := os.Open(jsonPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open index file: %w", err)
+	}
+	defer file.Close()
+
+	var columns optionalColumns
+	index, err := scanJSONIndex(file, func(_ string, fileInfo FileIndex) error {
+		columns.add(fileInfo)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to seek index file: %w", err)
+	}
+
+	n := 0
+	err = writeIndexFile(csvPath, func(w io.Writer) error {
+		writer := csv.NewWriter(w)
+		checksum := newIndexChecksum()
+		header := columns.header(false)
+		writer.Write(header)
+		checksum.add(header)
+		for _, record := range metadataRows(index, nil) {
+			record = escapeFields(record)
+			writer.Write(record)
+			checksum.add(record)
+		}
+		_, err := scanJSONIndex(file, func(key string, fileInfo FileIndex) error {
+			if err := validateEntry(key, fileInfo.Start, fileInfo.Size, index.ArchiveSize); err != nil {
+				return err
+			}
+			record := escapeFields(columns.record(key, fileInfo, nil))
+			writer.Write(record)
+			checksum.add(record)
+			n++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		writer.Write([]string{checksumRowKey, checksum.sum()})
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("failed to write index file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// scanJSONIndex decodes the JSON index in r, calling fn with each entry in
+// turn, and returns the metadata of the index, with no entries
+func scanJSONIndex(r io.Reader, fn func(key string, fileInfo FileIndex) error) (*TarIndex, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	corrupt := func(err error) error {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("%w: %v", ErrIndexCorrupt, err)
+	}
+	delim := func(want json.Delim) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return corrupt(err)
+		}
+		if tok != want {
+			return corrupt(fmt.Errorf("expected %v, got %v", want, tok))
+		}
+		return nil
+	}
+
+	if err := delim('{'); err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, corrupt(err)
+		}
+		name, _ := tok.(string)
+		if name != "files" {
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, corrupt(err)
+			}
+			fields[name] = value
+			continue
+		}
+
+		if err := delim('{'); err != nil {
+			return nil, err
+		}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, corrupt(err)
+			}
+			key, _ := tok.(string)
+			var fileInfo FileIndex
+			if err := dec.Decode(&fileInfo); err != nil {
+				return nil, corrupt(err)
+			}
+			if fileInfo.HeaderBlocks < 0 || int64(fileInfo.HeaderBlocks) > fileInfo.Start/headerSize {
+				return nil, fmt.Errorf("%w: header blocks of %s start before the archive", ErrIndexCorrupt, key)
+			}
+			if err := fn(key, fileInfo); err != nil {
+				return nil, err
+			}
+		}
+		if err := delim('}'); err != nil {
+			return nil, err
+		}
+	}
+	if err := delim('}'); err != nil {
+		return nil, err
+	}
+
+	metaJSON, err := json.Marshal(fields)
+	if err != nil {
+		return nil, corrupt(err)
+	}
+	index := &TarIndex{}
+	if err := json.Unmarshal(metaJSON, index); err != nil {
+		return nil, corrupt(err)
+	}
+	if _, err := ParseNormForm(string(index.Normalize)); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
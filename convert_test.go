@@ -0,0 +1,132 @@
+package tarix
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertIndex(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "archive.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"top/a.txt":        "hello",
+		"top/sub/b.html":   "<html><body>b</body></html>",
+		"top/sub/c, d.txt": "comma",
+		"top/empty.txt":    "",
+	})
+
+	for _, tc := range []struct {
+		name string
+		opts IndexOptions
+	}{
+		{"default", IndexOptions{}},
+		{"paths and checksums", IndexOptions{StorePaths: true, ContentHash: true, MIMETypes: true, Root: "top"}},
+		{"prefixes tab", IndexOptions{StorePaths: true, PathPrefixes: true, Delimiter: '\t', Normalize: NFC}},
+		{"path keys", IndexOptions{KeyScheme: PathKeyScheme, Occurrences: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			csvPath := filepath.Join(t.TempDir(), "index.csv")
+			if err := CreateTarIndexWithOptions(tarFilePath, csvPath, tc.opts); err != nil {
+				t.Fatalf("Failed to create TAR index: %v", err)
+			}
+			want, err := ReadTarIndex(csvPath)
+			if err != nil {
+				t.Fatalf("Failed to read index: %v", err)
+			}
+
+			jsonPath := filepath.Join(t.TempDir(), "index.json")
+			n, toJSON, err := ConvertIndex(csvPath, jsonPath)
+			if err != nil {
+				t.Fatalf("Failed to convert to JSON: %v", err)
+			}
+			if !toJSON || n != want.Len() {
+				t.Errorf("Expected %d entries converted to JSON, got %d (to JSON: %v)", want.Len(), n, toJSON)
+			}
+			fromJSON, err := ReadTarIndexJSON(jsonPath)
+			if err != nil {
+				t.Fatalf("Failed to read JSON index: %v", err)
+			}
+			if c := CompareIndexes(want, fromJSON); !c.Equal() {
+				t.Errorf("JSON index differs: %+v", c)
+			}
+
+			backPath := filepath.Join(t.TempDir(), "index.csv")
+			n, toJSON, err = ConvertIndex(jsonPath, backPath)
+			if err != nil {
+				t.Fatalf("Failed to convert to CSV: %v", err)
+			}
+			if toJSON || n != want.Len() {
+				t.Errorf("Expected %d entries converted to CSV, got %d (to JSON: %v)", want.Len(), n, toJSON)
+			}
+			back, err := ReadTarIndex(backPath)
+			if err != nil {
+				t.Fatalf("Failed to read converted CSV index: %v", err)
+			}
+			if c := CompareIndexes(want, back); !c.Equal() {
+				t.Errorf("CSV index differs after a round trip: %+v", c)
+			}
+		})
+	}
+}
+
+func TestConvertJSONIndexToCSV(t *testing.T) {
+	// Keys are whatever the key scheme made of the paths
+	index := &TarIndex{
+		Files: map[string]FileIndex{
+			"line\r\nbreak": {Start: 0, Size: 3, Path: "line\r\nbreak"},
+			"#hash":         {Start: 1024, Size: 5, NonExtractable: true},
+			` "quoted"`:     {Start: 2048, Size: 1, HeaderBlocks: 2, Compressed: true},
+		},
+		KeyScheme:   PathKeyScheme.Name,
+		ArchiveSize: 10240,
+		Trailer:     true,
+	}
+	jsonPath := filepath.Join(t.TempDir(), "index.json")
+	if err := WriteTarIndexJSON(index, jsonPath); err != nil {
+		t.Fatalf("Failed to write JSON index: %v", err)
+	}
+
+	csvPath := filepath.Join(t.TempDir(), "index.csv")
+	n, err := ConvertJSONIndexToCSV(jsonPath, csvPath)
+	if err != nil {
+		t.Fatalf("Failed to convert: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 entries, got %d", n)
+	}
+	loaded, err := ReadTarIndex(csvPath)
+	if err != nil {
+		t.Fatalf("Failed to read converted index: %v", err)
+	}
+	if c := CompareIndexes(index, loaded); !c.Equal() {
+		t.Errorf("Converted index differs: %+v", c)
+	}
+}
+
+func TestConvertCorruptJSONIndex(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"truncated":     `{"files":{"a":{"start":0,"size":1}`,
+		"negative":      `{"files":{"a":{"start":-512,"size":1}}}`,
+		"past end":      `{"files":{"a":{"start":4096,"size":1}},"archive_size":2048}`,
+		"bad files":     `{"files":[1,2]}`,
+		"bad metadata":  `{"files":{},"archive_size":"big"}`,
+		"bad normalize": `{"files":{},"normalize":"nfkc"}`,
+	} {
+		jsonPath := filepath.Join(dir, "index.json")
+		if err := os.WriteFile(jsonPath, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write index: %v", err)
+		}
+		csvPath := filepath.Join(dir, "index.csv")
+		if _, err := ConvertJSONIndexToCSV(jsonPath, csvPath); err == nil {
+			t.Errorf("%s: expected an error", name)
+		} else if name != "bad normalize" && !errors.Is(err, ErrIndexCorrupt) {
+			t.Errorf("%s: expected ErrIndexCorrupt, got %v", name, err)
+		}
+		if _, err := os.Stat(csvPath); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: expected no CSV index to be written", name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package tarix
+
+// CoverageReport tells how much of a TAR the index reaches, e.g. after
+// indexing with IndexOptions.Include or Exclude
+type CoverageReport struct {
+	ArchiveSize  int64 // Size of the TAR in bytes
+	ContentBytes int64 // Total size of the indexed files, counting shared data once
+	// Gaps are the byte ranges between consecutive indexed entries, which
+	// hold the headers and data of entries left out of the index, as
+	// directories, links and filtered files, and the end-of-archive trailer
+	Gaps []CoverageGap
+}
+
+// CoverageGap is a range of the TAR outside of any indexed entry
+type CoverageGap struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+// Percent returns ContentBytes as a percentage of ArchiveSize
+func (c CoverageReport) Percent() float64 {
+	if c.ArchiveSize <= 0 {
+		return 0
+	}
+	return float64(c.ContentBytes) * 100 / float64(c.ArchiveSize)
+}
+
+// GapBytes returns the total size of the gaps
+func (c CoverageReport) GapBytes() int64 {
+	var n int64
+	for _, gap := range c.Gaps {
+		n += gap.Size
+	}
+	return n
+}
+
+// Coverage reports how much of a TAR of archiveSize bytes the index covers,
+// without touching the TAR. With archiveSize 0 the size the index records is
+// used. Each indexed entry spans its extended headers, its header and its
+// padded data. Sparse files store less data than their size, which the index
+// doesn't record, so they are taken to span up to the next indexed entry and
+// count at most those bytes as content; a gap right after one is missed.
+func (ti *TarIndex) Coverage(archiveSize int64) CoverageReport {
+	if archiveSize <= 0 {
+		archiveSize = ti.ArchiveSize
+	}
+	report := CoverageReport{ArchiveSize: archiveSize}
+
+	// Deduplicated files share the data at one Start
+	var entries []FileIndex
+	lastStart := int64(-1)
+	ti.eachByOffset(func(_ string, fileInfo FileIndex) {
+		if fileInfo.Start != lastStart {
+			entries = append(entries, fileInfo)
+		}
+		lastStart = fileInfo.Start
+	})
+
+	var end int64
+	for i, fileInfo := range entries {
+		begin := entryBegin(fileInfo)
+		if begin > end {
+			report.Gaps = append(report.Gaps, CoverageGap{Offset: end, Size: begin - end})
+		}
+		entryEnd := begin + PaddedEntryLength(fileInfo)
+		content := fileInfo.Size
+		if fileInfo.Sparse {
+			next := archiveSize
+			if i+1 < len(entries) {
+				next = entryBegin(entries[i+1])
+			}
+			entryEnd = min(entryEnd, max(next, fileInfo.DataOffset()))
+			content = min(content, entryEnd-fileInfo.DataOffset())
+		}
+		report.ContentBytes += content
+		end = max(end, entryEnd)
+	}
+	if archiveSize > end {
+		report.Gaps = append(report.Gaps, CoverageGap{Offset: end, Size: archiveSize - end})
+	}
+	return report
+}
+
+// entryBegin returns the offset of the first extended header of fileInfo
+func entryBegin(fileInfo FileIndex) int64 {
+	return fileInfo.Start - int64(fileInfo.HeaderBlocks)*headerSize
+}
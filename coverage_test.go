@@ -0,0 +1,102 @@
+package tarix
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexCoverage(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "coverage.tar")
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "d/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "d/a.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "d/b.log", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "d/c.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	})
+	tarIndexPath := filepath.Join(dir, "coverage.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Exclude: []string{"*.log"}}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	info, err := os.Stat(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to stat TAR: %v", err)
+	}
+
+	report := index.Coverage(info.Size())
+	if report.ArchiveSize != 4608 || report.ContentBytes != 14 {
+		t.Errorf("Expected 14 of 4608 bytes, got %d of %d", report.ContentBytes, report.ArchiveSize)
+	}
+	// The directory, the excluded file and the trailer
+	expected := []CoverageGap{{0, 512}, {1536, 1024}, {3584, 1024}}
+	if len(report.Gaps) != len(expected) {
+		t.Fatalf("Expected gaps %v, got %v", expected, report.Gaps)
+	}
+	for i, gap := range expected {
+		if report.Gaps[i] != gap {
+			t.Errorf("Expected gap %v at %d, got %v", gap, i, report.Gaps[i])
+		}
+	}
+	if report.GapBytes() != 2560 {
+		t.Errorf("Expected 2560 gap bytes, got %d", report.GapBytes())
+	}
+	if p := report.Percent(); p < 0.30 || p > 0.31 {
+		t.Errorf("Expected about 0.30%%, got %f", p)
+	}
+
+	// The recorded size is used by default
+	if got := index.Coverage(0); got.ArchiveSize != index.ArchiveSize || len(got.Gaps) != 3 {
+		t.Errorf("Unexpected report with the recorded size: %+v", got)
+	}
+}
+
+func TestIndexCoverageSharedData(t *testing.T) {
+	index := &TarIndex{Files: map[string]FileIndex{
+		"a": {Start: 512, Size: 100},
+		"b": {Start: 512, Size: 100},
+		"c": {Start: 3072, Size: 10, HeaderBlocks: 2},
+	}}
+	report := index.Coverage(4096)
+	if report.ContentBytes != 110 {
+		t.Errorf("Expected shared data counted once, got %d bytes", report.ContentBytes)
+	}
+	expected := []CoverageGap{{0, 512}, {1536, 512}}
+	if len(report.Gaps) != len(expected) || report.Gaps[0] != expected[0] || report.Gaps[1] != expected[1] {
+		t.Errorf("Expected gaps %v, got %v", expected, report.Gaps)
+	}
+	if (&TarIndex{}).Coverage(0).Percent() != 0 {
+		t.Errorf("Expected 0%% without an archive size")
+	}
+}
+
+func TestIndexCoverageSparse(t *testing.T) {
+	// See TestCreateTarIndexSparse for the contents of sparse-gnu.tar
+	tarFilePath := filepath.Join("testdata", "sparse-gnu.tar")
+	tarIndexPath := filepath.Join(t.TempDir(), "sparse-gnu.tar.index")
+	if err := CreateTarIndex(tarFilePath, tarIndexPath); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	// The 1MB sparse file counts only what is stored before after.txt
+	report := index.Coverage(0)
+	if report.ContentBytes != 8192+14+13 {
+		t.Errorf("Expected %d content bytes, got %d", 8192+14+13, report.ContentBytes)
+	}
+	if p := report.Percent(); p > 100 {
+		t.Errorf("Expected at most 100%%, got %f", p)
+	}
+	// The trailer is still found after the last file
+	if len(report.Gaps) != 1 || report.Gaps[0] != (CoverageGap{10752, 9728}) {
+		t.Errorf("Expected the trailer as the only gap, got %v", report.Gaps)
+	}
+}
@@ -0,0 +1,48 @@
+package tarix
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// quotedFieldPrefix marks index fields written Go-quoted by escapeFields.
+// Archive paths end at their first NUL, so no path starts with one.
+const quotedFieldPrefix = "\x00"
+
+// escapeFields returns record with the fields encoding/csv can't round-trip
+// Go-quoted behind quotedFieldPrefix. Only "\r\n" is affected, which the CSV
+// reader turns into "\n" even in quoted fields; commas, quotes and newlines
+// are quoted by the CSV writer. Records without such fields are returned as
+// they are, so indexes of ordinary archives look the same as before.
+func escapeFields(record []string) []string {
+	var escaped []string
+	for i, field := range record {
+		if !strings.Contains(field, "\r\n") && !strings.HasPrefix(field, quotedFieldPrefix) {
+			continue
+		}
+		if escaped == nil {
+			escaped = append([]string{}, record...)
+		}
+		escaped[i] = quotedFieldPrefix + strconv.Quote(field)
+	}
+	if escaped == nil {
+		return record
+	}
+	return escaped
+}
+
+// unescapeFields reverses escapeFields in place
+func unescapeFields(record []string) error {
+	for i, field := range record {
+		if !strings.HasPrefix(field, quotedFieldPrefix) {
+			continue
+		}
+		unquoted, err := strconv.Unquote(field[len(quotedFieldPrefix):])
+		if err != nil {
+			return fmt.Errorf("%w: invalid quoted field %q", ErrIndexCorrupt, field)
+		}
+		record[i] = unquoted
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package tarix
+
+import (
+	"archive/tar"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAwkwardPathsRoundTrip(t *testing.T) {
+	names := []string{
+		"with,comma.txt",
+		`with "quotes".txt`,
+		"new\nline.txt",
+		"crlf\r\nname.txt",
+		"cr\ronly.txt",
+		" leading space.txt",
+		"dir,a/sub\nb/x.txt",
+		"dir,a/sub\nb/y.txt",
+		"#hash.txt",
+	}
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "awkward.tar")
+	var headers []*tar.Header
+	for _, name := range names {
+		headers = append(headers, &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644})
+	}
+	// Each file's content is its name
+	writeTarWithDirs(t, tarFilePath, headers)
+
+	for _, tc := range []struct {
+		name string
+		opts IndexOptions
+	}{
+		{"default", IndexOptions{}},
+		{"paths", IndexOptions{StorePaths: true}},
+		{"prefixes", IndexOptions{StorePaths: true, PathPrefixes: true}},
+		{"path keys", IndexOptions{StorePaths: true, KeyScheme: PathKeyScheme}},
+		{"tab by offset", IndexOptions{StorePaths: true, Delimiter: '\t', ByOffset: true}},
+		{"checkpointed", IndexOptions{StorePaths: true, CheckpointEvery: 2}},
+		{"root", IndexOptions{StorePaths: true, Root: "dir,a"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			indexPath := filepath.Join(t.TempDir(), "index.csv")
+			if err := CreateTarIndexWithOptions(tarFilePath, indexPath, tc.opts); err != nil {
+				t.Fatalf("Failed to create index: %v", err)
+			}
+			th, err := NewTarixHandle(tarFilePath, indexPath)
+			if err != nil {
+				t.Fatalf("Failed to open handle: %v", err)
+			}
+			defer th.Close()
+
+			var stored []string
+			for _, name := range names {
+				lookup := name
+				if tc.opts.Root != "" {
+					if !strings.HasPrefix(name, tc.opts.Root+"/") {
+						continue
+					}
+					lookup = strings.TrimPrefix(name, tc.opts.Root+"/")
+				}
+				data, err := th.ExtractBytesOfFile(lookup)
+				if err != nil {
+					t.Errorf("Failed to extract %q: %v", lookup, err)
+					continue
+				}
+				if string(data) != name {
+					t.Errorf("Extracted %q from %q", data, lookup)
+				}
+				fi, _ := th.Stat(lookup)
+				if tc.opts.StorePaths && fi.Path != lookup {
+					t.Errorf("Stored path %q, want %q", fi.Path, lookup)
+				}
+				stored = append(stored, fi.Path)
+			}
+			if !tc.opts.StorePaths {
+				return
+			}
+
+			// Stored paths find their files again
+			memFS := NewMemFS()
+			if err := ExtractMany(tarFilePath, indexPath, stored, "out", ExtractOptions{FS: memFS}); err != nil {
+				t.Fatalf("Failed to extract stored paths: %v", err)
+			}
+			for _, path := range stored {
+				if f := memFS.Files[filepath.Join("out", path)]; f == nil || !strings.HasSuffix(string(f.Data), path) {
+					t.Errorf("File %q not extracted", path)
+				}
+			}
+		})
+	}
+}
+
+func TestEscapeFields(t *testing.T) {
+	record := []string{"a\r\nb", "plain", "\x00starts with NUL", "comma,\"quote\"\n"}
+	escaped := escapeFields(record)
+	if escaped[1] != "plain" || escaped[3] != record[3] || escaped[0] == record[0] {
+		t.Errorf("Unexpected escaping %q", escaped)
+	}
+	if err := unescapeFields(escaped); err != nil {
+		t.Fatalf("Failed to unescape: %v", err)
+	}
+	for i := range record {
+		if escaped[i] != record[i] {
+			t.Errorf("Field %d round-tripped to %q, want %q", i, escaped[i], record[i])
+		}
+	}
+	if plain := []string{"a", "b"}; &escapeFields(plain)[0] != &plain[0] {
+		t.Error("Expected records without awkward fields to be returned as they are")
+	}
+	if err := unescapeFields([]string{"\x00not quoted"}); err == nil {
+		t.Error("Expected an invalid quoted field to fail")
+	}
+}
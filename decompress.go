@@ -0,0 +1,89 @@
+package tarix
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Decompressor wraps the compressed data of a file in a reader of its contents
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[string]Decompressor{
+		".gz": func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	}
+)
+
+// RegisterDecompressor makes OpenFileDecompressed decompress files whose path
+// ends in ext (e.g. ".zst") with fn. Extensions are matched case-insensitively;
+// registering one again replaces it.
+func RegisterDecompressor(ext string, fn Decompressor) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	decompressors[strings.ToLower(ext)] = fn
+}
+
+// UnregisterDecompressor removes the Decompressor of ext, so files with the
+// extension are read as they are again
+func UnregisterDecompressor(ext string) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	delete(decompressors, strings.ToLower(ext))
+}
+
+// OpenFileDecompressed returns a reader of the contents of filePath, which
+// decompresses it if its extension has a registered Decompressor, like ".gz"
+// for gzip. Other files are read as they are.
+func (th *TarixHandle) OpenFileDecompressed(filePath string) (io.ReadCloser, error) {
+	key := th.key(filePath)
+	fileInfo, err := th.fileEntry(key)
+	if err != nil {
+		return nil, err
+	}
+	sr, err := th.openEntry(key, fileInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	decompress, ok := decompressorFor(filePath)
+	if !ok {
+		return io.NopCloser(sr), nil
+	}
+
+	rc, err := decompress(sr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", filePath, err)
+	}
+	return rc, nil
+}
+
+// Locate returns the index entry of filePath and whether the file is stored
+// compressed, in which case OpenFileDecompressed reads its contents. The flag
+// is set at index time from the extensions with a registered Decompressor.
+func (th *TarixHandle) Locate(filePath string) (FileIndex, bool, error) {
+	fileInfo, err := th.fileEntry(th.key(filePath))
+	if err != nil {
+		return FileIndex{}, false, err
+	}
+	return fileInfo, fileInfo.Compressed, nil
+}
+
+// decompressorFor returns the Decompressor registered for the extension of filePath
+func decompressorFor(filePath string) (Decompressor, bool) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	decompressorsMu.RLock()
+	defer decompressorsMu.RUnlock()
+	decompress, ok := decompressors[ext]
+	return decompress, ok
+}
+
+// isCompressed reports whether filePath has the extension of a registered Decompressor
+func isCompressed(filePath string) bool {
+	_, ok := decompressorFor(filePath)
+	return ok
+}
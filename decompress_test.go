@@ -0,0 +1,137 @@
+package tarix
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenFileDecompressed(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("compressed content"))
+	zw.Close()
+
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "gz.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"a.txt.gz": gz.String(),
+		"b.txt":    "plain content",
+		"c.TXT.GZ": gz.String(),
+		"d.rev":    "desrever",
+	})
+	tarIndexPath := filepath.Join(dir, "gz.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// A custom codec, reversing the data
+	RegisterDecompressor(".rev", func(r io.Reader) (io.ReadCloser, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(string(data))
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return io.NopCloser(strings.NewReader(string(runes))), nil
+	})
+	t.Cleanup(func() { UnregisterDecompressor(".rev") })
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	for name, content := range map[string]string{
+		"a.txt.gz": "compressed content",
+		"b.txt":    "plain content",
+		"c.TXT.GZ": "compressed content",
+		"d.rev":    "reversed",
+	} {
+		rc, err := th.OpenFileDecompressed(name)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+
+	// Unregistered, the codec no longer applies
+	UnregisterDecompressor(".REV")
+	rc, err := th.OpenFileDecompressed("d.rev")
+	if err != nil {
+		t.Fatalf("Failed to open d.rev: %v", err)
+	}
+	defer rc.Close()
+	if data, err := io.ReadAll(rc); err != nil || string(data) != "desrever" {
+		t.Errorf("Expected d.rev as it is, got %q, %v", data, err)
+	}
+}
+
+func TestLocateCompressed(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "mixed.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"a.txt.gz": "not really gzip",
+		"b.txt":    "plain content",
+	})
+	tarIndexPath := filepath.Join(dir, "mixed.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	raw, err := os.ReadFile(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index file: %v", err)
+	}
+	if !bytes.HasPrefix(raw, []byte("key,start,size,compressed\n")) {
+		t.Errorf("Expected a compressed column, got %q", raw)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	for name, compressed := range map[string]bool{"a.txt.gz": true, "b.txt": false} {
+		fileInfo, gotCompressed, err := th.Locate(name)
+		if err != nil {
+			t.Fatalf("Failed to locate %s: %v", name, err)
+		}
+		if gotCompressed != compressed || fileInfo.Compressed != compressed {
+			t.Errorf("Expected %s compressed %v, got %v", name, compressed, gotCompressed)
+		}
+		if fileInfo.Size != th.Index.Files[hashFilePath(name)].Size {
+			t.Errorf("Unexpected entry of %s: %+v", name, fileInfo)
+		}
+	}
+	if _, _, err := th.Locate("missing.gz"); err == nil {
+		t.Error("Expected error locating a missing file")
+	}
+
+	// Indexes without the column have nothing marked compressed
+	legacy := "key,start,size\n" + hashFilePath("a.txt.gz") + ",0,15\n"
+	if err := os.WriteFile(tarIndexPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write index file: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read legacy index: %v", err)
+	}
+	if index.Files[hashFilePath("a.txt.gz")].Compressed {
+		t.Error("Expected no file marked compressed in a legacy index")
+	}
+}
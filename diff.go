@@ -0,0 +1,56 @@
+package tarix
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DiffIndexes compares the indexes at oldPath and newPath by key. A file is
+// changed if its size differs, or its content checksum when both indexes have
+// one; offsets are not compared. Files are named by their stored path, or by
+// their key if the index has none. Each group is sorted.
+func DiffIndexes(oldPath, newPath string) (added, removed, changed []string, err error) {
+	oldIndex, err := ReadTarIndex(oldPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	newIndex, err := ReadTarIndex(newPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if oldIndex.KeyScheme != newIndex.KeyScheme {
+		return nil, nil, nil, fmt.Errorf("cannot compare indexes with different key schemes %q and %q", oldIndex.KeyScheme, newIndex.KeyScheme)
+	}
+	if oldIndex.Normalize != newIndex.Normalize {
+		return nil, nil, nil, fmt.Errorf("cannot compare indexes with different normalization forms %q and %q", oldIndex.Normalize, newIndex.Normalize)
+	}
+
+	newIndex.each(func(key string, newInfo FileIndex) {
+		oldInfo, ok := oldIndex.lookup(key)
+		switch {
+		case !ok:
+			added = append(added, displayName(key, newInfo))
+		case oldInfo.Size != newInfo.Size,
+			oldInfo.ContentHash != "" && newInfo.ContentHash != "" && oldInfo.ContentHash != newInfo.ContentHash:
+			changed = append(changed, displayName(key, newInfo))
+		}
+	})
+	oldIndex.each(func(key string, oldInfo FileIndex) {
+		if _, ok := newIndex.lookup(key); !ok {
+			removed = append(removed, displayName(key, oldInfo))
+		}
+	})
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed, nil
+}
+
+// displayName names a file by its stored path, falling back to its key
+func displayName(key string, fileInfo FileIndex) string {
+	if fileInfo.Path != "" {
+		return fileInfo.Path
+	}
+	return key
+}
@@ -0,0 +1,57 @@
+package tarix
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffIndexes(t *testing.T) {
+	dir := t.TempDir()
+	oldTar := filepath.Join(dir, "old.tar")
+	writeTestTar(t, oldTar, map[string]string{
+		"same.txt":    "same",
+		"resized.txt": "short",
+		"edited.txt":  "abc",
+		"removed.txt": "gone",
+	})
+	newTar := filepath.Join(dir, "new.tar")
+	writeTestTar(t, newTar, map[string]string{
+		"added.txt":   "new",
+		"same.txt":    "same",
+		"resized.txt": "much longer",
+		"edited.txt":  "xyz",
+	})
+
+	for _, checksum := range []bool{false, true} {
+		opts := IndexOptions{StorePaths: true, ContentHash: checksum}
+		oldIndex := filepath.Join(dir, "old.index.json")
+		newIndex := filepath.Join(dir, "new.index.json")
+		if err := CreateTarIndexWithOptions(oldTar, oldIndex, opts); err != nil {
+			t.Fatalf("Failed to create TAR index: %v", err)
+		}
+		if err := CreateTarIndexWithOptions(newTar, newIndex, opts); err != nil {
+			t.Fatalf("Failed to create TAR index: %v", err)
+		}
+
+		added, removed, changed, err := DiffIndexes(oldIndex, newIndex)
+		if err != nil {
+			t.Fatalf("Failed to diff indexes: %v", err)
+		}
+
+		// Same-size edits are only visible through checksums
+		expectedChanged := []string{"resized.txt"}
+		if checksum {
+			expectedChanged = []string{"edited.txt", "resized.txt"}
+		}
+		if !reflect.DeepEqual(added, []string{"added.txt"}) {
+			t.Errorf("Unexpected added files: %v", added)
+		}
+		if !reflect.DeepEqual(removed, []string{"removed.txt"}) {
+			t.Errorf("Unexpected removed files: %v", removed)
+		}
+		if !reflect.DeepEqual(changed, expectedChanged) {
+			t.Errorf("Expected changed %v with checksum %v, got %v", expectedChanged, checksum, changed)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package tarix
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ExtractFromTarDirect returns the contents of filePath in the TAR without
+// an index, for one-off reads. The TAR is read from the start up to the first
+// regular file whose path matches, so it takes time proportional to where the
+// file is in the archive; use an index for repeated reads. Paths are
+// normalized as when indexing, so it finds the same files lookups do.
+func ExtractFromTarDirect(tarPath, filePath string) ([]byte, error) {
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar file: %w", err)
+	}
+	defer file.Close()
+
+	cleanFilePath := normalizePath(filePath, "")
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("file %s not found in %s", cleanFilePath, tarPath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading tar header: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeGNUSparse {
+			continue
+		}
+		if normalizePath(header.Name, "") != cleanFilePath {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file data: %w", err)
+		}
+		return data, nil
+	}
+}
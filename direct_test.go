@@ -0,0 +1,21 @@
+package tarix
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractFromTarDirect(t *testing.T) {
+	tarFilePath := filepath.Join(t.TempDir(), "direct.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "aaa", "dir/b.txt": "bb"})
+
+	// Paths are normalized like index lookups
+	for _, filePath := range []string{"dir/b.txt", "./dir/b.txt", "dir//b.txt"} {
+		if data, err := ExtractFromTarDirect(tarFilePath, filePath); err != nil || string(data) != "bb" {
+			t.Errorf("Expected bb for %s, got %q, %v", filePath, data, err)
+		}
+	}
+	if _, err := ExtractFromTarDirect(tarFilePath, "missing.txt"); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
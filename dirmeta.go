@@ -0,0 +1,88 @@
+package tarix
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChtimesFS is an FS that can also set the access and modification times of
+// files and directories. ExtractAll restores directory times on such an FS.
+type ChtimesFS interface {
+	FS
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// dirMetadata is the mode, times and owner of a directory entry of the TAR
+type dirMetadata struct {
+	mode     os.FileMode
+	atime    time.Time
+	mtime    time.Time
+	uid, gid int
+}
+
+// newDirMetadata returns the metadata to restore for the directory of header
+func newDirMetadata(header *tar.Header) dirMetadata {
+	atime := header.AccessTime
+	if atime.IsZero() {
+		atime = header.ModTime
+	}
+	return dirMetadata{mode: header.FileInfo().Mode().Perm(), atime: atime, mtime: header.ModTime, uid: header.Uid, gid: header.Gid}
+}
+
+// restoreDirs applies the modes and times of dirs, keyed by output path, and
+// their owners with owners, to the ones of them in created. It runs after all files are written, since
+// writing a file changes the mtime of its directory and a read-only mode
+// would prevent it, and goes deepest first so restoring a directory doesn't
+// change its parent, as GNU tar does. It returns an error for each directory
+// it failed to restore instead of stopping at the first.
+func restoreDirs(fsys FS, dirs map[string]dirMetadata, created map[string]bool, owners *ownerSetter) []error {
+	var paths []string
+	for dir := range dirs {
+		if created[dir] {
+			paths = append(paths, dir)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		di, dj := strings.Count(paths[i], string(filepath.Separator)), strings.Count(paths[j], string(filepath.Separator))
+		if di != dj {
+			return di > dj
+		}
+		return paths[i] > paths[j]
+	})
+
+	chtimesFS, canChtimes := fsys.(ChtimesFS)
+	var errs []error
+	for _, dir := range paths {
+		metadata := dirs[dir]
+		// Changing the owner may clear the setuid and setgid bits of the mode
+		if err := owners.chown(dir, metadata.uid, metadata.gid); err != nil {
+			errs = append(errs, err)
+		}
+		if err := fsys.Chmod(dir, metadata.mode); err != nil {
+			errs = append(errs, fmt.Errorf("failed to set directory mode: %w", err))
+		}
+		if canChtimes && !metadata.mtime.IsZero() {
+			if err := chtimesFS.Chtimes(dir, metadata.atime, metadata.mtime); err != nil {
+				errs = append(errs, fmt.Errorf("failed to set directory times: %w", err))
+			}
+		}
+	}
+	return errs
+}
+
+// addParents adds the directories between outputDir and the file at
+// outputPath to created
+func addParents(created map[string]bool, outputDir, outputPath string) {
+	outputDir = filepath.Clean(outputDir)
+	for dir := filepath.Dir(outputPath); dir != outputDir && dir != "." && !created[dir]; dir = filepath.Dir(dir) {
+		created[dir] = true
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+}
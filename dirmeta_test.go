@@ -0,0 +1,131 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTarWithDirs writes a TAR of the headers, with each file's content
+// being its name
+func writeTarWithDirs(t *testing.T, tarFilePath string, headers []*tar.Header) {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, header := range headers {
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(header.Name))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			tw.Write([]byte(header.Name))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+	if err := os.WriteFile(tarFilePath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write TAR: %v", err)
+	}
+}
+
+func TestExtractAllRestoresDirs(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "dirs.tar")
+	subTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	nestedTime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0750, ModTime: subTime},
+		{Name: "sub/nested/", Typeflag: tar.TypeDir, Mode: 0555, ModTime: nestedTime},
+		{Name: "sub/nested/a.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "sub/b.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "empty/", Typeflag: tar.TypeDir, Mode: 0700},
+	})
+
+	tarIndexPath := filepath.Join(dir, "dirs.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	outputDir := filepath.Join(dir, "out")
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, outputDir, ExtractOptions{}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(filepath.Join(outputDir, "sub", "nested"), 0755) })
+
+	// The files were written after the directories were created, so their
+	// times only match if they were set last
+	for _, expected := range []struct {
+		path  string
+		mode  os.FileMode
+		mtime time.Time
+	}{
+		{filepath.Join(outputDir, "sub"), 0750, subTime},
+		{filepath.Join(outputDir, "sub", "nested"), 0555, nestedTime},
+	} {
+		info, err := os.Stat(expected.path)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", expected.path, err)
+		}
+		if info.Mode().Perm() != expected.mode || !info.ModTime().Equal(expected.mtime) {
+			t.Errorf("Expected %s with mode %v and mtime %v, got %v and %v", expected.path, expected.mode, expected.mtime, info.Mode().Perm(), info.ModTime())
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(outputDir, "sub", "nested", "a.txt")); err != nil || string(data) != "sub/nested/a.txt" {
+		t.Errorf("Unexpected content %q, %v", data, err)
+	}
+	// Directories without extracted files are not created
+	if _, err := os.Stat(filepath.Join(outputDir, "empty")); !os.IsNotExist(err) {
+		t.Errorf("Expected no empty directory, got %v", err)
+	}
+}
+
+// chmodFailingFS is a MemFS failing to change the mode of directories
+type chmodFailingFS struct {
+	*MemFS
+}
+
+func (f chmodFailingFS) Chmod(name string, mode os.FileMode) error {
+	if _, ok := f.Dirs[filepath.Clean(name)]; ok {
+		return errors.New("chmod not supported")
+	}
+	return f.MemFS.Chmod(name, mode)
+}
+
+func TestExtractAllReportsDirFailures(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "dirs.tar")
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0750, ModTime: mtime},
+		{Name: "sub/a.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	})
+
+	tarIndexPath := filepath.Join(dir, "dirs.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	fsys := chmodFailingFS{NewMemFS()}
+	var log bytes.Buffer
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, "out", ExtractOptions{FS: fsys, Log: &log}); err != nil {
+		t.Fatalf("Expected the extraction to succeed, got %v", err)
+	}
+	if f, ok := fsys.Files[filepath.Join("out", "sub", "a.txt")]; !ok || string(f.Data) != "sub/a.txt" {
+		t.Errorf("Expected the file to be extracted, got %+v", f)
+	}
+	if !strings.Contains(log.String(), "chmod not supported") {
+		t.Errorf("Expected the failure to be reported, got %q", log.String())
+	}
+	// The times are still set when the mode can't be
+	if got := fsys.ModTimes[filepath.Join("out", "sub")]; !got.Equal(mtime) {
+		t.Errorf("Expected mtime %v, got %v", mtime, got)
+	}
+}
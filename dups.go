@@ -0,0 +1,53 @@
+package tarix
+
+import (
+	"errors"
+	"sort"
+)
+
+// FindDuplicates groups the files of the index at indexPath by the SHA-256
+// of their content, keeping only groups of more than one file. The index
+// must have been built with IndexOptions.ContentHash or Dedup. Files are
+// named by their stored path, or by their key in indexes without paths.
+func FindDuplicates(indexPath string) (map[string][]string, error) {
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	dups, _, err := index.Duplicates()
+	return dups, err
+}
+
+// Duplicates is like FindDuplicates for a loaded index, also returning the
+// bytes that keeping a single file of each group would save
+func (ti *TarIndex) Duplicates() (map[string][]string, int64, error) {
+	groups := map[string][]string{}
+	sizes := map[string]int64{}
+	missing := false
+	ti.each(func(key string, fileInfo FileIndex) {
+		if fileInfo.ContentHash == "" {
+			missing = true
+			return
+		}
+		name := fileInfo.Path
+		if name == "" {
+			name = key
+		}
+		groups[fileInfo.ContentHash] = append(groups[fileInfo.ContentHash], name)
+		sizes[fileInfo.ContentHash] = fileInfo.Size
+	})
+	if missing {
+		return nil, 0, errors.New("index has no content checksums, build it with ContentHash")
+	}
+
+	var reclaimable int64
+	for checksum, names := range groups {
+		if len(names) < 2 {
+			delete(groups, checksum)
+			continue
+		}
+		sort.Strings(names)
+		reclaimable += int64(len(names)-1) * sizes[checksum]
+	}
+	return groups, reclaimable, nil
+}
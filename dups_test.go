@@ -0,0 +1,73 @@
+package tarix
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "dups.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"a.txt":     "same",
+		"b/a.txt":   "same",
+		"c.txt":     "same",
+		"d.txt":     "other",
+		"e.txt":     "other!",
+		"f.txt":     "other!",
+		"empty.txt": "",
+	})
+
+	checksum := func(content string) string {
+		sum, _ := hashContent(strings.NewReader(content))
+		return sum
+	}
+
+	indexPath := filepath.Join(dir, "dups.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, indexPath, IndexOptions{ContentHash: true, StorePaths: true}); err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	dups, err := FindDuplicates(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to find duplicates: %v", err)
+	}
+	want := map[string][]string{
+		checksum("same"):   {"a.txt", "b/a.txt", "c.txt"},
+		checksum("other!"): {"e.txt", "f.txt"},
+	}
+	if !reflect.DeepEqual(dups, want) {
+		t.Errorf("Expected %v, got %v", want, dups)
+	}
+
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if _, reclaimable, _ := index.Duplicates(); reclaimable != 2*4+6 {
+		t.Errorf("Expected 14 reclaimable bytes, got %d", reclaimable)
+	}
+
+	// Without stored paths, files are named by their keys
+	if err := CreateTarIndexWithOptions(tarFilePath, indexPath, IndexOptions{ContentHash: true}); err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	dups, err = FindDuplicates(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to find duplicates: %v", err)
+	}
+	wantKeys := []string{index.Key("e.txt"), index.Key("f.txt")}
+	sort.Strings(wantKeys)
+	if keys := dups[checksum("other!")]; !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("Expected keys %v, got %v", wantKeys, keys)
+	}
+
+	if err := CreateTarIndexWithOptions(tarFilePath, indexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	if _, err := FindDuplicates(indexPath); err == nil {
+		t.Error("Expected an index without checksums to fail")
+	}
+}
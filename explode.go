@@ -0,0 +1,307 @@
+package tarix
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LinkFS is an FS that can also create links and remove files. Explode
+// recreates symbolic and hard links on such an FS and skips them on others.
+type LinkFS interface {
+	FS
+	Symlink(oldname, newname string) error
+	Link(oldname, newname string) error
+	Remove(name string) error
+}
+
+// ExplodeSummary counts what Explode recreated
+type ExplodeSummary struct {
+	Files     int64
+	Bytes     int64
+	Dirs      int64
+	Symlinks  int64
+	HardLinks int64
+	// NotIndexed counts regular files of the TAR left out of the index, e.g.
+	// by IndexOptions.Exclude, which are not extracted
+	NotIndexed int64
+	// Skipped lists the entries that were not recreated and why
+	Skipped []SkippedEntry
+}
+
+// SkippedEntry is an entry of the TAR Explode did not recreate
+type SkippedEntry struct {
+	Name   string
+	Reason string
+}
+
+// Explode recreates the whole archive in outputDir: the files of the index
+// with their modes and modification times, every directory, including empty
+// ones, and symbolic and hard links. Like ExtractAll it reads the TAR once,
+// streaming each file to disk. Entries whose paths lead outside of outputDir
+// are refused, and symbolic links pointing outside of it, also through other
+// links, unless opts.UnsafeLinks is set, devices and FIFOs are skipped and
+// listed in the summary. Of a path stored more than once in an index built
+// with IndexOptions.Occurrences, the last occurrence is recreated, as tar
+// leaves it.
+func Explode(tarPath, indexPath, outputDir string, opts ExtractOptions) (*ExplodeSummary, error) {
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	root := opts.Root
+	if root == "" {
+		root = index.Root
+	}
+	fsys := opts.fs()
+	linkFS, canLink := fsys.(LinkFS)
+	chtimesFS, canChtimes := fsys.(ChtimesFS)
+
+	progress := Progress{FilesTotal: int64(index.count())}
+	index.each(func(_ string, fileInfo FileIndex) {
+		progress.BytesTotal += fileInfo.Size
+	})
+
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar file: %w", err)
+	}
+	defer file.Close()
+
+	log := slogger(opts.Logger)
+	adviseRead(file, opts.ReadPattern, log)
+	owners := newOwnerSetter(fsys, opts)
+	summary := &ExplodeSummary{}
+	skip := func(name, reason string) {
+		summary.Skipped = append(summary.Skipped, SkippedEntry{Name: name, Reason: reason})
+		log.Info("skipped entry", "path", name, "reason", reason)
+	}
+	dirs := map[string]dirMetadata{}
+	created := map[string]bool{}
+	// extracted holds the output paths of the files written, which hard
+	// links may point at, links the symbolic links created, and traversed
+	// the directories their targets lead through
+	extracted := map[string]bool{}
+	links := map[string]bool{}
+	traversed := map[string]bool{}
+	keys := newOccurrenceKeys(index)
+
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return summary, fmt.Errorf("error reading tar header: %w", err)
+		}
+
+		// Every occurrence of a path is counted, also of entries skipped
+		var key string
+		var superseded bool
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeGNUSparse {
+			key, superseded = keys.next(header.Name)
+		}
+
+		cleanPath := normalizePath(header.Name, root)
+		if cleanPath == "." {
+			continue
+		}
+		if !filepath.IsLocal(cleanPath) {
+			return summary, fmt.Errorf("refusing to extract %s outside of output directory", header.Name)
+		}
+		outputPath := filepath.Join(outputDir, cleanPath)
+		// A link checked to stay in outputDir could still lead out of it
+		// through another link, so nothing is written through links
+		if throughLink(links, outputDir, outputPath) {
+			skip(cleanPath, "path goes through a symbolic link")
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := fsys.MkdirAll(outputPath, 0755); err != nil {
+				return summary, fmt.Errorf("failed to create directory: %w", err)
+			}
+			dirs[outputPath] = newDirMetadata(header)
+			created[outputPath] = true
+			addParents(created, outputDir, outputPath)
+			summary.Dirs++
+
+		case tar.TypeReg, tar.TypeGNUSparse:
+			if _, ok := index.lookup(key); !ok {
+				summary.NotIndexed++
+				continue
+			}
+			if superseded {
+				skip(cleanPath, "replaced by a later occurrence")
+				continue
+			}
+			if exists, err := opts.Overwrite.checkOutput(fsys, outputPath); err != nil {
+				return summary, err
+			} else if exists {
+				skip(cleanPath, "output file exists")
+				continue
+			}
+			sparse := opts.RestoreSparse && opts.Transform == nil && isSparse(header)
+			n, err := extractEntry(fsys, opts.Transform.apply(tr), outputPath, header, sparse, owners, opts)
+			if err != nil {
+				return summary, err
+			}
+			if canChtimes && !header.ModTime.IsZero() {
+				metadata := newDirMetadata(header)
+				if err := chtimesFS.Chtimes(outputPath, metadata.atime, metadata.mtime); err != nil {
+					return summary, fmt.Errorf("failed to set file times: %w", err)
+				}
+			}
+			log.Debug("extracted file", "path", cleanPath, "output", outputPath, "size", n)
+			addParents(created, outputDir, outputPath)
+			extracted[outputPath] = true
+			summary.Files++
+			summary.Bytes += n
+
+			progress.FilesDone++
+			progress.BytesDone += n
+			if opts.Progress != nil {
+				opts.Progress(progress)
+			}
+
+		case tar.TypeSymlink:
+			// Links are resolved from their own directory, and must stay in
+			// outputDir, also through the links created before and after
+			// them, unless the archive is trusted
+			target := filepath.FromSlash(header.Linkname)
+			linkDirs, inside := linkTargetDirs(links, outputDir, outputPath, target)
+			switch {
+			case !canLink:
+				skip(cleanPath, "filesystem can't create links")
+			case !opts.UnsafeLinks && !inside:
+				skip(cleanPath, "link target "+header.Linkname+" is outside of output directory")
+			case !opts.UnsafeLinks && traversed[outputPath]:
+				skip(cleanPath, "target of another link goes through it")
+			default:
+				ok, err := createLink(linkFS, linkFS.Symlink, target, outputPath, opts.Overwrite)
+				if err != nil {
+					return summary, err
+				}
+				if !ok {
+					skip(cleanPath, "output file exists")
+					continue
+				}
+				addParents(created, outputDir, outputPath)
+				links[outputPath] = true
+				for _, dir := range linkDirs {
+					traversed[dir] = true
+				}
+				summary.Symlinks++
+			}
+
+		case tar.TypeLink:
+			targetPath := normalizePath(header.Linkname, root)
+			targetOutput := filepath.Join(outputDir, targetPath)
+			switch {
+			case !canLink:
+				skip(cleanPath, "filesystem can't create links")
+			case !filepath.IsLocal(targetPath):
+				skip(cleanPath, "link target "+header.Linkname+" is outside of output directory")
+			case !extracted[targetOutput]:
+				skip(cleanPath, "link target "+header.Linkname+" was not extracted")
+			default:
+				ok, err := createLink(linkFS, linkFS.Link, targetOutput, outputPath, opts.Overwrite)
+				if err != nil {
+					return summary, err
+				}
+				if !ok {
+					skip(cleanPath, "output file exists")
+					continue
+				}
+				addParents(created, outputDir, outputPath)
+				summary.HardLinks++
+			}
+
+		case tar.TypeXHeader, tar.TypeXGlobalHeader, tar.TypeGNULongName, tar.TypeGNULongLink:
+			// Consumed by the tar reader
+
+		default:
+			skip(cleanPath, fmt.Sprintf("unsupported entry type %q", header.Typeflag))
+		}
+	}
+
+	for _, err := range restoreDirs(fsys, dirs, created, owners) {
+		fmt.Fprintf(logWriter(opts.Log), "Warning: %v\n", err)
+		log.Warn("failed to restore directory", "error", err)
+	}
+	return summary, nil
+}
+
+// createLink makes a link at outputPath to target with link, applying the
+// overwrite policy to an existing file there. It reports whether the link
+// was created rather than skipped because of the policy.
+func createLink(fsys LinkFS, link func(oldname, newname string) error, target, outputPath string, policy OverwritePolicy) (bool, error) {
+	if err := fsys.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return false, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if exists, err := policy.checkOutput(fsys, outputPath); err != nil || exists {
+		return false, err
+	}
+	// Links can't replace files like Create does
+	if err := fsys.Remove(outputPath); err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to replace existing file: %w", err)
+	}
+	if err := link(target, outputPath); err != nil {
+		return false, fmt.Errorf("failed to create link: %w", err)
+	}
+	return true, nil
+}
+
+// throughLink reports whether a directory between outputDir and outputPath
+// is one of links
+func throughLink(links map[string]bool, outputDir, outputPath string) bool {
+	outputDir = filepath.Clean(outputDir)
+	for dir := filepath.Dir(outputPath); dir != outputDir && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if links[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// linkTargetDirs follows target, the target of a symbolic link at
+// outputPath, a name at a time from the link's directory and returns the
+// directories it goes through. It reports whether the target stays in
+// outputDir all the way: it must be relative, never go above outputDir and
+// not go through any of links, as a ".." after a link leaves its target
+// rather than the link.
+func linkTargetDirs(links map[string]bool, outputDir, outputPath, target string) ([]string, bool) {
+	if filepath.IsAbs(target) {
+		return nil, false
+	}
+	outputDir = filepath.Clean(outputDir)
+	var dirs []string
+	current := filepath.Dir(outputPath)
+	names := strings.Split(target, string(filepath.Separator))
+	for i, name := range names {
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+		default:
+			current = filepath.Join(current, name)
+		}
+		if rel, err := filepath.Rel(outputDir, current); err != nil || !filepath.IsLocal(rel) {
+			return dirs, false
+		}
+		if i < len(names)-1 {
+			if links[current] {
+				return dirs, false
+			}
+			dirs = append(dirs, current)
+		}
+	}
+	return dirs, true
+}
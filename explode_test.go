@@ -0,0 +1,76 @@
+package tarix
+
+import (
+	"archive/tar"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExplode(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "tree.tar")
+	fileTime := time.Date(2019, 5, 6, 7, 8, 9, 0, time.UTC)
+	dirTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0750, ModTime: dirTime},
+		{Name: "sub/a.txt", Typeflag: tar.TypeReg, Mode: 0600, ModTime: fileTime},
+		{Name: "empty/", Typeflag: tar.TypeDir, Mode: 0700},
+		{Name: "skip.log", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "sub/a.txt"},
+		{Name: "fifo", Typeflag: tar.TypeFifo, Mode: 0644},
+	})
+	tarIndexPath := filepath.Join(dir, "tree.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Exclude: []string{"*.log"}}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	memFS := NewMemFS()
+	summary, err := Explode(tarFilePath, tarIndexPath, "out", ExtractOptions{FS: memFS})
+	if err != nil {
+		t.Fatalf("Failed to explode: %v", err)
+	}
+
+	if summary.Files != 1 || summary.Bytes != int64(len("sub/a.txt")) || summary.Dirs != 2 || summary.NotIndexed != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	// MemFS can't create links, and FIFOs aren't recreated
+	if len(summary.Skipped) != 2 || summary.Skipped[0].Name != "link" || summary.Skipped[1].Name != "fifo" {
+		t.Errorf("Unexpected skipped entries: %+v", summary.Skipped)
+	}
+
+	if f := memFS.Files[filepath.Join("out", "sub", "a.txt")]; f == nil || string(f.Data) != "sub/a.txt" || f.Mode != 0600 {
+		t.Errorf("Unexpected extracted file: %+v", f)
+	}
+	if got := memFS.ModTimes[filepath.Join("out", "sub", "a.txt")]; !got.Equal(fileTime) {
+		t.Errorf("File mtime = %v, want %v", got, fileTime)
+	}
+	if got := memFS.ModTimes[filepath.Join("out", "sub")]; !got.Equal(dirTime) {
+		t.Errorf("Directory mtime = %v, want %v", got, dirTime)
+	}
+	if mode, ok := memFS.Dirs[filepath.Join("out", "empty")]; !ok || mode != 0700 {
+		t.Errorf("Empty directory not recreated with its mode, got %v, %v", mode, ok)
+	}
+	if _, ok := memFS.Files[filepath.Join("out", "skip.log")]; ok {
+		t.Error("Expected files left out of the index not to be extracted")
+	}
+}
+
+func TestExplodeRefusesTraversal(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "evil.tar")
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "../evil/", Typeflag: tar.TypeDir, Mode: 0755},
+	})
+	tarIndexPath := filepath.Join(dir, "evil.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	memFS := NewMemFS()
+	if _, err := Explode(tarFilePath, tarIndexPath, "out", ExtractOptions{FS: memFS}); err == nil {
+		t.Error("Expected a directory outside of the output directory to be refused")
+	}
+	if len(memFS.Dirs) != 0 {
+		t.Errorf("Expected nothing created, got %v", memFS.Dirs)
+	}
+}
@@ -0,0 +1,177 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tarix
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExplodeLinks(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "links.tar")
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "sub/a.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "sub/rel", Typeflag: tar.TypeSymlink, Linkname: "a.txt"},
+		{Name: "hard", Typeflag: tar.TypeLink, Linkname: "sub/a.txt"},
+		{Name: "abs", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		{Name: "up", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+		// Each link stays in the output directory, but through both a file
+		// would be written outside of it
+		{Name: "sub/dir/back", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+		{Name: "sub/dir/back/escape", Typeflag: tar.TypeSymlink, Linkname: ".."},
+		{Name: "sub/dir/back/x.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	})
+	tarIndexPath := filepath.Join(dir, "links.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	outputDir := filepath.Join(dir, "out")
+	summary, err := Explode(tarFilePath, tarIndexPath, outputDir, ExtractOptions{})
+	if err != nil {
+		t.Fatalf("Failed to explode: %v", err)
+	}
+	if summary.Files != 1 || summary.Symlinks != 2 || summary.HardLinks != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	var skipped []string
+	for _, entry := range summary.Skipped {
+		skipped = append(skipped, entry.Name)
+	}
+	if want := []string{"abs", "up", "sub/dir/back/escape", "sub/dir/back/x.txt"}; len(skipped) != len(want) || skipped[0] != want[0] || skipped[1] != want[1] || skipped[2] != want[2] || skipped[3] != want[3] {
+		t.Errorf("Skipped %v, want %v", skipped, want)
+	}
+
+	if target, err := os.Readlink(filepath.Join(outputDir, "sub", "rel")); err != nil || target != "a.txt" {
+		t.Errorf("Unexpected symlink target %q: %v", target, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(outputDir, "sub", "rel")); err != nil || string(data) != "sub/a.txt" {
+		t.Errorf("Unexpected content through symlink %q: %v", data, err)
+	}
+	a, err := os.Stat(filepath.Join(outputDir, "sub", "a.txt"))
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	hard, err := os.Stat(filepath.Join(outputDir, "hard"))
+	if err != nil || !os.SameFile(a, hard) {
+		t.Errorf("Expected a hard link to sub/a.txt: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "x.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written outside of the output directory: %v", err)
+	}
+
+	// Exploding again replaces the links
+	if _, err := Explode(tarFilePath, tarIndexPath, outputDir, ExtractOptions{}); err != nil {
+		t.Fatalf("Failed to explode again: %v", err)
+	}
+}
+
+func TestExplodeLinkChains(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		headers []*tar.Header
+		skipped string
+	}{
+		// Each target is local on its own, but m leads through d/l to the
+		// parent of the output directory, whichever is created first
+		{"link first", []*tar.Header{
+			{Name: "d/l", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "m", Typeflag: tar.TypeSymlink, Linkname: "d/l/.."},
+		}, "m"},
+		{"link last", []*tar.Header{
+			{Name: "m", Typeflag: tar.TypeSymlink, Linkname: "d/l/.."},
+			{Name: "d/l", Typeflag: tar.TypeSymlink, Linkname: ".."},
+		}, "d/l"},
+		{"malicious", []*tar.Header{
+			{Name: "d/a", Typeflag: tar.TypeSymlink, Linkname: "../../.."},
+			{Name: "d/b", Typeflag: tar.TypeSymlink, Linkname: "../d/../../x"},
+		}, "d/a"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tarFilePath := filepath.Join(dir, "links.tar")
+			writeTarWithDirs(t, tarFilePath, tc.headers)
+			tarIndexPath := filepath.Join(dir, "links.tar.index")
+			if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+				t.Fatalf("Failed to create TAR index: %v", err)
+			}
+			outputDir := filepath.Join(dir, "out")
+			summary, err := Explode(tarFilePath, tarIndexPath, outputDir, ExtractOptions{})
+			if err != nil {
+				t.Fatalf("Failed to explode: %v", err)
+			}
+			if len(summary.Skipped) == 0 || summary.Skipped[0].Name != tc.skipped {
+				t.Errorf("Expected %s to be skipped, got %+v", tc.skipped, summary.Skipped)
+			}
+			if _, err := os.Lstat(filepath.Join(outputDir, tc.skipped)); !os.IsNotExist(err) {
+				t.Errorf("Expected no link at %s: %v", tc.skipped, err)
+			}
+		})
+	}
+}
+
+func TestExplodeUnsafeLinks(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "links.tar")
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "abs", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		{Name: "up", Typeflag: tar.TypeSymlink, Linkname: "../../"},
+		{Name: "up/x.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	})
+	tarIndexPath := filepath.Join(dir, "links.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	outputDir := filepath.Join(dir, "out")
+	summary, err := Explode(tarFilePath, tarIndexPath, outputDir, ExtractOptions{UnsafeLinks: true})
+	if err != nil {
+		t.Fatalf("Failed to explode: %v", err)
+	}
+	if summary.Symlinks != 2 || summary.Files != 0 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	for name, want := range map[string]string{"abs": "/etc/passwd", "up": "../../"} {
+		if target, err := os.Readlink(filepath.Join(outputDir, name)); err != nil || target != want {
+			t.Errorf("Unexpected target %q of %s: %v", target, name, err)
+		}
+	}
+	// Files are still not written through links
+	if _, err := os.Lstat(filepath.Join(filepath.Dir(dir), "x.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written outside of the output directory: %v", err)
+	}
+}
+
+func TestExtractAllSkipsLinks(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "links.tar")
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "up", Typeflag: tar.TypeSymlink, Linkname: "../../"},
+		{Name: "hard", Typeflag: tar.TypeLink, Linkname: "../../x.txt"},
+		{Name: "up/x.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "a.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	})
+	tarIndexPath := filepath.Join(dir, "links.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	outputDir := filepath.Join(dir, "out")
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, outputDir, ExtractOptions{}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	for _, name := range []string{"up", "hard"} {
+		if info, err := os.Lstat(filepath.Join(outputDir, name)); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			t.Errorf("Expected no symlink at %s", name)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(filepath.Dir(dir), "x.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written outside of the output directory: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(outputDir, "a.txt")); err != nil || string(data) != "a.txt" {
+		t.Errorf("Unexpected content %q: %v", data, err)
+	}
+}
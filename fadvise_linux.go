@@ -0,0 +1,37 @@
+//go:build linux && (amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x)
+
+package tarix
+
+import (
+	"os"
+	"syscall"
+)
+
+// Advice values of posix_fadvise, the same on all these architectures
+const (
+	fadvNormal     = 0
+	fadvRandom     = 1
+	fadvSequential = 2
+)
+
+// fadvise gives the OS the advice of pattern for the whole of f
+func fadvise(f *os.File, pattern ReadPattern) error {
+	advice := fadvNormal
+	switch pattern {
+	case ReadSequential:
+		advice = fadvSequential
+	case ReadRandom:
+		advice = fadvRandom
+	}
+	return fadviseFile(f, advice)
+}
+
+// fadviseFile calls posix_fadvise on the whole of f. On 64-bit platforms
+// the offset and length each fit in an argument.
+func fadviseFile(f *os.File, advice int) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), 0, 0, uintptr(advice), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
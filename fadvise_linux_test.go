@@ -0,0 +1,69 @@
+//go:build linux && (amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x)
+
+package tarix
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestFadvise(t *testing.T) {
+	file, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer file.Close()
+	for _, pattern := range []ReadPattern{ReadDefault, ReadSequential, ReadRandom} {
+		if err := fadvise(file, pattern); err != nil {
+			t.Errorf("Failed to advise pattern %v: %v", pattern, err)
+		}
+	}
+}
+
+// dropCache evicts the pages of the file at path from the page cache, so
+// reading it goes to the disk
+func dropCache(b *testing.B, path string) {
+	b.Helper()
+	dontNeed := 4
+	if runtime.GOARCH == "s390x" {
+		dontNeed = 6
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		b.Fatalf("Failed to open TAR: %v", err)
+	}
+	defer file.Close()
+	if err := fadviseFile(file, dontNeed); err != nil {
+		b.Fatalf("Failed to drop cached pages: %v", err)
+	}
+}
+
+// BenchmarkExtractAllReadPattern reads a large archive from a cold cache, as
+// a restore of an archive not read lately does. On disks where reading ahead
+// pays off, ReadSequential takes less time than the default.
+func BenchmarkExtractAllReadPattern(b *testing.B) {
+	shape := TarShape{Files: 256, FileSize: 1024 * 1024, FilesPerDir: 64, Seed: 1}
+	tarFilePath, tarIndexPath, _ := writeBenchTar(b, shape)
+
+	for _, bm := range []struct {
+		name    string
+		pattern ReadPattern
+	}{
+		{"default", ReadDefault},
+		{"sequential", ReadSequential},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(shape.FileSize * int64(shape.Files))
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				outputDir := b.TempDir()
+				dropCache(b, tarFilePath)
+				b.StartTimer()
+				if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, outputDir, ExtractOptions{ReadPattern: bm.pattern}); err != nil {
+					b.Fatalf("Failed to extract: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !(linux && (amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x))
+
+package tarix
+
+import "os"
+
+// fadvise does nothing on platforms without posix_fadvise, or where
+// passing its 64-bit offsets takes more than a plain system call
+func fadvise(f *os.File, pattern ReadPattern) error {
+	return nil
+}
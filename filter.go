@@ -0,0 +1,169 @@
+package tarix
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// FilterTar writes a TAR to dstTar holding only the files at paths of
+// srcTar, which indexPath indexes. Members are copied as raw blocks, their
+// headers (extended ones included) and data byte for byte, in archive order,
+// after the global PAX headers starting srcTar and followed by an
+// end-of-archive trailer. A file deduplicated in the index is
+// copied as the member holding its data, under that member's name.
+func FilterTar(srcTar, indexPath, dstTar string, paths []string) (err error) {
+	th, err := NewTarixHandle(srcTar, indexPath)
+	if err != nil {
+		return err
+	}
+	defer th.Close()
+
+	// Find the members first, so a missing file leaves no output behind
+	members, err := th.subsetMembers(paths)
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.Create(dstTar)
+	if err != nil {
+		return fmt.Errorf("failed to create output tar: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(dstTar)
+		}
+	}()
+
+	if err := th.copyMembers(dst, members); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close output tar: %w", err)
+	}
+	return nil
+}
+
+// StreamSubsetTar writes a TAR to w holding only the files at paths of
+// srcTarPath, copied like FilterTar does, so a subset can be piped to
+// another process or a network connection without a temporary file. All
+// paths are looked up before anything is written, but an error while
+// copying leaves w with a partial TAR.
+func StreamSubsetTar(w io.Writer, srcTarPath, indexPath string, paths []string) error {
+	th, err := NewTarixHandle(srcTarPath, indexPath)
+	if err != nil {
+		return err
+	}
+	defer th.Close()
+
+	members, err := th.subsetMembers(paths)
+	if err != nil {
+		return err
+	}
+	return th.copyMembers(w, members)
+}
+
+// subsetMembers looks up the members holding the files at paths, once each,
+// in archive order
+func (th *TarixHandle) subsetMembers(paths []string) ([]FileIndex, error) {
+	seen := map[int64]bool{}
+	var members []FileIndex
+	for _, filePath := range paths {
+		fileInfo, err := th.lookup(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkExtractable(th.key(filePath), fileInfo); err != nil {
+			return nil, err
+		}
+		if !seen[fileInfo.Start] {
+			seen[fileInfo.Start] = true
+			members = append(members, fileInfo)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Start < members[j].Start })
+	return members, nil
+}
+
+// copyMembers copies members to w as raw blocks, after the global headers
+// starting the archive and followed by an end-of-archive trailer
+func (th *TarixHandle) copyMembers(w io.Writer, members []FileIndex) error {
+	globalEnd, err := leadingGlobalHeaders(th.Source)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, io.NewSectionReader(th.Source, 0, globalEnd)); err != nil {
+		return fmt.Errorf("failed to copy global headers: %w", err)
+	}
+
+	for _, fileInfo := range members {
+		start, end, err := th.memberRange(fileInfo)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, io.NewSectionReader(th.Source, start, end-start)); err != nil {
+			return fmt.Errorf("failed to copy member at offset %d: %w", fileInfo.Start, err)
+		}
+	}
+
+	// Closing a tar.Writer with nothing written writes just the trailer
+	if err := tar.NewWriter(w).Close(); err != nil {
+		return fmt.Errorf("failed to write tar trailer: %w", err)
+	}
+	return nil
+}
+
+// leadingGlobalHeaders returns the end of the global PAX headers, such as
+// the commit ID git archive writes, at the start of the TAR in r
+func leadingGlobalHeaders(r io.ReaderAt) (int64, error) {
+	var pos int64
+	block := make([]byte, headerSize)
+	for {
+		if _, err := r.ReadAt(block, pos); err != nil {
+			if err == io.EOF {
+				return pos, nil
+			}
+			return 0, fmt.Errorf("failed to read tar header at offset %d: %w", pos, err)
+		}
+		if block[156] != tar.TypeXGlobalHeader {
+			return pos, nil
+		}
+		size, err := parseOctal(block[124:136])
+		if err != nil {
+			return 0, fmt.Errorf("invalid size in tar header at offset %d: %w", pos, err)
+		}
+		pos += headerSize + padToBlock(size)
+	}
+}
+
+// memberRange returns the range of the TAR holding the member of fileInfo,
+// from its first extended header to the end of its padded data. Sparse
+// members store less data than their size, so theirs is read to find the end.
+func (th *TarixHandle) memberRange(fileInfo FileIndex) (int64, int64, error) {
+	start := fileInfo.Start - int64(fileInfo.HeaderBlocks)*headerSize
+	sr := io.NewSectionReader(th.Source, start, math.MaxInt64-start)
+	tr := tar.NewReader(sr)
+	header, err := tr.Next()
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: no valid header at offset %d: %v", ErrIndexStale, fileInfo.Start, err)
+	}
+
+	if isSparse(header) {
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return 0, 0, fmt.Errorf("error reading sparse entry: %w", err)
+		}
+		dataEnd, _ := sr.Seek(0, io.SeekCurrent)
+		return start, start + padToBlock(dataEnd), nil
+	}
+
+	// The reader has consumed the headers and nothing of the data
+	dataStart, _ := sr.Seek(0, io.SeekCurrent)
+	if start+dataStart != fileInfo.DataOffset() {
+		return 0, 0, fmt.Errorf("%w: headers before offset %d don't match the index", ErrIndexStale, fileInfo.Start)
+	}
+	return start, start + dataStart + padToBlock(header.Size), nil
+}
@@ -0,0 +1,194 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilterTar(t *testing.T) {
+	dir := t.TempDir()
+	longName := strings.Repeat("long/", 30) + "c.txt"
+	files := map[string]string{"a.txt": "alpha", "b.txt": strings.Repeat("b", 700), longName: "charlie"}
+	tarFilePath := filepath.Join(dir, "src.tar")
+	writeTestTar(t, tarFilePath, files)
+	tarIndexPath := filepath.Join(dir, "src.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	dstPath := filepath.Join(dir, "dst.tar")
+	if err := FilterTar(tarFilePath, tarIndexPath, dstPath, []string{longName, "b.txt"}); err != nil {
+		t.Fatalf("Failed to filter TAR: %v", err)
+	}
+
+	dst, err := os.ReadFile(dstPath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+	if len(dst)%512 != 0 || !bytes.Equal(dst[len(dst)-1024:], make([]byte, 1024)) {
+		t.Errorf("Expected the TAR to end with a trailer")
+	}
+
+	// Members come in archive order with their original headers
+	tr := tar.NewReader(bytes.NewReader(dst))
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read filtered TAR: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		if string(data) != files[header.Name] {
+			t.Errorf("Unexpected content of %s", header.Name)
+		}
+		names = append(names, header.Name)
+	}
+	if len(names) != 2 || names[0] != "b.txt" || names[1] != longName {
+		t.Errorf("Expected b.txt and the long name, got %v", names)
+	}
+
+	src, _ := os.ReadFile(tarFilePath)
+	if !bytes.Contains(src, dst[:len(dst)-1024]) {
+		t.Errorf("Expected the members to be copied byte for byte")
+	}
+
+	if err := FilterTar(tarFilePath, tarIndexPath, filepath.Join(dir, "missing.tar"), []string{"missing.txt"}); err == nil {
+		t.Error("Expected an error for a file not in the index")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.tar")); !os.IsNotExist(err) {
+		t.Errorf("Expected no output for a failed filter, got %v", err)
+	}
+}
+
+func TestFilterTarSparse(t *testing.T) {
+	// See TestCreateTarIndexSparse for the contents of sparse-gnu.tar
+	tarFilePath := filepath.Join("testdata", "sparse-gnu.tar")
+	dir := t.TempDir()
+	tarIndexPath := filepath.Join(dir, "sparse-gnu.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	dstPath := filepath.Join(dir, "dst.tar")
+	if err := FilterTar(tarFilePath, tarIndexPath, dstPath, []string{"sparse.img", "after.txt"}); err != nil {
+		t.Fatalf("Failed to filter TAR: %v", err)
+	}
+	dstIndexPath := filepath.Join(dir, "dst.tar.index.json")
+	if err := CreateTarIndexWithOptions(dstPath, dstIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to index filtered TAR: %v", err)
+	}
+	th, err := NewTarixHandle(dstPath, dstIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	if th.Index.Len() != 2 {
+		t.Errorf("Expected 2 files, got %d", th.Index.Len())
+	}
+	if data, err := th.ExtractBytesOfFile("after.txt"); err != nil || string(data) != "after sparse\n" {
+		t.Errorf("Expected after.txt after the sparse file, got %q, %v", data, err)
+	}
+}
+
+func TestFilterTarGlobalHeader(t *testing.T) {
+	// See TestGlobalPAXHeader for the contents of pax-global.tar
+	tarFilePath := filepath.Join("testdata", "pax-global.tar")
+	dir := t.TempDir()
+	tarIndexPath := filepath.Join(dir, "pax-global.tar.index.json")
+	if err := CreateTarIndex(tarFilePath, tarIndexPath); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	dstPath := filepath.Join(dir, "dst.tar")
+	if err := FilterTar(tarFilePath, tarIndexPath, dstPath, []string{"dir/b.txt"}); err != nil {
+		t.Fatalf("Failed to filter TAR: %v", err)
+	}
+	dst, err := os.ReadFile(dstPath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+	src, _ := os.ReadFile(tarFilePath)
+	if !bytes.Equal(dst[:1024], src[:1024]) {
+		t.Errorf("Expected the global header to be copied byte for byte")
+	}
+
+	tr := tar.NewReader(bytes.NewReader(dst))
+	var types []byte
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read filtered TAR: %v", err)
+		}
+		types = append(types, header.Typeflag)
+		names = append(names, header.Name)
+	}
+	if len(types) != 2 || types[0] != tar.TypeXGlobalHeader || names[1] != "dir/b.txt" {
+		t.Errorf("Expected the global header and dir/b.txt, got types %q and names %v", types, names)
+	}
+}
+
+func TestStreamSubsetTar(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a.txt": "alpha", "b.txt": strings.Repeat("b", 700), "c.txt": "charlie"}
+	tarFilePath := filepath.Join(dir, "src.tar")
+	writeTestTar(t, tarFilePath, files)
+	tarIndexPath := filepath.Join(dir, "src.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// Streamed through a pipe, as to another process
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(StreamSubsetTar(pw, tarFilePath, tarIndexPath, []string{"c.txt", "a.txt"}))
+	}()
+	streamed, err := io.ReadAll(pr)
+	if err != nil {
+		t.Fatalf("Failed to stream TAR: %v", err)
+	}
+
+	dstPath := filepath.Join(dir, "dst.tar")
+	if err := FilterTar(tarFilePath, tarIndexPath, dstPath, []string{"c.txt", "a.txt"}); err != nil {
+		t.Fatalf("Failed to filter TAR: %v", err)
+	}
+	filtered, _ := os.ReadFile(dstPath)
+	if !bytes.Equal(streamed, filtered) {
+		t.Errorf("Expected the streamed TAR to match the filtered one")
+	}
+
+	tr := tar.NewReader(bytes.NewReader(streamed))
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read streamed TAR: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	if len(names) != 2 || names[0] != "a.txt" || names[1] != "c.txt" {
+		t.Errorf("Expected a.txt and c.txt in offset order, got %v", names)
+	}
+
+	var buf bytes.Buffer
+	if err := StreamSubsetTar(&buf, tarFilePath, tarIndexPath, []string{"a.txt", "missing.txt"}); err == nil {
+		t.Error("Expected an error for a file not in the index")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing written for a missing file, got %d bytes", buf.Len())
+	}
+}
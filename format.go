@@ -0,0 +1,104 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// formatRowKey starts the index row recording the TAR format
+const formatRowKey = "#format"
+
+// entryHeader finds the header of the entry starting at pos in r. PAX records
+// and GNU long names are stored in extra header blocks ahead of the entry's
+// own header, which is the one followed by the entry's data. It returns the
+// offset of that header and the formats seen in the blocks on the way.
+func entryHeader(r io.ReaderAt, pos int64) (int64, tar.Format, error) {
+	var format tar.Format
+	block := make([]byte, headerSize)
+	for {
+		if _, err := r.ReadAt(block, pos); err != nil {
+			return 0, format, fmt.Errorf("failed to read tar header at offset %d: %w", pos, err)
+		}
+		format |= blockFormat(block)
+
+		switch block[156] {
+		case tar.TypeXHeader:
+			format |= tar.FormatPAX
+		case tar.TypeGNULongName, tar.TypeGNULongLink:
+			format |= tar.FormatGNU
+		case tar.TypeXGlobalHeader:
+			// Global headers are entries of their own
+			return pos, format | tar.FormatPAX, nil
+		default:
+			return pos, format, nil
+		}
+
+		size, err := parseOctal(block[124:136])
+		if err != nil {
+			return 0, format, fmt.Errorf("invalid size in tar header at offset %d: %w", pos, err)
+		}
+		pos += headerSize + padToBlock(size)
+	}
+}
+
+// blockFormat detects the format of a header block from its magic
+func blockFormat(block []byte) tar.Format {
+	switch {
+	case bytes.Equal(block[257:265], []byte("ustar\x0000")):
+		return tar.FormatUSTAR
+	case bytes.Equal(block[257:265], []byte("ustar  \x00")):
+		return tar.FormatGNU
+	default:
+		// V7 archives have no magic and no exported tar.Format
+		return tar.FormatUnknown
+	}
+}
+
+// headerSizeAt returns the size field of the header block at pos in r
+func headerSizeAt(r io.ReaderAt, pos int64) (int64, error) {
+	block := make([]byte, headerSize)
+	if _, err := r.ReadAt(block, pos); err != nil {
+		return 0, fmt.Errorf("failed to read tar header at offset %d: %w", pos, err)
+	}
+	size, err := parseOctal(block[124:136])
+	if err != nil {
+		return 0, fmt.Errorf("invalid size in tar header at offset %d: %w", pos, err)
+	}
+	return size, nil
+}
+
+// parseOctal parses a NUL or space terminated octal header field
+func parseOctal(field []byte) (int64, error) {
+	s := strings.Trim(string(field), " \x00")
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 8, 64)
+}
+
+// archiveFormat reduces the formats seen across an archive to the one
+// describing it: any PAX records make it PAX, otherwise any GNU extensions
+// make it GNU
+func archiveFormat(formats tar.Format) tar.Format {
+	for _, f := range []tar.Format{tar.FormatPAX, tar.FormatGNU, tar.FormatUSTAR} {
+		if formats&f != 0 {
+			return f
+		}
+	}
+	return tar.FormatUnknown
+}
+
+// parseFormat parses a format written by Format.String, returning
+// tar.FormatUnknown for names it doesn't know
+func parseFormat(name string) tar.Format {
+	for _, f := range []tar.Format{tar.FormatPAX, tar.FormatGNU, tar.FormatUSTAR} {
+		if f.String() == name {
+			return f
+		}
+	}
+	return tar.FormatUnknown
+}
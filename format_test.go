@@ -0,0 +1,152 @@
+package tarix
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTarFormats(t *testing.T) {
+	longName := "dir/" + strings.Repeat("n", 110) + ".txt"
+	withLongName := map[string]string{"a.txt": "short\n", longName: "long name content\n", "z.txt": "last\n"}
+	withoutLongName := map[string]string{"a.txt": "short\n", "z.txt": "last\n"}
+
+	// The fixtures were created from the same files with -b1 by GNU tar
+	// --format=gnu, --format=ustar (which can't store the long name) and
+	// --format=pax, and by bsdtar with its default format
+	tests := []struct {
+		fixture string
+		format  tar.Format
+		files   map[string]string
+	}{
+		{"format-gnu.tar", tar.FormatGNU, withLongName},
+		{"format-ustar.tar", tar.FormatUSTAR, withoutLongName},
+		{"format-pax.tar", tar.FormatPAX, withLongName},
+		{"format-bsdtar.tar", tar.FormatPAX, withLongName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			tarFilePath := filepath.Join("testdata", tt.fixture)
+			tarIndexPath := filepath.Join(t.TempDir(), tt.fixture+".index.json")
+			if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+				t.Fatalf("Failed to create TAR index: %v", err)
+			}
+
+			th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+			if err != nil {
+				t.Fatalf("Failed to open handle: %v", err)
+			}
+			defer th.Close()
+
+			stats := th.Index.Stats()
+			if stats.Format != tt.format {
+				t.Errorf("Expected format %v, got %v", tt.format, stats.Format)
+			}
+			if stats.Files != len(tt.files) {
+				t.Errorf("Expected %d files, got %d", len(tt.files), stats.Files)
+			}
+
+			// Extended headers must not shift the offsets of the data
+			for name, content := range tt.files {
+				data, err := th.ExtractBytesOfFile(name)
+				if err != nil {
+					t.Fatalf("Failed to extract %s: %v", name, err)
+				}
+				if string(data) != content {
+					t.Errorf("Unexpected content of %s: %q", name, data)
+				}
+			}
+		})
+	}
+}
+
+func TestTarixWriterPAX(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "pax.tar")
+	tarIndexPath := filepath.Join(dir, "pax.tar.index.json")
+
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	defer tarFile.Close()
+
+	// tar.Writer stores names over 100 characters in PAX records
+	longName := strings.Repeat("p", 150) + ".txt"
+	w := NewTarixWriter(tarFile)
+	for _, name := range []string{longName, "b.txt"} {
+		if err := w.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(name))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := w.Write([]byte(name)); err != nil {
+			t.Fatalf("Failed to write data: %v", err)
+		}
+	}
+	if err := w.Close(tarIndexPath); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	if th.Index.Format != tar.FormatPAX {
+		t.Errorf("Expected format PAX, got %v", th.Index.Format)
+	}
+	for _, name := range []string{longName, "b.txt"} {
+		data, err := th.ExtractBytesOfFile(name)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", name, err)
+		}
+		if string(data) != name {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+}
+
+func TestGlobalPAXHeader(t *testing.T) {
+	// testdata/pax-global.tar was created by git archive, which starts the
+	// archive with a global PAX header holding the commit ID
+	tarFilePath := filepath.Join("testdata", "pax-global.tar")
+	tarIndexPath := filepath.Join(t.TempDir(), "pax-global.tar.index.json")
+	if err := CreateTarIndex(tarFilePath, tarIndexPath); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	th.Verify = true
+
+	// The global header and its records take the first two blocks
+	for name, want := range map[string]struct {
+		start   int64
+		content string
+	}{
+		"a.txt":     {1024, "first\n"},
+		"c.txt":     {2048, "third\n"},
+		"dir/b.txt": {3584, "second\n"},
+	} {
+		fileInfo, err := th.Stat(name)
+		if err != nil {
+			t.Fatalf("Failed to find %s: %v", name, err)
+		}
+		if fileInfo.Start != want.start {
+			t.Errorf("Expected %s at %d, got %d", name, want.start, fileInfo.Start)
+		}
+		data, err := th.ExtractBytesOfFile(name)
+		if err != nil || string(data) != want.content {
+			t.Errorf("Unexpected content of %s: %q, %v", name, data, err)
+		}
+	}
+	if format := th.Index.Stats().Format; format != tar.FormatPAX {
+		t.Errorf("Expected format PAX, got %v", format)
+	}
+}
@@ -0,0 +1,208 @@
+package tarix
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FS is the filesystem extracted files are written to
+type FS interface {
+	// Create creates or truncates the named file for writing
+	Create(name string) (io.WriteCloser, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Chmod(name string, mode os.FileMode) error
+}
+
+// OSFS is the FS of the operating system
+type OSFS struct{}
+
+func (OSFS) Create(name string) (io.WriteCloser, error) {
+	return os.Create(name)
+}
+
+func (OSFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (OSFS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (OSFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (OSFS) Chown(name string, uid, gid int) error {
+	return os.Chown(name, uid, gid)
+}
+
+func (OSFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFS) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+func (OSFS) Link(oldname, newname string) error {
+	return os.Link(oldname, newname)
+}
+
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// MemFile is a file of a MemFS
+type MemFile struct {
+	Data []byte
+	Mode os.FileMode
+}
+
+// MemFS is an in-memory FS, so tests can check extracted trees without
+// touching the disk. Names are cleaned before use.
+type MemFS struct {
+	mu    sync.Mutex
+	Files map[string]*MemFile
+	Dirs  map[string]os.FileMode
+	// ModTimes holds the modification times set with Chtimes
+	ModTimes map[string]time.Time
+	// Owners holds the owners set with Chown
+	Owners map[string]MemOwner
+}
+
+// MemOwner is the numeric owner of a file of a MemFS
+type MemOwner struct {
+	Uid, Gid int
+}
+
+// NewMemFS creates an empty MemFS
+func NewMemFS() *MemFS {
+	return &MemFS{
+		Files:    map[string]*MemFile{},
+		Dirs:     map[string]os.FileMode{},
+		ModTimes: map[string]time.Time{},
+		Owners:   map[string]MemOwner{},
+	}
+}
+
+// hasDir reports whether dir exists; the current and root directories always do
+func (m *MemFS) hasDir(dir string) bool {
+	if dir == "." || dir == string(filepath.Separator) {
+		return true
+	}
+	_, ok := m.Dirs[dir]
+	return ok
+}
+
+func (m *MemFS) Create(name string) (io.WriteCloser, error) {
+	name = filepath.Clean(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.hasDir(filepath.Dir(name)) {
+		return nil, fmt.Errorf("create %s: %w", name, os.ErrNotExist)
+	}
+	if _, ok := m.Dirs[name]; ok {
+		return nil, fmt.Errorf("create %s: is a directory", name)
+	}
+
+	mode := os.FileMode(0666)
+	if f, ok := m.Files[name]; ok {
+		mode = f.Mode
+	}
+	m.Files[name] = &MemFile{Mode: mode}
+	return &memFileWriter{fs: m, name: name}, nil
+}
+
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	path = filepath.Clean(path)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dir := path; !m.hasDir(dir); dir = filepath.Dir(dir) {
+		if _, ok := m.Files[dir]; ok {
+			return fmt.Errorf("mkdir %s: not a directory", dir)
+		}
+		m.Dirs[dir] = perm
+	}
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode os.FileMode) error {
+	name = filepath.Clean(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.Files[name]; ok {
+		f.Mode = mode
+		return nil
+	}
+	if _, ok := m.Dirs[name]; ok {
+		m.Dirs[name] = mode
+		return nil
+	}
+	return fmt.Errorf("chmod %s: %w", name, os.ErrNotExist)
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	name = filepath.Clean(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.Files[name]; ok {
+		return fileInfo{DirEntry{name: filepath.Base(name), size: int64(len(f.Data))}}, nil
+	}
+	if m.hasDir(name) {
+		return fileInfo{DirEntry{name: filepath.Base(name), dir: true}}, nil
+	}
+	return nil, fmt.Errorf("stat %s: %w", name, os.ErrNotExist)
+}
+
+func (m *MemFS) Chtimes(name string, atime, mtime time.Time) error {
+	name = filepath.Clean(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Files[name]; !ok && !m.hasDir(name) {
+		return fmt.Errorf("chtimes %s: %w", name, os.ErrNotExist)
+	}
+	m.ModTimes[name] = mtime
+	return nil
+}
+
+func (m *MemFS) Chown(name string, uid, gid int) error {
+	name = filepath.Clean(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Files[name]; !ok && !m.hasDir(name) {
+		return fmt.Errorf("chown %s: %w", name, os.ErrNotExist)
+	}
+	m.Owners[name] = MemOwner{Uid: uid, Gid: gid}
+	return nil
+}
+
+// memFileWriter buffers writes and stores them in the MemFS on Close
+type memFileWriter struct {
+	fs   *MemFS
+	name string
+	buf  bytes.Buffer
+}
+
+func (w *memFileWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *memFileWriter) Close() error {
+	w.fs.mu.Lock()
+	defer w.fs.mu.Unlock()
+	if f, ok := w.fs.Files[w.name]; ok {
+		f.Data = w.buf.Bytes()
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package tarix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractAllMemFS(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "mem.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "a", "sub/dir/b.txt": "bb"})
+
+	tarIndexPath := filepath.Join(dir, "mem.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	fsys := NewMemFS()
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, "out", ExtractOptions{FS: fsys}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+
+	expected := map[string]string{
+		filepath.Join("out", "a.txt"):               "a",
+		filepath.Join("out", "sub", "dir", "b.txt"): "bb",
+	}
+	if len(fsys.Files) != len(expected) {
+		t.Errorf("Expected %d files, got %d", len(expected), len(fsys.Files))
+	}
+	for name, content := range expected {
+		f, ok := fsys.Files[name]
+		if !ok {
+			t.Errorf("Missing %s", name)
+			continue
+		}
+		if string(f.Data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, f.Data)
+		}
+		if f.Mode != 0644 {
+			t.Errorf("Unexpected mode of %s: %v", name, f.Mode)
+		}
+	}
+	if _, ok := fsys.Dirs[filepath.Join("out", "sub")]; !ok {
+		t.Errorf("Expected directory out/sub, got %v", fsys.Dirs)
+	}
+
+	// Nothing is written to the real disk
+	if _, err := os.Stat("out"); !os.IsNotExist(err) {
+		t.Errorf("Expected no out directory on disk, got %v", err)
+	}
+}
+
+func TestExtractFileMemFS(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"x.txt": "xyz"})
+
+	fsys := NewMemFS()
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "x.txt", "x.txt", ExtractOptions{FS: fsys}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if f, ok := fsys.Files["x.txt"]; !ok || string(f.Data) != "xyz" {
+		t.Errorf("Expected x.txt with xyz, got %v", fsys.Files)
+	}
+
+	// Parent directories must exist, as on disk
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "x.txt", "missing/x.txt", ExtractOptions{FS: fsys}); err == nil {
+		t.Error("Expected error for a missing parent directory")
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/t0mk/tarix
 
 go 1.22.2
+
+require golang.org/x/text v0.22.0
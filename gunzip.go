@@ -0,0 +1,95 @@
+package tarix
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// isGzip reports whether f starts with the gzip magic bytes
+func isGzip(f *os.File) (bool, error) {
+	magic := make([]byte, len(gzipMagic))
+	if _, err := f.ReadAt(magic, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read tar file: %w", err)
+	}
+	return bytes.Equal(magic, gzipMagic), nil
+}
+
+// decompressedSource is a Source reading a decompressed copy of a
+// gzip-compressed TAR. Close also closes the compressed file, releasing its
+// lock, and removes the temporary file of the copy, if any.
+type decompressedSource struct {
+	Source
+	compressed *os.File
+	tempPath   string
+}
+
+func (s *decompressedSource) Close() error {
+	err := s.Source.Close()
+	if s.tempPath != "" {
+		if rmErr := os.Remove(s.tempPath); rmErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to remove decompressed tar file: %w", rmErr))
+		}
+	}
+	return errors.Join(err, s.compressed.Close())
+}
+
+// newDecompressedSource decompresses the gzip-compressed TAR f once, into a
+// temporary file in opts.TempDir or, with opts.DecompressInMemory, into
+// memory, and returns a Source reading the copy. The Source owns f.
+func newDecompressedSource(f *os.File, opts HandleOptions) (Source, error) {
+	zr, err := gzip.NewReader(bufio.NewReader(io.NewSectionReader(f, 0, 1<<63-1)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress tar file: %w", err)
+	}
+	defer zr.Close()
+
+	if opts.DecompressInMemory {
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress tar file: %w", err)
+		}
+		return &decompressedSource{Source: NewReaderSource(bytes.NewReader(data), int64(len(data))), compressed: f}, nil
+	}
+
+	temp, err := os.CreateTemp(opts.TempDir, "tarix-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decompressed tar file: %w", err)
+	}
+	source, err := func() (Source, error) {
+		if _, err := io.Copy(temp, zr); err != nil {
+			return nil, fmt.Errorf("failed to decompress tar file: %w", err)
+		}
+		return NewFileSource(temp)
+	}()
+	if err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return nil, err
+	}
+	return &decompressedSource{Source: source, compressed: f, tempPath: temp.Name()}, nil
+}
+
+// decompressedName returns the name of the TAR inside the gzip-compressed
+// archive name: "a.tar" for "a.tar.gz" and "a.tgz"
+func decompressedName(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tgz"):
+		return name[:len(name)-len(".tgz")] + ".tar"
+	case strings.HasSuffix(lower, ".gz"):
+		return name[:len(name)-len(".gz")]
+	}
+	return name
+}
@@ -0,0 +1,84 @@
+package tarix
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecompressHandle(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "aaa", "b.txt": "bbbbb"})
+	data, err := os.ReadFile(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(data)
+	zw.Close()
+	gzPath := tarFilePath + ".gz"
+	if err := os.WriteFile(gzPath, gz.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write compressed TAR: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		tarPath  string
+		inMemory bool
+	}{
+		{"temp file", gzPath, false},
+		{"memory", gzPath, true},
+		{"uncompressed", tarFilePath, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			var log bytes.Buffer
+			opts := HandleOptions{
+				Decompress:         true,
+				DecompressInMemory: tc.inMemory,
+				TempDir:            tempDir,
+				Logger:             slog.New(slog.NewTextHandler(&log, nil)),
+			}
+			th, err := NewTarixHandleWithOptions(tc.tarPath, tarIndexPath, opts)
+			if err != nil {
+				t.Fatalf("Failed to open handle: %v", err)
+			}
+			th.Verify = true
+			if got, err := th.ExtractBytesOfFile("b.txt"); err != nil || string(got) != "bbbbb" {
+				t.Errorf("Expected bbbbb, got %q, %v", got, err)
+			}
+			if th.Source.Size() != int64(len(data)) {
+				t.Errorf("Expected size %d, got %d", len(data), th.Source.Size())
+			}
+			if log.Len() > 0 {
+				t.Errorf("Expected no warnings, got %q", log.String())
+			}
+
+			temps, _ := filepath.Glob(filepath.Join(tempDir, "*"))
+			if want := tc.tarPath == gzPath && !tc.inMemory; (len(temps) == 1) != want {
+				t.Errorf("Unexpected temporary files %v", temps)
+			}
+			if err := th.Close(); err != nil {
+				t.Fatalf("Failed to close handle: %v", err)
+			}
+			if temps, _ := filepath.Glob(filepath.Join(tempDir, "*")); len(temps) > 0 {
+				t.Errorf("Expected temporary files to be removed, got %v", temps)
+			}
+		})
+	}
+}
+
+func TestDecompressedName(t *testing.T) {
+	for name, want := range map[string]string{
+		"a.tar.gz": "a.tar",
+		"a.TGZ":    "a.tar",
+		"a.tar":    "a.tar",
+	} {
+		if got := decompressedName(name); got != want {
+			t.Errorf("decompressedName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
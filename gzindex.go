@@ -0,0 +1,263 @@
+package tarix
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// GzipAccessPoint is a place in a gzip file where decompression can start,
+// like the access points of zlib's zran example
+type GzipAccessPoint struct {
+	Out int64 `json:"out"` // Offset in the decompressed data
+	In  int64 `json:"in"`  // Offset in the gzip file
+	// Member is set for points at the header of a gzip member, which need no
+	// other state to decompress from
+	Member bool `json:"member,omitempty"`
+	// Bits, for points inside a member, is how many of the high bits of the
+	// byte before In start the deflate block, when it isn't byte aligned
+	Bits uint8 `json:"bits,omitempty"`
+	// Window, for points inside a member, holds up to 32KB of decompressed
+	// data before Out that the following blocks may refer back to
+	Window []byte `json:"window,omitempty"`
+}
+
+// GzipIndex lists the access points of a gzip file, sorted by Out, so that
+// the TAR inside can be read at the offsets of its TarIndex without
+// decompressing everything before them
+type GzipIndex struct {
+	Points []GzipAccessPoint `json:"points"`
+	// Size is the size of the decompressed data, zero if unknown
+	Size int64 `json:"size,omitempty"`
+}
+
+// BuildGzipIndex reads the gzip file r and returns an index with an access
+// point at each of its members. Random access is only as fine as the members
+// are small, so it suits archives compressed in independent members, as with
+// bgzip or by concatenating separately gzipped chunks. Points inside members
+// can be added from external tools that record inflate state.
+func BuildGzipIndex(r io.Reader) (*GzipIndex, error) {
+	cr := &countingReader{r: r}
+	br := bufio.NewReader(cr)
+	gi := &GzipIndex{}
+
+	var zr gzip.Reader
+	for {
+		// The gzip reader reads byte by byte from br, so it stops right after a member
+		in := cr.n - int64(br.Buffered())
+		if _, err := br.Peek(1); err == io.EOF {
+			break
+		}
+		if err := zr.Reset(br); err != nil {
+			return nil, fmt.Errorf("failed to read gzip member at offset %d: %w", in, err)
+		}
+		zr.Multistream(false)
+
+		gi.Points = append(gi.Points, GzipAccessPoint{Out: gi.Size, In: in, Member: true})
+		n, err := io.Copy(io.Discard, &zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress gzip member at offset %d: %w", in, err)
+		}
+		gi.Size += n
+	}
+	return gi, nil
+}
+
+// WriteGzipIndex saves gi to path as JSON
+func WriteGzipIndex(gi *GzipIndex, path string) error {
+	data, err := json.Marshal(gi)
+	if err != nil {
+		return fmt.Errorf("failed to encode gzip index: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write gzip index: %w", err)
+	}
+	return nil
+}
+
+// ReadGzipIndex loads a gzip index saved with WriteGzipIndex
+func ReadGzipIndex(path string) (*GzipIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip index: %w", err)
+	}
+	gi := &GzipIndex{}
+	if err := json.Unmarshal(data, gi); err != nil {
+		return nil, fmt.Errorf("failed to parse gzip index: %w", err)
+	}
+	if !sort.SliceIsSorted(gi.Points, func(i, j int) bool { return gi.Points[i].Out < gi.Points[j].Out }) {
+		return nil, fmt.Errorf("failed to parse gzip index: access points are not sorted")
+	}
+	return gi, nil
+}
+
+// NewReader returns a reader of the decompressed data of the gzip file r from
+// off on. It starts at the nearest access point before off and decompresses
+// forward from there.
+func (gi *GzipIndex) NewReader(r io.ReaderAt, off int64) (io.Reader, error) {
+	i := sort.Search(len(gi.Points), func(i int) bool { return gi.Points[i].Out > off }) - 1
+	if i < 0 {
+		return nil, fmt.Errorf("no gzip access point before offset %d", off)
+	}
+
+	zr, err := gi.readFrom(r, i)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, zr, off-gi.Points[i].Out); err != nil {
+		return nil, fmt.Errorf("failed to decompress up to offset %d: %w", off, err)
+	}
+	return zr, nil
+}
+
+// readFrom returns a reader of the decompressed data from the ith access point on
+func (gi *GzipIndex) readFrom(r io.ReaderAt, i int) (io.Reader, error) {
+	p := gi.Points[i]
+	if p.Member {
+		// Continues through the following members
+		zr, err := gzip.NewReader(bufio.NewReader(io.NewSectionReader(r, p.In, math.MaxInt64-p.In)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip member at offset %d: %w", p.In, err)
+		}
+		return zr, nil
+	}
+
+	var compressed flate.Reader
+	if p.Bits > 0 {
+		br := bufio.NewReader(io.NewSectionReader(r, p.In-1, math.MaxInt64-p.In+1))
+		compressed = &bitReader{r: br, skip: 8 - uint(p.Bits)}
+	} else {
+		compressed = bufio.NewReader(io.NewSectionReader(r, p.In, math.MaxInt64-p.In))
+	}
+	return &gzipStream{index: gi, r: r, cur: flate.NewReaderDict(compressed, p.Window), out: p.Out, inMember: true}, nil
+}
+
+// gzipStream reads the rest of a member from a point inside it, then the
+// following members from the access point at the start of the next one
+type gzipStream struct {
+	index    *GzipIndex
+	r        io.ReaderAt
+	cur      io.Reader
+	out      int64
+	inMember bool
+}
+
+func (s *gzipStream) Read(p []byte) (int, error) {
+	n, err := s.cur.Read(p)
+	s.out += int64(n)
+	if err != io.EOF || !s.inMember {
+		return n, err
+	}
+
+	// The deflate stream of the member ended
+	s.inMember = false
+	i := sort.Search(len(s.index.Points), func(i int) bool { return s.index.Points[i].Out >= s.out })
+	for ; i < len(s.index.Points) && s.index.Points[i].Out == s.out; i++ {
+		if s.index.Points[i].Member {
+			s.cur, err = s.index.readFrom(s.r, i)
+			return n, err
+		}
+	}
+	if s.out < s.index.Size {
+		return n, fmt.Errorf("no gzip access point at the member ending at offset %d", s.out)
+	}
+	return n, io.EOF
+}
+
+// bitReader reads a stream of bytes starting skip bits into r, for deflate
+// blocks that don't start on a byte boundary. Deflate packs bits starting
+// with the least significant one, so the skipped bits are the low ones.
+type bitReader struct {
+	r       io.ByteReader
+	skip    uint
+	cur     byte
+	started bool
+	done    bool
+}
+
+func (b *bitReader) ReadByte() (byte, error) {
+	if b.done {
+		return 0, io.EOF
+	}
+	if !b.started {
+		c, err := b.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		b.cur, b.started = c, true
+	}
+	next, err := b.r.ReadByte()
+	if errors.Is(err, io.EOF) {
+		// The high bits of the last byte are all that is left
+		b.done = true
+		return b.cur >> b.skip, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	c := b.cur>>b.skip | next<<(8-b.skip)
+	b.cur = next
+	return c, nil
+}
+
+func (b *bitReader) Read(p []byte) (int, error) {
+	for i := range p {
+		c, err := b.ReadByte()
+		if err != nil {
+			return i, err
+		}
+		p[i] = c
+	}
+	return len(p), nil
+}
+
+// GzipReaderAt reads the decompressed data of a gzip file at any offset,
+// using its GzipIndex. Use it with ExtractBytesFromReaderAt and a TarIndex of
+// the decompressed TAR.
+type GzipReaderAt struct {
+	r     io.ReaderAt
+	index *GzipIndex
+}
+
+// NewGzipReaderAt creates a GzipReaderAt for the gzip file r indexed by index
+func NewGzipReaderAt(r io.ReaderAt, index *GzipIndex) *GzipReaderAt {
+	return &GzipReaderAt{r: r, index: index}
+}
+
+// ReadAt reads len(p) bytes of decompressed data at off
+func (g *GzipReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if g.index.Size > 0 && off >= g.index.Size {
+		return 0, io.EOF
+	}
+	zr, err := g.index.NewReader(g.r, off)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(zr, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
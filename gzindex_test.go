@@ -0,0 +1,160 @@
+package tarix
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// gzipMembers compresses data as separate gzip members of chunk bytes each
+func gzipMembers(t *testing.T, data []byte, chunk int) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	for len(data) > 0 {
+		n := min(chunk, len(data))
+		zw := gzip.NewWriter(&out)
+		zw.Write(data[:n])
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Failed to compress: %v", err)
+		}
+		data = data[n:]
+	}
+	return out.Bytes()
+}
+
+func TestGzipIndexMembers(t *testing.T) {
+	dir := t.TempDir()
+	var tarData bytes.Buffer
+	paths, err := WriteSyntheticTar(&tarData, TarShape{Files: 40, FileSize: 3000, Seed: 2})
+	if err != nil {
+		t.Fatalf("Failed to write synthetic TAR: %v", err)
+	}
+	tarFilePath := filepath.Join(dir, "data.tar")
+	if err := os.WriteFile(tarFilePath, tarData.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write TAR: %v", err)
+	}
+	tarIndexPath := tarFilePath + ".index.json"
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	gz := gzipMembers(t, tarData.Bytes(), 20000)
+	gi, err := BuildGzipIndex(bytes.NewReader(gz))
+	if err != nil {
+		t.Fatalf("Failed to build gzip index: %v", err)
+	}
+	members := (tarData.Len() + 19999) / 20000
+	if len(gi.Points) != members || gi.Size != int64(tarData.Len()) {
+		t.Fatalf("Expected %d points over %d bytes, got %d over %d", members, tarData.Len(), len(gi.Points), gi.Size)
+	}
+
+	gzIndexPath := filepath.Join(dir, "data.tar.gz.gzindex")
+	if err := WriteGzipIndex(gi, gzIndexPath); err != nil {
+		t.Fatalf("Failed to write gzip index: %v", err)
+	}
+	if gi, err = ReadGzipIndex(gzIndexPath); err != nil {
+		t.Fatalf("Failed to read gzip index: %v", err)
+	}
+
+	// Files are read from the compressed archive at their offsets in the TAR,
+	// including ones spanning two members
+	r := NewGzipReaderAt(bytes.NewReader(gz), gi)
+	for i, p := range paths {
+		data, err := ExtractBytesFromReaderAt(index, r, p)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", p, err)
+		}
+		fileInfo := index.Files[hashFilePath(p)]
+		expected := tarData.Bytes()[fileInfo.Start+headerSize : fileInfo.Start+headerSize+fileInfo.Size]
+		if !bytes.Equal(data, expected) {
+			t.Errorf("Unexpected content of file %d, %s", i, p)
+		}
+	}
+
+	buf := make([]byte, 10)
+	if n, err := r.ReadAt(buf, gi.Size-4); n != 4 || err != io.EOF {
+		t.Errorf("Expected 4 bytes and io.EOF at the end, got %d, %v", n, err)
+	}
+}
+
+func TestGzipIndexWindowPoints(t *testing.T) {
+	data := make([]byte, 300000)
+	rnd := rand.New(rand.NewSource(3))
+	for i := range data {
+		// Compressible, with back references across flush points
+		data[i] = "abcdefgh"[rnd.Intn(8)]
+	}
+
+	// Two members, each flushed every 40000 bytes, recording the flush points
+	// with their windows as an external indexer would
+	var gz bytes.Buffer
+	gi := &GzipIndex{Size: int64(len(data))}
+	for _, member := range [][2]int{{0, 170000}, {170000, len(data)}} {
+		gi.Points = append(gi.Points, GzipAccessPoint{Out: int64(member[0]), In: int64(gz.Len()), Member: true})
+		zw := gzip.NewWriter(&gz)
+		for off := member[0]; off < member[1]; off += 40000 {
+			end := min(off+40000, member[1])
+			zw.Write(data[off:end])
+			if end == member[1] {
+				break
+			}
+			zw.Flush()
+			window := data[max(member[0], end-32768):end]
+			gi.Points = append(gi.Points, GzipAccessPoint{Out: int64(end), In: int64(gz.Len()), Window: window})
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Failed to compress: %v", err)
+		}
+	}
+
+	r := NewGzipReaderAt(bytes.NewReader(gz.Bytes()), gi)
+	for _, rng := range [][2]int{{0, 100}, {45000, 46000}, {79990, 80010}, {165000, 175000}, {250000, 300000}} {
+		buf := make([]byte, rng[1]-rng[0])
+		if _, err := r.ReadAt(buf, int64(rng[0])); err != nil && err != io.EOF {
+			t.Fatalf("Failed to read %v: %v", rng, err)
+		}
+		if !bytes.Equal(buf, data[rng[0]:rng[1]]) {
+			t.Errorf("Unexpected data in %v", rng)
+		}
+	}
+}
+
+func TestGzipIndexUnalignedPoint(t *testing.T) {
+	data := bytes.Repeat([]byte("unaligned deflate block "), 100)
+	var raw bytes.Buffer
+	fw, _ := flate.NewWriter(&raw, flate.BestCompression)
+	fw.Write(data)
+	fw.Close()
+
+	// Shift the deflate stream so it starts 3 bits into its first byte, after
+	// 5 bits belonging to whatever came before
+	for _, bits := range []uint8{1, 3, 7} {
+		skip := 8 - uint(bits)
+		stream := raw.Bytes()
+		shifted := make([]byte, len(stream)+1)
+		shifted[0] = 0xff >> bits // Unrelated low bits
+		for i, c := range stream {
+			shifted[i] |= c << skip
+			shifted[i+1] = c >> (8 - skip)
+		}
+		file := append([]byte("junk"), shifted...)
+
+		gi := &GzipIndex{Points: []GzipAccessPoint{{Out: 0, In: 5, Bits: bits}}, Size: int64(len(data))}
+		buf := make([]byte, len(data))
+		if _, err := NewGzipReaderAt(bytes.NewReader(file), gi).ReadAt(buf, 0); err != nil {
+			t.Fatalf("Failed to read with %d bits: %v", bits, err)
+		}
+		if !bytes.Equal(buf, data) {
+			t.Errorf("Unexpected data with %d bits", bits)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package tarix
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler returns an http.Handler serving the files of th read-only. The URL
+// path, relative to th.Root, is looked up in the index, so nothing is
+// unpacked. Content-Length comes from the indexed size and paths not in the
+// index get 404. The Content-Type is the MIME type recorded in the index, if
+// any. Range requests get 206 Partial Content with only the requested bytes
+// read from the TAR, several ranges as multipart/byteranges, and
+// unsatisfiable ones 416. Sparse files are always sent whole. Directories
+// are not listed. Data is served as stored, without th.Transform.
+func Handler(th *TarixHandle) http.Handler {
+	return HandlerWithOptions(th, HandlerOptions{})
+}
+
+// HandlerOptions configures HandlerWithOptions
+type HandlerOptions struct {
+	// AccessLog, if set, is called after each request with the requested
+	// file path, the bytes of the response body and its status, e.g. to
+	// record which files are read most
+	AccessLog func(path string, bytesServed int64, status int)
+}
+
+// HandlerWithOptions is like Handler with options
+func HandlerWithOptions(th *TarixHandle, opts HandlerOptions) http.Handler {
+	handler := serveHandler(th)
+	if opts.AccessLog == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		aw := &accessLogWriter{ResponseWriter: w}
+		handler.ServeHTTP(aw, r)
+		if aw.status == 0 {
+			aw.status = http.StatusOK
+		}
+		opts.AccessLog(requestPath(r), aw.bytes, aw.status)
+	})
+}
+
+// requestPath returns the file path requested by r
+func requestPath(r *http.Request) string {
+	return strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+}
+
+func serveHandler(th *TarixHandle) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		filePath := requestPath(r)
+		fileInfo, ok := th.Index.lookup(th.key(filePath))
+		if filePath == "" || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if fileInfo.MIMEType != "" {
+			w.Header().Set("Content-Type", fileInfo.MIMEType)
+		}
+		if fileInfo.Sparse {
+			// The data of sparse files isn't contiguous in the TAR, so they
+			// are sent whole, without ranges
+			serveSparse(w, r, th, filePath, fileInfo.Size)
+			return
+		}
+		sr, err := th.SectionReaderOf(filePath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// ServeContent handles Range and conditional requests, and sets the
+		// content type from the extension unless the index recorded one
+		http.ServeContent(w, r, path.Base(filePath), time.Time{}, sr)
+	})
+}
+
+// serveSparse sends the whole sparse file filePath of size bytes, with its
+// holes filled with zeros
+func serveSparse(w http.ResponseWriter, r *http.Request, th *TarixHandle, filePath string, size int64) {
+	key := th.key(filePath)
+	fileInfo, err := th.fileEntry(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data, err := th.openEntry(key, fileInfo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if w.Header().Get("Content-Type") == "" {
+		if ctype := mime.TypeByExtension(path.Ext(filePath)); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	if r.Method == http.MethodHead {
+		return
+	}
+	io.Copy(w, data)
+}
+
+// accessLogWriter records the status and the body bytes of a response
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
@@ -0,0 +1,166 @@
+package tarix
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "abcdef", "b.html": "<p>b</p>"})
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	handler := Handler(th)
+
+	serve := func(method, target string, header http.Header) *http.Response {
+		req := httptest.NewRequest(method, target, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+	body := func(resp *http.Response) string {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read body: %v", err)
+		}
+		return string(data)
+	}
+
+	resp := serve(http.MethodGet, "/a.txt", nil)
+	if resp.StatusCode != http.StatusOK || body(resp) != "abcdef" || resp.Header.Get("Content-Length") != "6" {
+		t.Errorf("Unexpected response for a.txt: %d %v", resp.StatusCode, resp.Header)
+	}
+	if ct := serve(http.MethodGet, "/b.html", nil).Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected an HTML content type, got %q", ct)
+	}
+
+	resp = serve(http.MethodGet, "/a.txt", http.Header{"Range": {"bytes=1-3"}})
+	if resp.StatusCode != http.StatusPartialContent || body(resp) != "bcd" || resp.Header.Get("Content-Range") != "bytes 1-3/6" {
+		t.Errorf("Unexpected range response: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	resp = serve(http.MethodHead, "/a.txt", nil)
+	if resp.StatusCode != http.StatusOK || body(resp) != "" || resp.Header.Get("Content-Length") != "6" {
+		t.Errorf("Unexpected HEAD response: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	for _, target := range []string{"/missing.txt", "/", "/../a.txt/.."} {
+		if resp := serve(http.MethodGet, target, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s, got %d", target, resp.StatusCode)
+		}
+	}
+	if resp := serve(http.MethodPost, "/a.txt", nil); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandlerRanges(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"media.bin": content})
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	get := func(rangeHeader string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/media.bin", nil)
+		req.Header.Set("Range", rangeHeader)
+		rec := httptest.NewRecorder()
+		Handler(th).ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	// A suffix range, as players send to read the end of a file
+	resp := get("bytes=-5")
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || string(data) != "56789" || resp.Header.Get("Content-Range") != "bytes 995-999/1000" {
+		t.Errorf("Unexpected suffix range response: %d %q %v", resp.StatusCode, data, resp.Header)
+	}
+
+	// Several ranges come back as a multipart response
+	resp = get("bytes=0-1,10-12")
+	if resp.StatusCode != http.StatusPartialContent || !strings.HasPrefix(resp.Header.Get("Content-Type"), "multipart/byteranges") {
+		t.Fatalf("Unexpected multi-range response: %d %v", resp.StatusCode, resp.Header)
+	}
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Failed to parse content type: %v", err)
+	}
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		data, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Range")+" "+string(data))
+	}
+	if expected := []string{"bytes 0-1/1000 01", "bytes 10-12/1000 012"}; !reflect.DeepEqual(parts, expected) {
+		t.Errorf("Expected parts %q, got %q", expected, parts)
+	}
+
+	resp = get("bytes=1000-")
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable || resp.Header.Get("Content-Range") != "bytes */1000" {
+		t.Errorf("Expected 416 for a range past the end, got %d %v", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestHandlerAccessLog(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "abcdef"})
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	type access struct {
+		path   string
+		bytes  int64
+		status int
+	}
+	var accesses []access
+	handler := HandlerWithOptions(th, HandlerOptions{AccessLog: func(path string, bytesServed int64, status int) {
+		accesses = append(accesses, access{path, bytesServed, status})
+	}})
+
+	for _, req := range []struct{ method, target, rangeHeader string }{
+		{http.MethodGet, "/a.txt", ""},
+		{http.MethodGet, "/./a.txt", "bytes=1-3"},
+		{http.MethodHead, "/a.txt", ""},
+		{http.MethodGet, "/missing.txt", ""},
+		{http.MethodPost, "/a.txt", ""},
+	} {
+		r := httptest.NewRequest(req.method, req.target, nil)
+		if req.rangeHeader != "" {
+			r.Header.Set("Range", req.rangeHeader)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	expected := []access{
+		{"a.txt", 6, http.StatusOK},
+		{"a.txt", 3, http.StatusPartialContent},
+		{"a.txt", 0, http.StatusOK},
+		{"missing.txt", int64(len("404 page not found\n")), http.StatusNotFound},
+		{"a.txt", int64(len("method not allowed\n")), http.StatusMethodNotAllowed},
+	}
+	if !reflect.DeepEqual(accesses, expected) {
+		t.Errorf("Expected accesses %v, got %v", expected, accesses)
+	}
+}
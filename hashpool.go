@@ -0,0 +1,85 @@
+package tarix
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// hashResult is the outcome of a hashJob
+type hashResult struct {
+	sum string
+	err error
+}
+
+// hashJob asks for the SHA-256 of size bytes at offset
+type hashJob struct {
+	offset int64
+	size   int64
+	result chan<- hashResult
+}
+
+// hashPool hashes file contents in worker goroutines reading with ReadAt, so
+// the indexing loop can move on to the next header meanwhile
+type hashPool struct {
+	r     io.ReaderAt
+	jobs  chan hashJob
+	wg    sync.WaitGroup
+	limit int
+}
+
+// newHashPool starts workers goroutines hashing data read from r
+func newHashPool(r io.ReaderAt, workers int) *hashPool {
+	p := &hashPool{r: r, jobs: make(chan hashJob, workers), limit: 4 * workers}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				sum, err := hashSection(p.r, job.offset, job.size)
+				job.result <- hashResult{sum: sum, err: err}
+			}
+		}()
+	}
+	return p
+}
+
+// submit queues the hashing of size bytes at offset
+func (p *hashPool) submit(offset, size int64) <-chan hashResult {
+	result := make(chan hashResult, 1)
+	p.jobs <- hashJob{offset: offset, size: size, result: result}
+	return result
+}
+
+// close waits for queued jobs to finish and stops the workers
+func (p *hashPool) close() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+// hashSection returns the hex SHA-256 of size bytes at offset in r
+func hashSection(r io.ReaderAt, offset, size int64) (string, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, io.NewSectionReader(r, offset, size))
+	if err != nil {
+		return "", fmt.Errorf("failed to read file data: %w", err)
+	}
+	if n != size {
+		return "", fmt.Errorf("failed to read file data: %w", io.ErrUnexpectedEOF)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// pendingFile is an indexed file that is finished once its content hash,
+// if one is being computed in the background, is ready
+type pendingFile struct {
+	key     string
+	offset  int64 // Where the next entry starts
+	files   int   // Number of files indexed up to and including this one
+	entries int64 // Number of entries read up to and including this one
+	formats tar.Format
+	hash    <-chan hashResult
+}
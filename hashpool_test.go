@@ -0,0 +1,66 @@
+package tarix
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeRandomTar writes a TAR of count files of size random bytes each
+func writeRandomTar(tb testing.TB, tarFilePath string, count, size int) {
+	tb.Helper()
+
+	files := map[string]string{}
+	for i := 0; i < count; i++ {
+		data := make([]byte, size)
+		rand.Read(data)
+		files[fmt.Sprintf("file%04d.bin", i)] = string(data)
+	}
+	writeTestTar(tb, tarFilePath, files)
+}
+
+func TestParallelHashDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "hash.tar")
+	writeRandomTar(t, tarFilePath, 50, 10000)
+
+	var indexes [][]byte
+	for _, workers := range []int{1, 2, 8} {
+		tarIndexPath := filepath.Join(dir, fmt.Sprintf("hash-%d.index.json", workers))
+		opts := IndexOptions{ContentHash: true, HashWorkers: workers, CheckpointEvery: 7}
+		if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, opts); err != nil {
+			t.Fatalf("Failed to create TAR index with %d workers: %v", workers, err)
+		}
+		data, err := os.ReadFile(tarIndexPath)
+		if err != nil {
+			t.Fatalf("Failed to read index: %v", err)
+		}
+		indexes = append(indexes, data)
+	}
+
+	for i := 1; i < len(indexes); i++ {
+		if !bytes.Equal(indexes[0], indexes[i]) {
+			t.Errorf("Index %d differs from the one hashed inline", i)
+		}
+	}
+}
+
+func BenchmarkIndexChecksum(b *testing.B) {
+	tarFilePath := filepath.Join(b.TempDir(), "bench.tar")
+	writeRandomTar(b, tarFilePath, 200, 256*1024)
+	tarIndexPath := tarFilePath + ".index.json"
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			opts := IndexOptions{ContentHash: true, HashWorkers: workers}
+			for i := 0; i < b.N; i++ {
+				if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, opts); err != nil {
+					b.Fatalf("Failed to create TAR index: %v", err)
+				}
+			}
+		})
+	}
+}
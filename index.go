@@ -0,0 +1,137 @@
+package tarix
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// indexEntry is an entry of the sorted TarIndex representation
+type indexEntry struct {
+	Key string
+	FileIndex
+}
+
+// lookup returns the entry stored under key
+func (ti *TarIndex) lookup(key string) (FileIndex, bool) {
+	if ti.sorted == nil {
+		fileInfo, ok := ti.Files[key]
+		return fileInfo, ok
+	}
+
+	i := sort.Search(len(ti.sorted), func(i int) bool { return ti.sorted[i].Key >= key })
+	if i < len(ti.sorted) && ti.sorted[i].Key == key {
+		return ti.sorted[i].FileIndex, true
+	}
+	return FileIndex{}, false
+}
+
+// count returns the number of entries in the index
+func (ti *TarIndex) count() int {
+	if ti.sorted == nil {
+		return len(ti.Files)
+	}
+	return len(ti.sorted)
+}
+
+// each calls fn for every entry in the index
+func (ti *TarIndex) each(fn func(key string, fileInfo FileIndex)) {
+	if ti.sorted == nil {
+		for key, fileInfo := range ti.Files {
+			fn(key, fileInfo)
+		}
+		return
+	}
+	for _, entry := range ti.sorted {
+		fn(entry.Key, entry.FileIndex)
+	}
+}
+
+// Get returns the entry stored under key, like a lookup in Files that also
+// works for indexes loaded with LoadOptions.Sorted
+func (ti *TarIndex) Get(key string) (FileIndex, bool) {
+	return ti.lookup(key)
+}
+
+// Len returns the number of entries in the index
+func (ti *TarIndex) Len() int {
+	return ti.count()
+}
+
+// Keys returns the keys of the index in sorted order
+func (ti *TarIndex) Keys() []string {
+	keys := make([]string, 0, ti.count())
+	ti.eachSorted(func(key string, _ FileIndex) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// eachSorted calls fn for every entry in the index in key order
+func (ti *TarIndex) eachSorted(fn func(key string, fileInfo FileIndex)) {
+	ti.Walk(func(key string, fileInfo FileIndex) error {
+		fn(key, fileInfo)
+		return nil
+	})
+}
+
+// eachByOffset calls fn for every entry in the index in archive order, by
+// Start, then by key for deduplicated files sharing a Start
+func (ti *TarIndex) eachByOffset(fn func(key string, fileInfo FileIndex)) {
+	entries := make([]indexEntry, 0, ti.count())
+	ti.eachSorted(func(key string, fileInfo FileIndex) {
+		entries = append(entries, indexEntry{Key: key, FileIndex: fileInfo})
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Start < entries[j].Start
+	})
+	for _, entry := range entries {
+		fn(entry.Key, entry.FileIndex)
+	}
+}
+
+// Walk calls fn for every entry in the index in key order. fileInfo.Path is
+// set for indexes built with IndexOptions.StorePaths. Walk stops at the first
+// error returned by fn and returns it, except for filepath.SkipAll, which
+// stops it without an error.
+func (ti *TarIndex) Walk(fn func(key string, fileInfo FileIndex) error) error {
+	var err error
+	if ti.sorted != nil {
+		for _, entry := range ti.sorted {
+			if err = fn(entry.Key, entry.FileIndex); err != nil {
+				break
+			}
+		}
+	} else {
+		keys := make([]string, 0, len(ti.Files))
+		for key := range ti.Files {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			if err = fn(key, ti.Files[key]); err != nil {
+				break
+			}
+		}
+	}
+	if err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// sortEntries sorts entries by key. When a key repeats, the entry read last
+// wins, as it would when filling the Files map.
+func sortEntries(entries []indexEntry) []indexEntry {
+	slices.SortStableFunc(entries, func(a, b indexEntry) int { return strings.Compare(a.Key, b.Key) })
+
+	deduped := entries[:0]
+	for i, entry := range entries {
+		if i+1 < len(entries) && entries[i+1].Key == entry.Key {
+			continue
+		}
+		deduped = append(deduped, entry)
+	}
+	return deduped
+}
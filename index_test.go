@@ -0,0 +1,212 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// syntheticIndex builds an index with n entries keyed like real path hashes
+func syntheticIndex(n int) *TarIndex {
+	index := &TarIndex{Files: make(map[string]FileIndex, n)}
+	for i := 0; i < n; i++ {
+		index.Files[hashFilePath(fmt.Sprintf("dir%d/file%d.txt", i%1000, i))] = FileIndex{
+			Start: int64(i) * 1024,
+			Size:  int64(i % 512),
+		}
+	}
+	return index
+}
+
+func TestSortedIndexLookup(t *testing.T) {
+	index := syntheticIndex(1000)
+	indexPath := filepath.Join(t.TempDir(), "synthetic.index.json")
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	sorted, err := ReadTarIndexWithOptions(indexPath, LoadOptions{Sorted: true})
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if sorted.Files != nil {
+		t.Errorf("Expected no map in sorted mode")
+	}
+	if sorted.count() != len(index.Files) {
+		t.Fatalf("Expected %d entries, got %d", len(index.Files), sorted.count())
+	}
+	for key, fileInfo := range index.Files {
+		got, ok := sorted.lookup(key)
+		if !ok || got != fileInfo {
+			t.Fatalf("Lookup of %s returned %+v, %v; expected %+v", key, got, ok, fileInfo)
+		}
+	}
+	if _, ok := sorted.lookup("ffffffffffffffff"); ok {
+		t.Errorf("Expected missing key not to be found")
+	}
+}
+
+func TestSortEntriesKeepsLast(t *testing.T) {
+	entries := sortEntries([]indexEntry{
+		{Key: "b", FileIndex: FileIndex{Start: 1}},
+		{Key: "a", FileIndex: FileIndex{Start: 2}},
+		{Key: "b", FileIndex: FileIndex{Start: 3}},
+	})
+	if len(entries) != 2 || entries[0].Key != "a" || entries[1].Key != "b" || entries[1].Start != 3 {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+const benchmarkIndexEntries = 1000000
+
+func writeBenchmarkIndex(b *testing.B) (*TarIndex, string) {
+	b.Helper()
+	index := syntheticIndex(benchmarkIndexEntries)
+	indexPath := filepath.Join(b.TempDir(), "benchmark.index.json")
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		b.Fatalf("Failed to write index: %v", err)
+	}
+	return index, indexPath
+}
+
+// BenchmarkIndexMemory reports the heap used per entry by each representation
+func BenchmarkIndexMemory(b *testing.B) {
+	_, indexPath := writeBenchmarkIndex(b)
+
+	for _, mode := range []struct {
+		name string
+		opts LoadOptions
+	}{{"map", LoadOptions{}}, {"sorted", LoadOptions{Sorted: true}}} {
+		b.Run(mode.name, func(b *testing.B) {
+			var perEntry float64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				index, err := ReadTarIndexWithOptions(indexPath, mode.opts)
+				if err != nil {
+					b.Fatalf("Failed to read index: %v", err)
+				}
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				perEntry = float64(after.HeapAlloc-before.HeapAlloc) / float64(index.count())
+				runtime.KeepAlive(index)
+			}
+			b.ReportMetric(perEntry, "B/entry")
+		})
+	}
+}
+
+// BenchmarkIndexLookup compares point lookup latency of each representation
+func BenchmarkIndexLookup(b *testing.B) {
+	source, indexPath := writeBenchmarkIndex(b)
+	keys := make([]string, 0, len(source.Files))
+	for key := range source.Files {
+		keys = append(keys, key)
+	}
+
+	for _, mode := range []struct {
+		name string
+		opts LoadOptions
+	}{{"map", LoadOptions{}}, {"sorted", LoadOptions{Sorted: true}}} {
+		index, err := ReadTarIndexWithOptions(indexPath, mode.opts)
+		if err != nil {
+			b.Fatalf("Failed to read index: %v", err)
+		}
+		b.Run(mode.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, ok := index.lookup(keys[i%len(keys)]); !ok {
+					b.Fatalf("Key not found")
+				}
+			}
+		})
+	}
+}
+
+func TestIndexWalk(t *testing.T) {
+	index := syntheticIndex(100)
+	indexPath := filepath.Join(t.TempDir(), "walk.index.json")
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	sorted, err := ReadTarIndexWithOptions(indexPath, LoadOptions{Sorted: true})
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	// Both representations walk the same entries in key order
+	for _, ti := range []*TarIndex{index, sorted} {
+		var keys []string
+		err := ti.Walk(func(key string, fileInfo FileIndex) error {
+			if fileInfo != index.Files[key] {
+				t.Errorf("Unexpected entry of %s: %+v", key, fileInfo)
+			}
+			keys = append(keys, key)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(keys) != len(index.Files) || !slices.IsSorted(keys) {
+			t.Errorf("Expected %d keys in order, got %v", len(index.Files), keys)
+		}
+	}
+
+	errStop := errors.New("stop")
+	visited := 0
+	err = index.Walk(func(string, FileIndex) error {
+		visited++
+		if visited == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || visited != 3 {
+		t.Errorf("Expected to stop with the error after 3 entries, got %v after %d", err, visited)
+	}
+
+	visited = 0
+	err = sorted.Walk(func(string, FileIndex) error {
+		visited++
+		return filepath.SkipAll
+	})
+	if err != nil || visited != 1 {
+		t.Errorf("Expected SkipAll to stop without an error, got %v after %d", err, visited)
+	}
+}
+
+func TestIndexAccessors(t *testing.T) {
+	index := syntheticIndex(100)
+	indexPath := filepath.Join(t.TempDir(), "synthetic.index.json")
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	for _, opts := range []LoadOptions{{}, {Sorted: true}} {
+		loaded, err := ReadTarIndexWithOptions(indexPath, opts)
+		if err != nil {
+			t.Fatalf("Failed to read index: %v", err)
+		}
+		if loaded.Len() != len(index.Files) {
+			t.Errorf("Expected %d entries, got %d", len(index.Files), loaded.Len())
+		}
+
+		keys := loaded.Keys()
+		if len(keys) != len(index.Files) || !slices.IsSorted(keys) {
+			t.Errorf("Expected %d sorted keys, got %d", len(index.Files), len(keys))
+		}
+		for _, key := range keys {
+			if fileInfo, ok := loaded.Get(key); !ok || fileInfo != index.Files[key] {
+				t.Errorf("Expected %+v for %s, got %+v, %v", index.Files[key], key, fileInfo, ok)
+			}
+		}
+		if _, ok := loaded.Get("0000000000000000"); ok {
+			t.Errorf("Expected a missing key not to be found")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package tarix
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WriteTarIndexJSON saves index to indexPath as JSON, the TarIndex with its
+// fields named by their json tags. Like the CSV index it is written to a
+// temporary file renamed into place.
+func WriteTarIndexJSON(index *TarIndex, indexPath string) error {
+	// The Files map is empty for sorted indexes
+	out := *index
+	out.Files = map[string]FileIndex{}
+	index.each(func(key string, fileInfo FileIndex) {
+		out.Files[key] = fileInfo
+	})
+	return writeIndexFile(indexPath, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(&out); err != nil {
+			return fmt.Errorf("failed to write index file: %w", err)
+		}
+		return nil
+	})
+}
+
+// ReadTarIndexJSON reads an index written by WriteTarIndexJSON, checking
+// its entries like ReadTarIndex
+func ReadTarIndexJSON(indexPath string) (*TarIndex, error) {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index file: %w", err)
+	}
+	index := &TarIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIndexCorrupt, err)
+	}
+	if index.Files == nil {
+		index.Files = map[string]FileIndex{}
+	}
+	for key, fileInfo := range index.Files {
+		if err := validateEntry(key, fileInfo.Start, fileInfo.Size, index.ArchiveSize); err != nil {
+			return nil, err
+		}
+		if fileInfo.HeaderBlocks < 0 || int64(fileInfo.HeaderBlocks) > fileInfo.Start/headerSize {
+			return nil, fmt.Errorf("%w: header blocks of %s start before the archive", ErrIndexCorrupt, key)
+		}
+	}
+	if _, err := ParseNormForm(string(index.Normalize)); err != nil {
+		return nil, err
+	}
+
+	keyScheme, err := lookupKeyScheme(index.KeyScheme)
+	if err != nil {
+		return nil, err
+	}
+	index.keys = keyScheme.Func
+	return index, nil
+}
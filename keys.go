@@ -0,0 +1,111 @@
+package tarix
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// keySchemeRowKey starts the index row naming the key scheme
+const keySchemeRowKey = "#keys"
+
+// KeyFunc maps a normalized file path to its key in the index
+type KeyFunc func(filePath string) string
+
+// KeyScheme is a KeyFunc with a name. The name is recorded in the index, so
+// readers look files up with the same function the index was built with.
+type KeyScheme struct {
+	Name string
+	Func KeyFunc
+}
+
+// Key returns the key of filePath, normalizing it the way indexing does
+func (s KeyScheme) Key(filePath string) string {
+	return s.Func(normalizePath(filePath, ""))
+}
+
+// MD5KeyScheme keys files by the MD5 of their path, truncated to HashLen hex
+// characters. It is the default.
+var MD5KeyScheme = KeyScheme{Name: "md5", Func: hashFilePath}
+
+// PathKeyScheme keys files by their normalized path
+var PathKeyScheme = KeyScheme{Name: "path", Func: func(filePath string) string { return filePath }}
+
+// SHA256KeyScheme keys files by the full hex SHA-256 of their path, for
+// indexes large enough that truncated MD5 keys could collide
+var SHA256KeyScheme = KeyScheme{Name: "sha256", Func: func(filePath string) string {
+	sum := sha256.Sum256([]byte(filePath))
+	return hex.EncodeToString(sum[:])
+}}
+
+var (
+	keySchemesMu sync.RWMutex
+	keySchemes   = map[string]KeyScheme{
+		MD5KeyScheme.Name:    MD5KeyScheme,
+		PathKeyScheme.Name:   PathKeyScheme,
+		SHA256KeyScheme.Name: SHA256KeyScheme,
+	}
+)
+
+// RegisterKeyScheme makes a custom scheme known to ReadTarIndex, which fails
+// on indexes built with an unregistered scheme. Like sql.Register, it panics
+// if the scheme has no name or function, or the name is already taken.
+func RegisterKeyScheme(scheme KeyScheme) {
+	keySchemesMu.Lock()
+	defer keySchemesMu.Unlock()
+	if scheme.Name == "" || scheme.Func == nil {
+		panic("tarix: RegisterKeyScheme needs a name and a function")
+	}
+	if _, exists := keySchemes[scheme.Name]; exists {
+		panic("tarix: RegisterKeyScheme called twice for scheme " + scheme.Name)
+	}
+	keySchemes[scheme.Name] = scheme
+}
+
+// lookupKeyScheme returns the registered scheme called name, "" meaning the default
+func lookupKeyScheme(name string) (KeyScheme, error) {
+	if name == "" {
+		return MD5KeyScheme, nil
+	}
+	keySchemesMu.RLock()
+	defer keySchemesMu.RUnlock()
+	scheme, ok := keySchemes[name]
+	if !ok {
+		return KeyScheme{}, fmt.Errorf("unknown key scheme %q, register it with RegisterKeyScheme", name)
+	}
+	return scheme, nil
+}
+
+// keyFunc returns the function the keys of the index were made with. Indexes
+// naming an unregistered scheme can't be loaded, so only indexes put together
+// by hand can get here with one, and they get the default.
+// Paths are put in the index's normalization form first.
+func (ti *TarIndex) keyFunc() KeyFunc {
+	keys := ti.keys
+	if keys == nil {
+		keys = hashFilePath
+		if scheme, err := lookupKeyScheme(ti.KeyScheme); err == nil {
+			keys = scheme.Func
+		}
+	}
+	if !ti.Normalize.normalizes() {
+		return keys
+	}
+	return func(filePath string) string {
+		return keys(ti.Normalize.apply(filePath))
+	}
+}
+
+// Key returns the key of filePath in the index. Like at index time, the
+// index's Root is stripped from the path if present.
+func (ti *TarIndex) Key(filePath string) string {
+	return ti.keyFunc()(normalizePath(filePath, ti.Root))
+}
+
+// Contains reports whether filePath is in the index, looking it up by the
+// same key as extraction does
+func (ti *TarIndex) Contains(filePath string) bool {
+	_, ok := ti.lookup(ti.Key(filePath))
+	return ok
+}
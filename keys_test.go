@@ -0,0 +1,118 @@
+package tarix
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathKeyScheme(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "keys.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "a", "dir/b.txt": "bb"})
+
+	tarIndexPath := filepath.Join(dir, "keys.tar.index.json")
+	opts := IndexOptions{KeyScheme: KeyScheme{Name: "path"}}
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, opts); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	if th.Index.KeyScheme != "path" {
+		t.Errorf("Expected key scheme path, got %q", th.Index.KeyScheme)
+	}
+	if _, ok := th.Index.Files["dir/b.txt"]; !ok {
+		t.Errorf("Expected paths as keys, got %v", th.Index.Files)
+	}
+	if data, err := th.ExtractBytesOfFile("./dir/b.txt"); err != nil || string(data) != "bb" {
+		t.Errorf("Expected bb, got %q, %v", data, err)
+	}
+	if data, err := th.ExtractBytesByKey("a.txt"); err != nil || string(data) != "a" {
+		t.Errorf("Expected a, got %q, %v", data, err)
+	}
+}
+
+func TestCustomKeyScheme(t *testing.T) {
+	upper := KeyScheme{Name: "test-upper", Func: strings.ToUpper}
+	RegisterKeyScheme(upper)
+
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "custom.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "a"})
+
+	tarIndexPath := filepath.Join(dir, "custom.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{KeyScheme: upper}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	if key := th.Index.Key("a.txt"); key != "A.TXT" {
+		t.Errorf("Expected key A.TXT, got %q", key)
+	}
+	if data, err := th.ExtractBytesOfFile("a.txt"); err != nil || string(data) != "a" {
+		t.Errorf("Expected a, got %q, %v", data, err)
+	}
+}
+
+func TestUnknownKeyScheme(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "unknown.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "a"})
+
+	tarIndexPath := filepath.Join(dir, "unknown.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{KeyScheme: KeyScheme{Name: "nope"}}); err == nil {
+		t.Error("Expected error for an unregistered scheme name")
+	}
+
+	// An index built with an unregistered scheme can be written but not read
+	unregistered := KeyScheme{Name: "test-unregistered", Func: strings.ToUpper}
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{KeyScheme: unregistered}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	if _, err := ReadTarIndex(tarIndexPath); err == nil {
+		t.Error("Expected error reading an index with an unregistered scheme")
+	}
+}
+
+func TestContains(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "contains.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"data/a.txt": "a", "data/dir/b.txt": "bb"})
+
+	tarIndexPath := filepath.Join(dir, "contains.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	// Paths are normalized like for extraction
+	for _, path := range []string{"data/a.txt", "./data/dir/b.txt", "data//dir/b.txt", "data/dir/../a.txt"} {
+		if !th.Index.Contains(path) || !th.Contains(path) {
+			t.Errorf("Expected %s to be in the index", path)
+		}
+	}
+	for _, path := range []string{"a.txt", "data", "data/c.txt"} {
+		if th.Index.Contains(path) || th.Contains(path) {
+			t.Errorf("Expected %s not to be in the index", path)
+		}
+	}
+
+	th.Root = "data"
+	if !th.Contains("dir/b.txt") {
+		t.Errorf("Expected dir/b.txt to be found relative to the root")
+	}
+}
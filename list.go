@@ -0,0 +1,90 @@
+package tarix
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// ListEntry describes one file of an index
+type ListEntry struct {
+	Path  string `json:"path"` // Empty if the path is not known
+	Key   string `json:"key"`
+	Start int64  `json:"start"`
+	Size  int64  `json:"size"`
+}
+
+// ListOptions controls which files ListFilesWithOptions returns
+type ListOptions struct {
+	// TarPath, if set, is scanned for the paths of indexes without stored paths
+	TarPath string
+	// MinSize leaves out files smaller than this many bytes
+	MinSize int64
+	// MaxSize, if positive, leaves out files larger than this many bytes
+	MaxSize int64
+}
+
+// filtered reports whether the options leave out files by size
+func (o ListOptions) filtered() bool {
+	return o.MinSize > 0 || o.MaxSize > 0
+}
+
+// includes reports whether a file of the size is within the size range
+func (o ListOptions) includes(size int64) bool {
+	return size >= o.MinSize && (o.MaxSize <= 0 || size <= o.MaxSize)
+}
+
+// ListFiles returns the files of the index sorted by path, then key. Paths
+// come from the index or, if it has none stored and tarPath is given, from a
+// scan of the TAR headers.
+func ListFiles(indexPath, tarPath string) ([]ListEntry, error) {
+	return ListFilesWithOptions(indexPath, ListOptions{TarPath: tarPath})
+}
+
+// ListFilesWithOptions is like ListFiles, returning only the files within
+// the size range of opts
+func ListFilesWithOptions(indexPath string, opts ListOptions) ([]ListEntry, error) {
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	return listFiles(index, opts)
+}
+
+func listFiles(index *TarIndex, opts ListOptions) ([]ListEntry, error) {
+	var paths map[string]string
+	if opts.TarPath != "" {
+		var err error
+		paths, err = scanTarPaths(opts.TarPath, index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]ListEntry, 0, index.count())
+	index.each(func(key string, fileInfo FileIndex) {
+		if !opts.includes(fileInfo.Size) {
+			return
+		}
+		entry := ListEntry{Path: fileInfo.Path, Key: key, Start: fileInfo.Start, Size: fileInfo.Size}
+		if entry.Path == "" {
+			entry.Path = paths[key]
+		}
+		entries = append(entries, entry)
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Path != entries[j].Path {
+			return entries[i].Path < entries[j].Path
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// WriteListJSON writes entries to w as a JSON array
+func WriteListJSON(w io.Writer, entries []ListEntry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
@@ -0,0 +1,194 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListFilesJSON(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "list.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"b.txt": "bb", "a.txt": "a"})
+
+	tarIndexPath := filepath.Join(dir, "list.tar.index")
+	if err := CreateTarIndex(tarFilePath, tarIndexPath); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// Paths come from a scan of the TAR headers
+	entries, err := ListFiles(tarIndexPath, tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteListJSON(&buf, entries); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(decoded))
+	}
+	first := decoded[0]
+	if first["path"] != "a.txt" || first["key"] != hashFilePath("a.txt") {
+		t.Errorf("Unexpected first entry: %v", first)
+	}
+	if size, ok := first["size"].(float64); !ok || size != 1 {
+		t.Errorf("Expected size as JSON number 1, got %v", first["size"])
+	}
+	if _, ok := first["start"].(float64); !ok {
+		t.Errorf("Expected start as JSON number, got %v", first["start"])
+	}
+	if decoded[1]["path"] != "b.txt" {
+		t.Errorf("Expected b.txt second, got %v", decoded[1]["path"])
+	}
+}
+
+func TestListFilesWithoutStoredPaths(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"x.txt": "x"})
+
+	entries, err := ListFiles(tarIndexPath, "")
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "" || entries[0].Key != hashFilePath("x.txt") {
+		t.Errorf("Expected a single entry without path, got %+v", entries)
+	}
+
+	entries, err = ListFiles(tarIndexPath, tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "x.txt" {
+		t.Errorf("Expected path from TAR scan, got %+v", entries)
+	}
+}
+
+func TestListFilesRoot(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "list.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"root/x.txt": "x", "root/dir/y.txt": "y"})
+
+	tarIndexPath := filepath.Join(dir, "list.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Root: "root"}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// Scanned paths are relative to the root, as lookups are
+	entries, err := ListFiles(tarIndexPath, tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != "dir/y.txt" || entries[1].Path != "x.txt" {
+		t.Errorf("Expected dir/y.txt and x.txt, got %+v", entries)
+	}
+}
+
+func TestListFilesOccurrences(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "versions.tar")
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	tw := tar.NewWriter(tarFile)
+	for _, f := range []struct{ name, content string }{
+		{"a.txt", "version 0"},
+		{"b.txt", "other"},
+		{"./a.txt", "v1"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f.content))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		tw.Write([]byte(f.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+	tarFile.Close()
+
+	tarIndexPath := filepath.Join(dir, "versions.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Occurrences: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	// Each occurrence is listed under the path it was archived at
+	entries, err := ListFiles(tarIndexPath, tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	sizes := map[string]int64{}
+	for _, entry := range entries {
+		sizes[entry.Key] = entry.Size
+		if entry.Path == "" {
+			t.Errorf("Expected a path for key %s", entry.Key)
+		}
+	}
+	if len(entries) != 3 || entries[0].Path != "a.txt" || entries[1].Path != "a.txt" || entries[2].Path != "b.txt" {
+		t.Errorf("Expected two a.txt and b.txt, got %+v", entries)
+	}
+	if sizes[index.OccurrenceKey("a.txt", 0)] != 9 || sizes[index.OccurrenceKey("a.txt", 1)] != 2 {
+		t.Errorf("Expected both occurrences of a.txt keyed by occurrence, got %+v", entries)
+	}
+}
+
+func TestListFilesStoredPaths(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "list.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"dir/a.txt": "a"})
+
+	tarIndexPath := filepath.Join(dir, "list.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{StorePaths: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// Paths come from the index, so the TAR isn't needed
+	entries, err := ListFiles(tarIndexPath, "")
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "dir/a.txt" {
+		t.Errorf("Expected the stored path, got %+v", entries)
+	}
+}
+
+func TestListFilesSizeRange(t *testing.T) {
+	_, tarIndexPath := createIndexedTar(t, map[string]string{"small.txt": "s", "medium.txt": "mmmmm", "large.txt": "llllllllll"})
+
+	tests := []struct {
+		opts     ListOptions
+		expected int
+	}{
+		{ListOptions{}, 3},
+		{ListOptions{MinSize: 5}, 2},
+		{ListOptions{MaxSize: 5}, 2},
+		{ListOptions{MinSize: 2, MaxSize: 9}, 1},
+		{ListOptions{MinSize: 11}, 0},
+	}
+	for _, test := range tests {
+		entries, err := ListFilesWithOptions(tarIndexPath, test.opts)
+		if err != nil {
+			t.Fatalf("Failed to list files: %v", err)
+		}
+		if len(entries) != test.expected {
+			t.Errorf("Expected %d files with %+v, got %+v", test.expected, test.opts, entries)
+		}
+		for _, entry := range entries {
+			if !test.opts.includes(entry.Size) {
+				t.Errorf("Listed %+v outside the range of %+v", entry, test.opts)
+			}
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package tarix
+
+import "os"
+
+// lockShared does nothing on systems without flock; archives are read unlocked
+func lockShared(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tarix
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockShared takes a shared advisory lock (flock) on f, waiting while another
+// process holds an exclusive one. It is released when f is closed.
+func lockShared(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
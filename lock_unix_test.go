@@ -0,0 +1,60 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tarix
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// tryLockExclusive reports whether another open file of path can take an exclusive lock
+func tryLockExclusive(t *testing.T, path string) bool {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open TAR: %v", err)
+	}
+	defer f.Close()
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("Failed to lock TAR: %v", err)
+	}
+	return true
+}
+
+func TestHandleLock(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "a"})
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	if tryLockExclusive(t, tarFilePath) {
+		t.Errorf("Expected the handle to hold a shared lock")
+	}
+	// Other readers can still share it
+	other, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open a second handle: %v", err)
+	}
+	other.Close()
+
+	th.Close()
+	if !tryLockExclusive(t, tarFilePath) {
+		t.Errorf("Expected Close to release the lock")
+	}
+
+	th, err = NewTarixHandleWithOptions(tarFilePath, tarIndexPath, HandleOptions{NoLock: true})
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	if !tryLockExclusive(t, tarFilePath) {
+		t.Errorf("Expected no lock with NoLock")
+	}
+}
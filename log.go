@@ -0,0 +1,22 @@
+package tarix
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler is a slog.Handler dropping every record
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// slogger returns l, or a logger discarding everything when l is nil
+func slogger(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.New(discardHandler{})
+	}
+	return l
+}
@@ -0,0 +1,62 @@
+package tarix
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+)
+
+// logRecords decodes the records a slog.JSONHandler wrote to buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var record map[string]any
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("Failed to decode log record: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestIndexLogger(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "log.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "a", "b.log": "b"})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tarIndexPath := filepath.Join(dir, "log.tar.index.json")
+	opts := IndexOptions{Exclude: []string{"*.log"}, Logger: logger}
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, opts); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	messages := map[string]map[string]any{}
+	for _, record := range logRecords(t, &buf) {
+		messages[record["msg"].(string)] = record
+	}
+	if record := messages["indexed file"]; record == nil || record["path"] != "a.txt" || record["level"] != "DEBUG" {
+		t.Errorf("Expected a debug record for a.txt, got %v", record)
+	}
+	if record := messages["skipping filtered file"]; record == nil || record["path"] != "b.log" {
+		t.Errorf("Expected a record for the excluded b.log, got %v", record)
+	}
+	if record := messages["created index"]; record == nil || record["files"] != float64(1) || record["level"] != "INFO" {
+		t.Errorf("Expected an info record for the index, got %v", record)
+	}
+
+	buf.Reset()
+	outputPath := filepath.Join(dir, "a.txt")
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "a.txt", outputPath, ExtractOptions{Logger: logger}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	records := logRecords(t, &buf)
+	if len(records) != 1 || records[0]["msg"] != "extracted file" || records[0]["output"] != outputPath {
+		t.Errorf("Expected a record for the extracted file, got %v", records)
+	}
+}
@@ -3,9 +3,17 @@ package tarix
 import (
 	"archive/tar"
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -114,3 +122,1198 @@ func createTar(tarFilePath, dir string) error {
 		return nil
 	})
 }
+
+// createIndexedTar writes the given files into a TAR in a temp directory and indexes it
+func createIndexedTar(t *testing.T, fileContents map[string]string) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range fileContents {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write temp file: %v", err)
+		}
+	}
+
+	tarDir := t.TempDir()
+	tarFilePath := filepath.Join(tarDir, "testarchive.tar")
+	if err := createTar(tarFilePath, dir); err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+
+	tarIndexPath := filepath.Join(tarDir, "testarchive.tar.index.json")
+	if err := CreateTarIndex(tarFilePath, tarIndexPath); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	return tarFilePath, tarIndexPath
+}
+
+func TestExtractBytesByKey(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{
+		"file1.txt": "Hello, World!",
+		"file2.txt": "This is a test.",
+	})
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	data, err := th.ExtractBytesByKey(hashFilePath("file2.txt"))
+	if err != nil {
+		t.Fatalf("Failed to extract by key: %v", err)
+	}
+	if string(data) != "This is a test." {
+		t.Errorf("Unexpected content: %q", data)
+	}
+
+	for _, key := range []string{"abc", "0123456789abcdeg", "0123456789ABCDEF"} {
+		if _, err := th.ExtractBytesByKey(key); err == nil {
+			t.Errorf("Expected error for malformed key %q", key)
+		}
+	}
+}
+
+// writeTestTar writes files, keyed by their full in-archive path, into a TAR in name order
+func writeTestTar(t testing.TB, tarFilePath string, files map[string]string) {
+	t.Helper()
+
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	defer tarFile.Close()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tar.NewWriter(tarFile)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatalf("Failed to write data: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+}
+
+func TestCreateTarIndexIncludeExclude(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "filtered.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"config/a.yaml": "a: 1",
+		"config/b.log":  "log line",
+		"data/x.bin":    "xxxxxxxxxx",
+		"top.log":       "top",
+		"zz/config.txt": "late entry",
+	})
+
+	tarIndexPath := filepath.Join(dir, "filtered.tar.index.json")
+	opts := IndexOptions{Include: []string{"config/*", "zz/*"}, Exclude: []string{"*.log"}}
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, opts); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	if len(th.Index.Files) != 2 {
+		t.Errorf("Expected 2 indexed files, got %d", len(th.Index.Files))
+	}
+	for _, skipped := range []string{"config/b.log", "data/x.bin", "top.log"} {
+		if _, err := th.ExtractBytesOfFile(skipped); err == nil {
+			t.Errorf("Expected %s to be filtered out", skipped)
+		}
+	}
+
+	// An entry after skipped ones must still have a correct offset
+	data, err := th.ExtractBytesOfFile("zz/config.txt")
+	if err != nil {
+		t.Fatalf("Failed to extract file: %v", err)
+	}
+	if string(data) != "late entry" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Exclude: []string{"["}}); err == nil {
+		t.Errorf("Expected error for malformed pattern")
+	}
+}
+
+func TestCreateTarIndexSparse(t *testing.T) {
+	// testdata/sparse-gnu.tar was created with GNU tar --sparse --format=gnu and holds
+	// before.txt, a 1 MiB sparse.img with two small data fragments, and after.txt
+	tarFilePath := filepath.Join("testdata", "sparse-gnu.tar")
+	tarIndexPath := filepath.Join(t.TempDir(), "sparse-gnu.tar.index.json")
+	if err := CreateTarIndex(tarFilePath, tarIndexPath); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	sparseInfo, ok := th.Index.Files[hashFilePath("sparse.img")]
+	if !ok {
+		t.Fatalf("Sparse file not indexed")
+	}
+	if sparseInfo.Size != 1024*1024 {
+		t.Errorf("Expected logical size %d, got %d", 1024*1024, sparseInfo.Size)
+	}
+
+	for name, content := range map[string]string{"before.txt": "before sparse\n", "after.txt": "after sparse\n"} {
+		data, err := th.ExtractBytesOfFile(name)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+}
+
+func TestExtractAllProgress(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.txt":         "alpha",
+		"sub/b.txt":     "bravo",
+		"sub/deep/c.md": "charlie",
+	}
+	tarFilePath := filepath.Join(dir, "all.tar")
+	writeTestTar(t, tarFilePath, files)
+
+	tarIndexPath := filepath.Join(dir, "all.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	var updates []Progress
+	outputDir := filepath.Join(dir, "out")
+	opts := ExtractOptions{Progress: func(p Progress) { updates = append(updates, p) }}
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, outputDir, opts); err != nil {
+		t.Fatalf("Failed to extract all: %v", err)
+	}
+
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read extracted file: %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+
+	if len(updates) != len(files) {
+		t.Fatalf("Expected %d progress updates, got %d", len(files), len(updates))
+	}
+	last := updates[len(updates)-1]
+	if last.FilesDone != last.FilesTotal || last.BytesDone != last.BytesTotal || last.BytesTotal != 17 {
+		t.Errorf("Unexpected final progress: %+v", last)
+	}
+}
+
+func TestTarIndexDelimiter(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"comma,name.txt": "with comma",
+		"tab\tname.txt":  "with tab",
+	}
+	tarFilePath := filepath.Join(dir, "delim.tar")
+	writeTestTar(t, tarFilePath, files)
+
+	tarIndexPath := filepath.Join(dir, "delim.tar.index.tsv")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Delimiter: '\t'}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	raw, err := os.ReadFile(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if !bytes.HasPrefix(raw, []byte("key\tstart\tsize\n")) {
+		t.Errorf("Expected tab-separated header, got %q", raw)
+	}
+
+	// Both the explicit delimiter and the detected one must load the index
+	for _, opts := range []LoadOptions{{Delimiter: '\t'}, {}} {
+		index, err := ReadTarIndexWithOptions(tarIndexPath, opts)
+		if err != nil {
+			t.Fatalf("Failed to read index with %+v: %v", opts, err)
+		}
+		if len(index.Files) != len(files) {
+			t.Errorf("Expected %d entries, got %d", len(files), len(index.Files))
+		}
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	for name, content := range files {
+		data, err := th.ExtractBytesOfFile(name)
+		if err != nil {
+			t.Fatalf("Failed to extract %q: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %q: %q", name, data)
+		}
+	}
+
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Delimiter: '"'}); err == nil {
+		t.Errorf("Expected error for invalid delimiter")
+	}
+}
+
+func TestScanTarPaths(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "names.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"x/y.txt": "y", "z.txt": "z"})
+
+	paths, err := scanTarPaths(tarFilePath, &TarIndex{})
+	if err != nil {
+		t.Fatalf("Failed to scan TAR: %v", err)
+	}
+	for _, name := range []string{"x/y.txt", "z.txt"} {
+		if paths[hashFilePath(name)] != name {
+			t.Errorf("Expected hash of %s to map back to it, got %q", name, paths[hashFilePath(name)])
+		}
+	}
+}
+
+func TestPathNormalization(t *testing.T) {
+	dir := t.TempDir()
+
+	dottedTar := filepath.Join(dir, "dotted.tar")
+	writeTestTar(t, dottedTar, map[string]string{"./a/b": "dotted"})
+	plainTar := filepath.Join(dir, "plain.tar")
+	writeTestTar(t, plainTar, map[string]string{"a/b": "plain"})
+	absoluteTar := filepath.Join(dir, "absolute.tar")
+	writeTestTar(t, absoluteTar, map[string]string{"/a/b": "absolute"})
+
+	for tarFilePath, content := range map[string]string{dottedTar: "dotted", plainTar: "plain", absoluteTar: "absolute"} {
+		tarIndexPath := tarFilePath + ".index.json"
+		if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+			t.Fatalf("Failed to create TAR index: %v", err)
+		}
+		th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+		if err != nil {
+			t.Fatalf("Failed to open handle: %v", err)
+		}
+		for _, lookup := range []string{"a/b", "./a/b", "a//b", "/a/b", "//a/b"} {
+			data, err := th.ExtractBytesOfFile(lookup)
+			if err != nil {
+				t.Errorf("Failed to extract %s from %s: %v", lookup, tarFilePath, err)
+				continue
+			}
+			if string(data) != content {
+				t.Errorf("Unexpected content for %s: %q", lookup, data)
+			}
+		}
+		th.Close()
+	}
+
+	rootedTar := filepath.Join(dir, "rooted.tar")
+	writeTestTar(t, rootedTar, map[string]string{"/data/x/y.txt": "rooted", "other.txt": "outside"})
+	rootedIndex := rootedTar + ".index.json"
+	if err := CreateTarIndexWithOptions(rootedTar, rootedIndex, IndexOptions{Root: "/data/"}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// The root is stripped from lookups too, given with or without it
+	th, err := NewTarixHandle(rootedTar, rootedIndex)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	for _, lookup := range []string{"x/y.txt", "/x/y.txt", "/data/x/y.txt", "data/x/y.txt"} {
+		if data, err := th.ExtractBytesOfFile(lookup); err != nil || string(data) != "rooted" {
+			t.Errorf("Unexpected content for %s: %q, %v", lookup, data, err)
+		}
+	}
+	th.Close()
+
+	// A relative root matches absolute archive paths
+	relativeIndex := rootedTar + ".relative.index"
+	if err := CreateTarIndexWithOptions(rootedTar, relativeIndex, IndexOptions{Root: "data"}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(relativeIndex)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if !index.Contains("/x/y.txt") || !index.Contains("/data/x/y.txt") || !index.Contains("other.txt") {
+		t.Errorf("Expected rooted and outside files in index with a relative root")
+	}
+
+	outputDir := filepath.Join(dir, "out")
+	if err := ExtractAllWithOptions(rootedTar, rootedIndex, outputDir, ExtractOptions{Root: "/data"}); err != nil {
+		t.Fatalf("Failed to extract all: %v", err)
+	}
+	for name, content := range map[string]string{"x/y.txt": "rooted", "other.txt": "outside"} {
+		data, err := os.ReadFile(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read extracted file: %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+}
+
+func TestExtractCreateDirs(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "nested.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"dir/sub/x.txt": "nested"})
+	tarIndexPath := tarFilePath + ".index"
+	if err := CreateTarIndex(tarFilePath, tarIndexPath); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	outputDir := filepath.Join(dir, "out")
+	opts := ExtractOptions{CreateDirs: true}
+
+	outputPath := filepath.Join(outputDir, "dir", "sub", "x.txt")
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "dir/sub/x.txt", outputPath, opts); err != nil {
+		t.Fatalf("Failed to extract file: %v", err)
+	}
+	if data, err := os.ReadFile(outputPath); err != nil || string(data) != "nested" {
+		t.Errorf("Unexpected extracted content: %q, %v", data, err)
+	}
+
+	// A missing file is looked up before any directory is created
+	missingPath := filepath.Join(outputDir, "other", "missing.txt")
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "other/missing.txt", missingPath, opts); err == nil {
+		t.Fatal("Expected an error extracting a missing file")
+	}
+	if _, err := os.Stat(filepath.Dir(missingPath)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no directory for a missing file, got %v", err)
+	}
+}
+
+func TestWriteFileTo(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{
+		"file1.txt": "Hello, World!",
+		"file2.txt": "This is a test.",
+	})
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	var buf bytes.Buffer
+	n, err := th.WriteFileTo("file2.txt", &buf)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if n != int64(buf.Len()) || buf.String() != "This is a test." {
+		t.Errorf("Unexpected result: %d bytes, %q", n, buf.String())
+	}
+
+	if _, err := th.WriteFileTo("missing.txt", &buf); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
+
+func TestCreateTarIndexDedup(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "dups.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"a.txt": "same content",
+		"b.txt": "other content",
+		"c.txt": "same content",
+	})
+
+	tarIndexPath := filepath.Join(dir, "dups.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Dedup: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	a := th.Index.Files[hashFilePath("a.txt")]
+	b := th.Index.Files[hashFilePath("b.txt")]
+	c := th.Index.Files[hashFilePath("c.txt")]
+	if a.ContentHash == "" || a.ContentHash != c.ContentHash || a.ContentHash == b.ContentHash {
+		t.Errorf("Unexpected content hashes: %q %q %q", a.ContentHash, b.ContentHash, c.ContentHash)
+	}
+	if a.Start != 0 || c.Start != a.Start || b.Start == a.Start {
+		t.Errorf("Expected identical files to share the first offset: %d %d %d", a.Start, b.Start, c.Start)
+	}
+
+	for name, content := range map[string]string{"a.txt": "same content", "b.txt": "other content", "c.txt": "same content"} {
+		data, err := th.ExtractBytesOfFile(name)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+}
+
+func TestReadTarIndexChecksum(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "index.json")
+	index := &TarIndex{Files: map[string]FileIndex{
+		hashFilePath("a.txt"): {Start: 0, Size: 123},
+		hashFilePath("b.txt"): {Start: 1024, Size: 7},
+	}}
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if _, err := ReadTarIndex(indexPath); err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	raw, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index file: %v", err)
+	}
+	tampered := bytes.Replace(raw, []byte(",123\n"), []byte(",124\n"), 1)
+	if err := os.WriteFile(indexPath, tampered, 0644); err != nil {
+		t.Fatalf("Failed to write index file: %v", err)
+	}
+	if _, err := ReadTarIndex(indexPath); !errors.Is(err, ErrIndexCorrupt) {
+		t.Errorf("Expected ErrIndexCorrupt, got %v", err)
+	}
+	if _, err := ReadTarIndexWithOptions(indexPath, LoadOptions{SkipChecksum: true}); err != nil {
+		t.Errorf("Expected tampered index to load without checksum verification: %v", err)
+	}
+
+	// The version row requires the trailer, so removing it doesn't help
+	if !bytes.HasPrefix(raw, []byte("key,start,size\n#version,1\n")) {
+		t.Errorf("Expected the version after the header, got %q", raw)
+	}
+	trailer := bytes.LastIndex(tampered, []byte("#sha256,"))
+	if err := os.WriteFile(indexPath, tampered[:trailer], 0644); err != nil {
+		t.Fatalf("Failed to write index file: %v", err)
+	}
+	if _, err := ReadTarIndex(indexPath); !errors.Is(err, ErrIndexCorrupt) {
+		t.Errorf("Expected ErrIndexCorrupt without the trailer, got %v", err)
+	}
+	if _, err := ReadTarIndexWithOptions(indexPath, LoadOptions{SkipChecksum: true}); err != nil {
+		t.Errorf("Expected index without trailer to load without checksum verification: %v", err)
+	}
+
+	// Indexes written before checksums were added have no trailer
+	legacy := "key,start,size\n" + hashFilePath("a.txt") + ",0,123\n"
+	if err := os.WriteFile(indexPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write index file: %v", err)
+	}
+	if _, err := ReadTarIndex(indexPath); err != nil {
+		t.Errorf("Failed to read legacy index: %v", err)
+	}
+}
+
+func TestReadTarIndexBOMAndCRLF(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "crlf.tar")
+	files := map[string]string{"a.txt": "first", "dir/b.txt": "second"}
+	writeTestTar(t, tarFilePath, files)
+
+	for _, delimiter := range []rune{',', '\t'} {
+		indexPath := filepath.Join(dir, "crlf.tar.index")
+		opts := IndexOptions{Delimiter: delimiter, StorePaths: true, ContentHash: true}
+		if err := CreateTarIndexWithOptions(tarFilePath, indexPath, opts); err != nil {
+			t.Fatalf("Failed to create TAR index: %v", err)
+		}
+		expected, err := ReadTarIndex(indexPath)
+		if err != nil {
+			t.Fatalf("Failed to read index: %v", err)
+		}
+
+		raw, err := os.ReadFile(indexPath)
+		if err != nil {
+			t.Fatalf("Failed to read index file: %v", err)
+		}
+		edited := append([]byte("\ufeff"), bytes.ReplaceAll(raw, []byte("\n"), []byte("\r\n"))...)
+		if err := os.WriteFile(indexPath, edited, 0644); err != nil {
+			t.Fatalf("Failed to write index file: %v", err)
+		}
+
+		// The checksum must still verify, so the rows are read exactly as written
+		index, err := ReadTarIndex(indexPath)
+		if err != nil {
+			t.Fatalf("Failed to read BOM-prefixed CRLF index delimited by %q: %v", delimiter, err)
+		}
+		if !reflect.DeepEqual(index.Files, expected.Files) {
+			t.Errorf("Expected %v, got %v", expected.Files, index.Files)
+		}
+	}
+
+	// Genuinely malformed rows still fail, pointing at the line
+	indexPath := filepath.Join(dir, "malformed.index")
+	malformed := "\ufeffkey,start,size\r\n" + hashFilePath("a.txt") + ",0,5\r\n" + hashFilePath("b.txt") + ",512\r\n"
+	if err := os.WriteFile(indexPath, []byte(malformed), 0644); err != nil {
+		t.Fatalf("Failed to write index file: %v", err)
+	}
+	if _, err := ReadTarIndex(indexPath); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected error on line 3, got %v", err)
+	}
+}
+
+func TestIndexArchiveSize(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "size.tar")
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	tw := tar.NewWriter(tarFile)
+	headers := []*tar.Header{
+		{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "dir/a.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+		{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "a.txt"},
+		{Name: "b.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+	}
+	for _, header := range headers {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		tw.Write(make([]byte, header.Size))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+	tarFile.Close()
+	info, err := os.Stat(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to stat TAR: %v", err)
+	}
+
+	tarIndexPath := filepath.Join(dir, "size.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if index.ArchiveSize != info.Size() {
+		t.Errorf("Expected archive size %d, got %d", info.Size(), index.ArchiveSize)
+	}
+	// Every entry counts, not only the indexed files
+	if index.EntryCount != int64(len(headers)) || len(index.Files) != 2 {
+		t.Errorf("Expected %d entries and 2 files, got %d and %d", len(headers), index.EntryCount, len(index.Files))
+	}
+
+	// Indexes without the metadata rows load with zero values
+	legacy := "key,start,size\n" + hashFilePath("b.txt") + ",0,1\n"
+	if err := os.WriteFile(tarIndexPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write index file: %v", err)
+	}
+	index, err = ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read legacy index: %v", err)
+	}
+	if index.ArchiveSize != 0 || index.EntryCount != 0 {
+		t.Errorf("Expected no size or count, got %d and %d", index.ArchiveSize, index.EntryCount)
+	}
+}
+
+func TestIndexMaxEntries(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "many.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+
+	tarIndexPath := filepath.Join(dir, "many.tar.index.json")
+	err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{MaxEntries: 2})
+	if !errors.Is(err, ErrTooManyEntries) {
+		t.Fatalf("Expected ErrTooManyEntries, got %v", err)
+	}
+	if _, err := os.Stat(tarIndexPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no index after aborting, got %v", err)
+	}
+
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{MaxEntries: 3}); err != nil {
+		t.Fatalf("Expected an archive at the limit to be indexed, got %v", err)
+	}
+}
+
+func TestIndexByOffset(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "order.tar")
+	files := map[string]string{}
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("file%02d.txt", i)] = "x"
+	}
+	writeTestTar(t, tarFilePath, files)
+
+	tarIndexPath := filepath.Join(dir, "order.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{ByOffset: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	data, err := os.ReadFile(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index file: %v", err)
+	}
+	var starts []int64
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n")[1:] {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		start, err := strconv.ParseInt(strings.Split(line, ",")[1], 10, 64)
+		if err != nil {
+			t.Fatalf("Failed to parse row %q: %v", line, err)
+		}
+		starts = append(starts, start)
+	}
+	if len(starts) != len(files) || !slices.IsSorted(starts) {
+		t.Errorf("Expected %d rows sorted by start, got %v", len(files), starts)
+	}
+
+	// The order doesn't matter to readers
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if len(index.Files) != len(files) {
+		t.Errorf("Expected %d files, got %d", len(files), len(index.Files))
+	}
+}
+
+func TestWriteTarIndexAtomic(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "atomic.index")
+	index := &TarIndex{Files: map[string]FileIndex{hashFilePath("a.txt"): {Start: 0, Size: 1}}}
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	written, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index file: %v", err)
+	}
+	info, err := os.Stat(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to stat index file: %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected mode 0644, got %v", info.Mode().Perm())
+	}
+
+	// A failed write leaves the existing index as it was
+	index.Files[hashFilePath("b.txt")] = FileIndex{Start: 512, Size: 2}
+	if err := writeTarIndex(index, indexPath, indexEncoding{delimiter: '"'}); err == nil {
+		t.Fatal("Expected error writing with an invalid delimiter")
+	}
+	if raw, _ := os.ReadFile(indexPath); !bytes.Equal(raw, written) {
+		t.Errorf("Expected index to be unchanged, got %q", raw)
+	}
+
+	// A failed rename too, e.g. onto a directory
+	dirPath := filepath.Join(dir, "taken")
+	if err := os.MkdirAll(filepath.Join(dirPath, "x"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := WriteTarIndex(index, dirPath); err == nil {
+		t.Fatal("Expected error writing over a directory")
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the index and the directory, got %v", entries)
+	}
+}
+
+func TestReadTarIndexRejectsBadOffsets(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "bad.index")
+	key := hashFilePath("a.txt")
+	for _, index := range []string{
+		"key,start,size\n" + key + ",-512,5\n",
+		"key,start,size\n" + key + ",0,-1\n",
+		"key,start,size\n#size,2048\n" + key + ",2048,5\n",
+	} {
+		if err := os.WriteFile(indexPath, []byte(index), 0644); err != nil {
+			t.Fatalf("Failed to write index file: %v", err)
+		}
+		_, err := ReadTarIndex(indexPath)
+		if !errors.Is(err, ErrIndexCorrupt) || !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected ErrIndexCorrupt naming %s for %q, got %v", key, index, err)
+		}
+	}
+
+	// A size larger than the archive fails before allocating for it
+	tarFilePath := filepath.Join(dir, "small.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "small"})
+	info, err := os.Stat(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to stat TAR: %v", err)
+	}
+	index := fmt.Sprintf("key,start,size\n#size,%d\n%s,0,%d\n", info.Size(), key, int64(1)<<60)
+	if err := os.WriteFile(indexPath, []byte(index), 0644); err != nil {
+		t.Fatalf("Failed to write index file: %v", err)
+	}
+	th, err := NewTarixHandle(tarFilePath, indexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	if _, err := th.ExtractBytesOfFile("a.txt"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+// maxWriteRecorder records the largest single write to it. Unlike
+// bytes.Buffer it has no ReadFrom, so copies go through their buffer.
+type maxWriteRecorder struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (w *maxWriteRecorder) Write(p []byte) (int, error) {
+	w.max = max(w.max, len(p))
+	return w.buf.Write(p)
+}
+
+func TestCopyBufferSize(t *testing.T) {
+	content := strings.Repeat("0123456789", 10000)
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"big.txt": content})
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	for bufferSize, expectedMax := range map[int]int{100: 100, 0: defaultBufferSize, -1: defaultBufferSize, 64 * 1024: 64 * 1024} {
+		th.BufferSize = bufferSize
+		var w maxWriteRecorder
+		if _, err := th.WriteFileTo("big.txt", &w); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if w.buf.String() != content {
+			t.Errorf("Unexpected content with buffer size %d", bufferSize)
+		}
+		if w.max != expectedMax {
+			t.Errorf("Expected writes of up to %d bytes with buffer size %d, got %d", expectedMax, bufferSize, w.max)
+		}
+	}
+
+	fsys := NewMemFS()
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, "out", ExtractOptions{FS: fsys, BufferSize: 10}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if string(fsys.Files[filepath.Join("out", "big.txt")].Data) != content {
+		t.Error("Unexpected content extracted with a small buffer")
+	}
+}
+
+func TestZeroByteFile(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "empty.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"a.txt":     "first",
+		"b-empty":   "",
+		"c.txt":     "last",
+		"d-empty/x": "",
+	})
+
+	tarIndexPath := filepath.Join(dir, "empty.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	empty, ok := th.Index.Files[hashFilePath("b-empty")]
+	if !ok || empty.Size != 0 {
+		t.Fatalf("Expected empty file to be indexed with size 0, got %+v", empty)
+	}
+	// A zero-byte file has no data blocks, so the next entry follows its header directly
+	if next := th.Index.Files[hashFilePath("c.txt")]; next.Start != empty.Start+headerSize {
+		t.Errorf("Expected entry after empty file at %d, got %d", empty.Start+headerSize, next.Start)
+	}
+
+	for name, content := range map[string]string{"a.txt": "first", "b-empty": "", "c.txt": "last", "d-empty/x": ""} {
+		data, err := th.ExtractBytesOfFile(name)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+
+	extractedFilePath := filepath.Join(dir, "extracted-empty")
+	if err := ExtractFileFromTar(tarFilePath, tarIndexPath, "b-empty", extractedFilePath); err != nil {
+		t.Fatalf("Failed to extract file: %v", err)
+	}
+	info, err := os.Stat(extractedFilePath)
+	if err != nil {
+		t.Fatalf("Expected extracted empty file to exist: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected extracted file to be empty, got %d bytes", info.Size())
+	}
+}
+
+func TestCreateTarIndexSkipBadHeaders(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "damaged.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"a.txt": "first",
+		"b.txt": string(bytes.Repeat([]byte("b"), 2000)),
+		"c.txt": "third",
+	})
+
+	// Corrupt the name of b.txt so its header checksum no longer matches
+	raw, err := os.ReadFile(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+	badPos := int64(2 * 512)
+	raw[badPos] = 'X'
+	if err := os.WriteFile(tarFilePath, raw, 0644); err != nil {
+		t.Fatalf("Failed to write TAR: %v", err)
+	}
+
+	tarIndexPath := filepath.Join(dir, "damaged.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err == nil {
+		t.Fatalf("Expected strict indexing to fail on a bad header")
+	}
+
+	var skipped []int64
+	opts := IndexOptions{
+		SkipBadHeaders: true,
+		OnSkip:         func(offset int64, _ error) { skipped = append(skipped, offset) },
+	}
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, opts); err != nil {
+		t.Fatalf("Failed to create partial TAR index: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0] != badPos {
+		t.Errorf("Expected one skipped region at %d, got %v", badPos, skipped)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	if len(th.Index.Files) != 2 {
+		t.Errorf("Expected 2 indexed files, got %d", len(th.Index.Files))
+	}
+	data, err := th.ExtractBytesOfFile("c.txt")
+	if err != nil || string(data) != "third" {
+		t.Errorf("Unexpected content after skipped entry: %q, %v", data, err)
+	}
+}
+
+func TestCreateTarIndexSkipBadHeadersZeroBlocks(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "damaged.tar")
+	// The data of b.txt starts with three zero blocks
+	writeTestTar(t, tarFilePath, map[string]string{
+		"a.txt": "first",
+		"b.txt": string(make([]byte, 3*512)) + "tail",
+		"c.txt": "third",
+	})
+
+	raw, err := os.ReadFile(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+	badPos := int64(2 * 512)
+	raw[badPos] = 'X'
+	if err := os.WriteFile(tarFilePath, raw, 0644); err != nil {
+		t.Fatalf("Failed to write TAR: %v", err)
+	}
+
+	var skipped []int64
+	tarIndexPath := filepath.Join(dir, "damaged.tar.index")
+	opts := IndexOptions{
+		SkipBadHeaders: true,
+		OnSkip:         func(offset int64, _ error) { skipped = append(skipped, offset) },
+	}
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, opts); err != nil {
+		t.Fatalf("Failed to create partial TAR index: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0] != badPos {
+		t.Errorf("Expected one skipped region at %d, got %v", badPos, skipped)
+	}
+
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	// The zero blocks don't end indexing before c.txt
+	if !index.Contains("a.txt") || index.Contains("b.txt") || !index.Contains("c.txt") {
+		t.Errorf("Expected a.txt and c.txt indexed, got %v", index.Files)
+	}
+	if !index.Trailer {
+		t.Error("Expected the real trailer to be found")
+	}
+}
+
+func TestIndexLogOutput(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "log.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "a"})
+
+	var log bytes.Buffer
+	tarIndexPath := filepath.Join(dir, "log.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Log: &log}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	if !bytes.Contains(log.Bytes(), []byte("Created index with 1 files")) {
+		t.Errorf("Expected summary in log, got %q", log.String())
+	}
+
+	log.Reset()
+	opts := ExtractOptions{Log: &log}
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "a.txt", filepath.Join(dir, "a.txt"), opts); err != nil {
+		t.Fatalf("Failed to extract file: %v", err)
+	}
+	if !bytes.HasPrefix(log.Bytes(), []byte("Extracted a.txt")) {
+		t.Errorf("Expected extraction message in log, got %q", log.String())
+	}
+}
+
+func TestIndexVerbose(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "verbose.tar")
+	longName := strings.Repeat("d/", 60) + "long.txt"
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "a", longName: "long"})
+
+	var log bytes.Buffer
+	tarIndexPath := filepath.Join(dir, "verbose.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Log: &log}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	if bytes.Contains(log.Bytes(), []byte("indexed ")) {
+		t.Errorf("Expected no listing without Verbose, got %q", log.String())
+	}
+
+	log.Reset()
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Log: &log, Verbose: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR index: %v", err)
+	}
+	for _, name := range []string{"a.txt", longName} {
+		fi := index.Files[index.Key(name)]
+		line := fmt.Sprintf("indexed %s @%d (%d bytes)\n", name, fi.Start, fi.Size)
+		if !strings.Contains(log.String(), line) {
+			t.Errorf("Expected %q in log, got %q", line, log.String())
+		}
+	}
+}
+
+func TestVerifyStaleIndex(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "stale.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "aaa", "b.txt": "bbb"})
+
+	tarIndexPath := filepath.Join(dir, "stale.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	th.Verify = true
+	data, err := th.ExtractBytesOfFile("b.txt")
+	if err != nil || string(data) != "bbb" {
+		t.Errorf("Expected bbb from a matching index, got %q, %v", data, err)
+	}
+	th.Close()
+
+	// Rewrite the TAR so the indexed offsets hold other files of the same size
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "aaa", "c.txt": "ccc"})
+
+	th, err = NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	if data, err := th.ExtractBytesOfFile("b.txt"); err != nil || string(data) != "ccc" {
+		t.Fatalf("Expected the unverified read to return the wrong file, got %q, %v", data, err)
+	}
+
+	th.Verify = true
+	if _, err := th.ExtractBytesOfFile("b.txt"); !errors.Is(err, ErrIndexStale) {
+		t.Errorf("Expected ErrIndexStale, got %v", err)
+	}
+	if _, err := th.SectionReaderOf("b.txt"); !errors.Is(err, ErrIndexStale) {
+		t.Errorf("Expected ErrIndexStale from SectionReaderOf, got %v", err)
+	}
+	if data, err := th.ExtractBytesOfFile("a.txt"); err != nil || string(data) != "aaa" {
+		t.Errorf("Expected aaa, got %q, %v", data, err)
+	}
+}
+
+func TestVerifyDedupedFile(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "dedup.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "same", "b.txt": "same"})
+
+	tarIndexPath := filepath.Join(dir, "dedup.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Dedup: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	// b.txt points at a.txt's copy, which Verify must accept
+	th.Verify = true
+	if data, err := th.ExtractBytesOfFile("b.txt"); err != nil || string(data) != "same" {
+		t.Errorf("Expected same, got %q, %v", data, err)
+	}
+}
+
+func TestVerifyLongName(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "long.tar")
+	longName := strings.Repeat("x", 150) + ".txt"
+	writeTestTar(t, tarFilePath, map[string]string{longName: "long", "a.txt": "short"})
+
+	tarIndexPath := filepath.Join(dir, "long.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR index: %v", err)
+	}
+	if fileInfo, _ := index.lookup(index.Key(longName)); fileInfo.HeaderBlocks == 0 {
+		t.Errorf("Expected header blocks for a long name, got %+v", fileInfo)
+	}
+	if fileInfo, _ := index.lookup(index.Key("a.txt")); fileInfo.HeaderBlocks != 0 {
+		t.Errorf("Expected no header blocks for a short name, got %+v", fileInfo)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	// The name only fits in the PAX header before the entry's own header
+	th.Verify = true
+	if data, err := th.ExtractBytesOfFile(longName); err != nil || string(data) != "long" {
+		t.Errorf("Expected long, got %q, %v", data, err)
+	}
+}
+
+func TestHandleRoot(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "root.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"data/foo.txt": "foo", "data/sub/bar.txt": "bar"})
+
+	plainIndex := filepath.Join(dir, "plain.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, plainIndex, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	rootedIndex := filepath.Join(dir, "rooted.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, rootedIndex, IndexOptions{Root: "data"}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// The handle's Root is prepended unless the index already strips it
+	for _, tarIndexPath := range []string{plainIndex, rootedIndex} {
+		th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+		if err != nil {
+			t.Fatalf("Failed to open handle: %v", err)
+		}
+		th.Root = "data"
+		th.Verify = true
+		for name, content := range map[string]string{"foo.txt": "foo", "sub/bar.txt": "bar"} {
+			data, err := th.ExtractBytesOfFile(name)
+			if err != nil {
+				t.Errorf("Failed to extract %s with %s: %v", name, tarIndexPath, err)
+				continue
+			}
+			if string(data) != content {
+				t.Errorf("Unexpected content of %s: %q", name, data)
+			}
+		}
+		th.Close()
+	}
+
+	// ExtractAll writes files relative to the root, also for an index built without it
+	outputDir := filepath.Join(dir, "out")
+	if err := ExtractAllWithOptions(tarFilePath, plainIndex, outputDir, ExtractOptions{Root: "data"}); err != nil {
+		t.Fatalf("Failed to extract all: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(outputDir, "sub", "bar.txt")); err != nil || string(data) != "bar" {
+		t.Errorf("Expected bar relative to the root, got %q, %v", data, err)
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "alpha"})
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	info, err := os.Stat(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to stat TAR: %v", err)
+	}
+	if index.ArchiveName != "testarchive.tar" || index.ArchiveSize != info.Size() {
+		t.Errorf("Index records archive %q of %d bytes, want testarchive.tar of %d", index.ArchiveName, index.ArchiveSize, info.Size())
+	}
+
+	openWithLog := func(tarPath string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		th, err := NewTarixHandleWithOptions(tarPath, tarIndexPath, HandleOptions{Logger: slog.New(slog.NewTextHandler(&buf, nil))})
+		if err != nil {
+			t.Fatalf("Failed to open handle: %v", err)
+		}
+		th.Close()
+		return buf.String()
+	}
+	if logged := openWithLog(tarFilePath); logged != "" {
+		t.Errorf("Expected no warnings for the indexed TAR, got %s", logged)
+	}
+
+	// Another archive with the index warns, but still opens
+	other := filepath.Join(t.TempDir(), "other.tar")
+	if err := os.WriteFile(other, make([]byte, info.Size()+512), 0644); err != nil {
+		t.Fatalf("Failed to write TAR: %v", err)
+	}
+	logged := openWithLog(other)
+	if !strings.Contains(logged, "name doesn't match") || !strings.Contains(logged, "indexed=testarchive.tar") || !strings.Contains(logged, "size doesn't match") {
+		t.Errorf("Expected name and size warnings, got %s", logged)
+	}
+}
@@ -0,0 +1,60 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tarix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTarIndexKeepsModeAndLinks(t *testing.T) {
+	dir := t.TempDir()
+	index := &TarIndex{Files: map[string]FileIndex{hashFilePath("a.txt"): {Start: 0, Size: 1}}}
+
+	// A new index gets the default mode
+	indexPath := filepath.Join(dir, "new.index")
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if info, err := os.Stat(indexPath); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("Expected mode 0644 for a new index, got %v, %v", info.Mode(), err)
+	}
+
+	// An existing index keeps its mode
+	if err := os.Chmod(indexPath, 0600); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if info, err := os.Stat(indexPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 kept, got %v, %v", info.Mode(), err)
+	}
+
+	// Writing through a link replaces its target, keeping the link
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data/target.index"), nil, 0640); err != nil {
+		t.Fatalf("Failed to create link target: %v", err)
+	}
+	for name, target := range map[string]string{"linked.index": "data/target.index", "dangling.index": "data/missing.index"} {
+		linkPath := filepath.Join(dir, name)
+		if err := os.Symlink(target, linkPath); err != nil {
+			t.Fatalf("Failed to create link: %v", err)
+		}
+		if err := WriteTarIndex(index, linkPath); err != nil {
+			t.Fatalf("Failed to write index through %s: %v", name, err)
+		}
+		if info, err := os.Lstat(linkPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("Expected %s to stay a link, got %v, %v", name, info.Mode(), err)
+		}
+		if _, err := ReadTarIndex(filepath.Join(dir, target)); err != nil {
+			t.Errorf("Expected the index written to %s: %v", target, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "data/target.index")); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Expected the mode of the link target kept, got %v, %v", info.Mode(), err)
+	}
+}
@@ -0,0 +1,157 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// ExtractMany extracts the files at filePaths into outputDir, recreating
+// their paths under it. Paths are relative to opts.Root like those given to
+// ExtractFileFromTarWithOptions. The files are read through the index, so
+// unlike ExtractAll the rest of the TAR is not scanned. Up to
+// opts.Concurrency files are extracted at once.
+func ExtractMany(tarPath, indexPath string, filePaths []string, outputDir string, opts ExtractOptions) error {
+	return extractMany(opts.fs(), tarPath, indexPath, filePaths, outputDir, opts)
+}
+
+func extractMany(fsys FS, tarPath, indexPath string, filePaths []string, outputDir string, opts ExtractOptions) error {
+	th, err := openManyHandle(tarPath, indexPath, opts)
+	if err != nil {
+		return err
+	}
+	defer th.Close()
+
+	entries := make([]ListEntry, len(filePaths))
+	for i, filePath := range filePaths {
+		entries[i] = ListEntry{Path: filePath, Key: th.key(filePath)}
+	}
+	return extractEntries(fsys, th, entries, outputDir, opts)
+}
+
+// ExtractEntries extracts the files of entries, as returned by ListFiles and
+// LargestFiles, into outputDir under their paths. Files are read by key, so
+// this works for indexes keyed by occurrence too, where the paths are not
+// keys. It fails before writing anything if an entry has no path, as for
+// indexes without stored paths listed without their TAR.
+func ExtractEntries(tarPath, indexPath string, entries []ListEntry, outputDir string, opts ExtractOptions) error {
+	for _, entry := range entries {
+		if entry.Path == "" {
+			return fmt.Errorf("no path known for key %s, rebuild the index with StorePaths or list it with its TAR", entry.Key)
+		}
+	}
+	th, err := openManyHandle(tarPath, indexPath, opts)
+	if err != nil {
+		return err
+	}
+	defer th.Close()
+	// The paths of entries are relative to the root of the index already
+	th.Root = ""
+	return extractEntries(opts.fs(), th, entries, outputDir, opts)
+}
+
+// openManyHandle opens a handle on the TAR configured by opts
+func openManyHandle(tarPath, indexPath string, opts ExtractOptions) (*TarixHandle, error) {
+	th, err := NewTarixHandleWithOptions(tarPath, indexPath, HandleOptions{NoLock: opts.NoLock, Logger: opts.Logger, ReadPattern: opts.ReadPattern})
+	if err != nil {
+		return nil, err
+	}
+	th.Verify = opts.Verify
+	th.Root = opts.Root
+	th.Transform = opts.Transform
+	th.BufferSize = opts.BufferSize
+	th.RestoreSparse = opts.RestoreSparse
+	return th, nil
+}
+
+// extractEntries extracts the files of entries by key to their paths under
+// outputDir
+func extractEntries(fsys FS, th *TarixHandle, entries []ListEntry, outputDir string, opts ExtractOptions) error {
+	// Fail before writing anything if a file is missing or unsafe
+	progress := Progress{FilesTotal: int64(len(entries))}
+	for _, entry := range entries {
+		fileInfo, ok := th.Index.lookup(entry.Key)
+		if !ok {
+			return fmt.Errorf("file %s not found in index", entry.Key)
+		}
+		if !filepath.IsLocal(normalizePath(entry.Path, "")) {
+			return fmt.Errorf("refusing to extract %s outside of output directory", entry.Path)
+		}
+		progress.BytesTotal += fileInfo.Size
+	}
+
+	log := slogger(opts.Logger)
+	// mu guards progress and opts.Log, used by the extracting goroutines
+	var mu sync.Mutex
+	done := func(n int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.FilesDone++
+		progress.BytesDone += n
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	pool := newExtractPool(opts.concurrency())
+	// A path given twice is written once, not by two goroutines at once
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		filePath := entry.Path
+		outputPath := filepath.Join(outputDir, normalizePath(filePath, ""))
+		if seen[outputPath] {
+			done(0)
+			continue
+		}
+		seen[outputPath] = true
+
+		skip, err := opts.Overwrite.checkOutput(fsys, outputPath)
+		if err != nil {
+			return errors.Join(err, pool.wait())
+		}
+		if skip {
+			mu.Lock()
+			fmt.Fprintf(logWriter(opts.Log), "Skipped %s: %s already exists\n", filePath, outputPath)
+			mu.Unlock()
+			log.Info("skipped existing file", "path", filePath, "output", outputPath)
+			done(0)
+			continue
+		}
+
+		pool.run(func() error {
+			n, err := extractManyFile(fsys, th, entry, outputPath, opts)
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", filePath, err)
+			}
+			mu.Lock()
+			fmt.Fprintf(logWriter(opts.Log), "Extracted %s to %s (size: %d bytes)\n", filePath, outputPath, n)
+			mu.Unlock()
+			log.Debug("extracted file", "path", filePath, "output", outputPath, "size", n)
+			done(n)
+			return nil
+		})
+	}
+	return pool.wait()
+}
+
+// extractManyFile writes the file of entry to outputPath in fsys
+func extractManyFile(fsys FS, th *TarixHandle, entry ListEntry, outputPath string, opts ExtractOptions) (int64, error) {
+	if err := fsys.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	outFile, _, err := opts.createOutput(fsys, outputPath)
+	if err != nil {
+		return 0, err
+	}
+	defer outFile.Close()
+
+	n, err := th.writeKeyTo(entry.Key, entry.Path, outFile)
+	if err != nil {
+		return n, err
+	}
+	if err := outFile.Close(); err != nil {
+		return n, fmt.Errorf("failed to close output file: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,106 @@
+package tarix
+
+import (
+	"archive/tar"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractMany(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "aaa", "b.txt": "bb", "c.txt": "c"})
+
+	fsys := NewMemFS()
+	var last Progress
+	opts := ExtractOptions{FS: fsys, Progress: func(p Progress) { last = p }}
+	if err := ExtractMany(tarFilePath, tarIndexPath, []string{"c.txt", "a.txt"}, "out", opts); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	for name, want := range map[string]string{"a.txt": "aaa", "c.txt": "c"} {
+		if f := fsys.Files[filepath.Join("out", name)]; f == nil || string(f.Data) != want {
+			t.Errorf("Expected %s to hold %q, got %+v", name, want, f)
+		}
+	}
+	if f := fsys.Files[filepath.Join("out", "b.txt")]; f != nil {
+		t.Errorf("Expected b.txt not to be extracted")
+	}
+	if want := (Progress{FilesDone: 2, FilesTotal: 2, BytesDone: 4, BytesTotal: 4}); last != want {
+		t.Errorf("Expected progress %+v, got %+v", want, last)
+	}
+
+	// A missing file fails before anything is written
+	fsys = NewMemFS()
+	if err := ExtractMany(tarFilePath, tarIndexPath, []string{"a.txt", "missing.txt"}, "out", ExtractOptions{FS: fsys}); err == nil {
+		t.Error("Expected an error for a file not in the index")
+	}
+	if len(fsys.Files) != 0 {
+		t.Errorf("Expected no files to be written, got %d", len(fsys.Files))
+	}
+
+	fsys = NewMemFS()
+	fsys.Dirs["out"] = 0755
+	fsys.Files[filepath.Join("out", "a.txt")] = &MemFile{Data: []byte("old"), Mode: 0644}
+	err := ExtractMany(tarFilePath, tarIndexPath, []string{"a.txt"}, "out", ExtractOptions{FS: fsys, Overwrite: OverwriteError})
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected fs.ErrExist, got %v", err)
+	}
+}
+
+func TestExtractEntries(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "versions.tar")
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	tw := tar.NewWriter(tarFile)
+	for _, f := range []struct{ name, content string }{
+		{"a.txt", "version 0"},
+		{"b.txt", "other"},
+		{"a.txt", "v1"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f.content))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		tw.Write([]byte(f.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+	tarFile.Close()
+	tarIndexPath := filepath.Join(dir, "versions.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Occurrences: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// Paths are not keys of an occurrence index, so files are read by key
+	entries, err := LargestFiles(tarIndexPath, 2, ListOptions{TarPath: tarFilePath})
+	if err != nil {
+		t.Fatalf("Failed to find the largest files: %v", err)
+	}
+	fsys := NewMemFS()
+	if err := ExtractEntries(tarFilePath, tarIndexPath, entries, "out", ExtractOptions{FS: fsys}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	for name, want := range map[string]string{"a.txt": "version 0", "b.txt": "other"} {
+		if f := fsys.Files[filepath.Join("out", name)]; f == nil || string(f.Data) != want {
+			t.Errorf("Expected %s to hold %q, got %+v", name, want, f)
+		}
+	}
+
+	// An entry without a path fails before anything is written
+	entries, err = LargestFiles(tarIndexPath, 2, ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to find the largest files: %v", err)
+	}
+	fsys = NewMemFS()
+	if err := ExtractEntries(tarFilePath, tarIndexPath, entries, "out", ExtractOptions{FS: fsys}); err == nil || !strings.Contains(err.Error(), "no path known") {
+		t.Errorf("Expected an error for entries without paths, got %v", err)
+	}
+	if len(fsys.Files) != 0 {
+		t.Errorf("Expected no files to be written, got %d", len(fsys.Files))
+	}
+}
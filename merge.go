@@ -0,0 +1,58 @@
+package tarix
+
+import (
+	"archive/tar"
+	"fmt"
+)
+
+// MergeIndexes combines indexes into a new index with every Start shifted by
+// baseOffset. For concatenated archives (cat a.tar b.tar > c.tar) the index of
+// b.tar is shifted by the size of a.tar. As in CreateTarIndex, a key present in
+// more than one index is an error.
+func MergeIndexes(baseOffset int64, indexes ...*TarIndex) (*TarIndex, error) {
+	merged := &TarIndex{
+		Files: map[string]FileIndex{},
+	}
+
+	var formats tar.Format
+	for i, index := range indexes {
+		if index.KeyScheme != indexes[0].KeyScheme {
+			return nil, fmt.Errorf("cannot merge indexes with different key schemes %q and %q", indexes[0].KeyScheme, index.KeyScheme)
+		}
+		if index.Root != indexes[0].Root {
+			return nil, fmt.Errorf("cannot merge indexes with different roots %q and %q", indexes[0].Root, index.Root)
+		}
+		if index.Occurrences != indexes[0].Occurrences {
+			return nil, fmt.Errorf("cannot merge indexes keyed by occurrence with ones that are not")
+		}
+		if index.Normalize != indexes[0].Normalize {
+			return nil, fmt.Errorf("cannot merge indexes with different normalization forms %q and %q", indexes[0].Normalize, index.Normalize)
+		}
+		if i == 0 {
+			merged.KeyScheme = index.KeyScheme
+			merged.Root = index.Root
+			merged.Occurrences = index.Occurrences
+			merged.Normalize = index.Normalize
+			merged.keys = index.keys
+		}
+		formats |= index.Format
+		var err error
+		index.each(func(key string, fileInfo FileIndex) {
+			if _, exists := merged.Files[key]; exists {
+				err = fmt.Errorf("duplicate file found when merging indexes: %s", key)
+			}
+			fileInfo.Start += baseOffset
+			merged.Files[key] = fileInfo
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	merged.Format = archiveFormat(formats)
+	if len(indexes) > 0 {
+		// The merged archive ends where the last one does
+		merged.Trailer = indexes[len(indexes)-1].Trailer
+	}
+	return merged, nil
+}
@@ -0,0 +1,69 @@
+package tarix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeIndexesConcatenated(t *testing.T) {
+	tarA, indexA := createIndexedTar(t, map[string]string{"a1.txt": "first archive", "a2.txt": "still first"})
+	tarB, indexB := createIndexedTar(t, map[string]string{"b1.txt": "second archive"})
+
+	dataA, err := os.ReadFile(tarA)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+	dataB, err := os.ReadFile(tarB)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+
+	dir := t.TempDir()
+	tarC := filepath.Join(dir, "c.tar")
+	if err := os.WriteFile(tarC, append(dataA, dataB...), 0644); err != nil {
+		t.Fatalf("Failed to write concatenated TAR: %v", err)
+	}
+
+	a, err := ReadTarIndex(indexA)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	b, err := ReadTarIndex(indexB)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	shiftedB, err := MergeIndexes(int64(len(dataA)), b)
+	if err != nil {
+		t.Fatalf("Failed to shift index: %v", err)
+	}
+	merged, err := MergeIndexes(0, a, shiftedB)
+	if err != nil {
+		t.Fatalf("Failed to merge indexes: %v", err)
+	}
+
+	indexC := filepath.Join(dir, "c.tar.index.json")
+	if err := WriteTarIndex(merged, indexC); err != nil {
+		t.Fatalf("Failed to write merged index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarC, indexC)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	for name, content := range map[string]string{"a1.txt": "first archive", "a2.txt": "still first", "b1.txt": "second archive"} {
+		data, err := th.ExtractBytesOfFile(name)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+
+	if _, err := MergeIndexes(0, a, a); err == nil {
+		t.Errorf("Expected error when merging duplicate keys")
+	}
+}
@@ -0,0 +1,100 @@
+package tarix
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MigrateIndexHash re-keys the index at oldIndexPath under newScheme and
+// writes the result to newIndexPath, e.g. to move an index keyed by truncated
+// MD5 to SHA256KeyScheme without indexing the archive again. Keys can't be
+// turned back into paths, so the paths are recovered by reading the headers
+// of the original TAR at tarPath; data is skipped. It fails if the TAR has no
+// path for some key of the index. A scheme with only a Name is looked up
+// among the registered ones, like IndexOptions.KeyScheme.
+func MigrateIndexHash(tarPath, oldIndexPath, newIndexPath string, newScheme KeyScheme) error {
+	scheme, err := IndexOptions{KeyScheme: newScheme}.keyScheme()
+	if err != nil {
+		return err
+	}
+	oldIndex, err := ReadTarIndex(oldIndexPath)
+	if err != nil {
+		return err
+	}
+	keyed, err := scanKeyedPaths(tarPath, oldIndex)
+	if err != nil {
+		return err
+	}
+
+	newIndex := &TarIndex{
+		Files:       map[string]FileIndex{},
+		Format:      oldIndex.Format,
+		Root:        oldIndex.Root,
+		Trailer:     oldIndex.Trailer,
+		ArchiveName: oldIndex.ArchiveName,
+		ArchiveSize: oldIndex.ArchiveSize,
+		EntryCount:  oldIndex.EntryCount,
+		Occurrences: oldIndex.Occurrences,
+		Normalize:   oldIndex.Normalize,
+		keys:        scheme.Func,
+	}
+	if scheme.Name != MD5KeyScheme.Name {
+		newIndex.KeyScheme = scheme.Name
+	}
+	newKeys := newIndex.keyFunc()
+
+	var missing int
+	oldIndex.each(func(key string, fileInfo FileIndex) {
+		keyedPath, ok := keyed[key]
+		if !ok {
+			missing++
+			return
+		}
+		newKey := newKeys(keyedPath)
+		if _, exists := newIndex.Files[newKey]; exists && err == nil {
+			err = fmt.Errorf("duplicate file path found for path %s: %s", keyedPath, newKey)
+		}
+		newIndex.Files[newKey] = fileInfo
+	})
+	if missing > 0 {
+		return fmt.Errorf("no path found in %s for %d of the %d files of the index, is it the TAR the index was built from?", tarPath, missing, oldIndex.count())
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeTarIndex(newIndex, newIndexPath, indexEncoding{})
+}
+
+// scanKeyedPaths reads the headers of the TAR and maps the keys of index to
+// the strings they were made of: normalized paths, with the index's Root
+// stripped, followed by their occurrence for indexes keyed by occurrence.
+func scanKeyedPaths(tarPath string, index *TarIndex) (map[string]string, error) {
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar file: %w", err)
+	}
+	defer file.Close()
+
+	keys := newOccurrenceKeys(index)
+	keyed := map[string]string{}
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading tar header: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeGNUSparse {
+			continue
+		}
+
+		keyedPath, _ := keys.nextPath(header.Name)
+		keyed[keys.keys(keyedPath)] = keyedPath
+	}
+	return keyed, nil
+}
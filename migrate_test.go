@@ -0,0 +1,66 @@
+package tarix
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateIndexHash(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "migrate.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"data/a.txt": "aaa", "data/sub/b.txt": "bb"})
+
+	oldIndexPath := filepath.Join(dir, "old.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, oldIndexPath, IndexOptions{Root: "data"}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	newIndexPath := filepath.Join(dir, "new.index.json")
+	if err := MigrateIndexHash(tarFilePath, oldIndexPath, newIndexPath, KeyScheme{Name: "sha256"}); err != nil {
+		t.Fatalf("Failed to migrate index: %v", err)
+	}
+
+	oldIndex, err := ReadTarIndex(oldIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read old index: %v", err)
+	}
+	newIndex, err := ReadTarIndex(newIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read new index: %v", err)
+	}
+	if newIndex.KeyScheme != "sha256" || newIndex.Root != "data" || len(newIndex.Files) != 2 {
+		t.Fatalf("Unexpected migrated index: %+v", newIndex)
+	}
+	for _, filePath := range []string{"a.txt", "sub/b.txt"} {
+		newKey := newIndex.Key(filePath)
+		if newKey != SHA256KeyScheme.Func(filePath) {
+			t.Errorf("Expected %s keyed by its SHA-256, got %s", filePath, newKey)
+		}
+		oldInfo, _ := oldIndex.lookup(oldIndex.Key(filePath))
+		if newInfo, ok := newIndex.lookup(newKey); !ok || newInfo != oldInfo {
+			t.Errorf("Expected %s to keep %+v, got %+v", filePath, oldInfo, newInfo)
+		}
+	}
+
+	th, err := NewTarixHandle(tarFilePath, newIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	th.Verify = true
+	if data, err := th.ExtractBytesOfFile("sub/b.txt"); err != nil || string(data) != "bb" {
+		t.Errorf("Expected bb, got %q, %v", data, err)
+	}
+}
+
+func TestMigrateIndexHashWrongTar(t *testing.T) {
+	_, oldIndexPath := createIndexedTar(t, map[string]string{"a.txt": "a"})
+	otherTarPath, _ := createIndexedTar(t, map[string]string{"b.txt": "b"})
+
+	newIndexPath := filepath.Join(t.TempDir(), "new.index.json")
+	err := MigrateIndexHash(otherTarPath, oldIndexPath, newIndexPath, SHA256KeyScheme)
+	if err == nil || !strings.Contains(err.Error(), "no path found") {
+		t.Errorf("Expected an error for a TAR without the indexed paths, got %v", err)
+	}
+}
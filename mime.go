@@ -0,0 +1,31 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// sniffLen is how much of a file http.DetectContentType looks at
+const sniffLen = 512
+
+// sniffContent reads the start of the current entry of tr and returns its
+// MIME type, with a reader giving the whole entry again for reading it on
+func sniffContent(tr *tar.Reader) (string, io.Reader, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(tr, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, fmt.Errorf("failed to read file data: %w", err)
+	}
+	head = head[:n]
+	return http.DetectContentType(head), io.MultiReader(bytes.NewReader(head), tr), nil
+}
+
+// Stat returns the index entry of filePath, relative to th.Root, with its
+// size, position and what else the index recorded, like the MIME type with
+// IndexOptions.MIMETypes. The TAR is not read.
+func (th *TarixHandle) Stat(filePath string) (FileIndex, error) {
+	return th.lookup(filePath)
+}
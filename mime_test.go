@@ -0,0 +1,64 @@
+package tarix
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMIMETypes(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 1000)
+	html := "<!DOCTYPE html><html><body>hi</body></html>"
+	tarFilePath, _ := createIndexedTar(t, map[string]string{
+		"image.dat": png,
+		"page.txt":  html,
+		"empty.bin": "",
+	})
+
+	for _, workers := range []int{1, 4} {
+		indexPath := filepath.Join(t.TempDir(), "mime.index")
+		opts := IndexOptions{MIMETypes: true, ContentHash: true, HashWorkers: workers}
+		if err := CreateTarIndexWithOptions(tarFilePath, indexPath, opts); err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+		th, err := NewTarixHandle(tarFilePath, indexPath)
+		if err != nil {
+			t.Fatalf("Failed to open handle: %v", err)
+		}
+		defer th.Close()
+
+		for name, want := range map[string]struct{ mime, content string }{
+			"image.dat": {"image/png", png},
+			"page.txt":  {"text/html; charset=utf-8", html},
+			"empty.bin": {"text/plain; charset=utf-8", ""},
+		} {
+			fi, err := th.Stat(name)
+			if err != nil {
+				t.Fatalf("Failed to stat %s: %v", name, err)
+			}
+			if fi.MIMEType != want.mime {
+				t.Errorf("MIME type of %s = %q, want %q", name, fi.MIMEType, want.mime)
+			}
+			// Sniffing must not take the start of the file from the checksum
+			sum := sha256.Sum256([]byte(want.content))
+			if fi.ContentHash != hex.EncodeToString(sum[:]) {
+				t.Errorf("Checksum of %s is not the one of its content", name)
+			}
+		}
+
+		// The type overrides the one of the extension when serving
+		rec := httptest.NewRecorder()
+		Handler(th).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/page.txt", nil))
+		if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+			t.Errorf("Served Content-Type %q", got)
+		}
+	}
+
+	if _, err := (&TarixHandle{Index: &TarIndex{Files: map[string]FileIndex{}}}).Stat("missing"); err == nil {
+		t.Error("Expected Stat of a missing file to fail")
+	}
+}
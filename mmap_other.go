@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package tarix
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile fails on systems without mmap support, so files are read instead
+func mmapFile(f *os.File) ([]byte, error) {
+	return nil, errors.New("mmap is not supported on this system")
+}
+
+// munmap does nothing, as mmapFile never maps anything here
+func munmap(data []byte) error {
+	return nil
+}
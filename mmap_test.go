@@ -0,0 +1,51 @@
+package tarix
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+func TestHandleMmap(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "aaa", "b.txt": "bbbbb"})
+
+	th, err := NewTarixHandleWithOptions(tarFilePath, tarIndexPath, HandleOptions{UseMmap: true})
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	th.Verify = true
+
+	if data, err := th.ExtractBytesOfFile("b.txt"); err != nil || string(data) != "bbbbb" {
+		t.Errorf("Expected bbbbb, got %q, %v", data, err)
+	}
+	sr, err := th.SectionReaderOf("a.txt")
+	if err != nil {
+		t.Fatalf("Failed to get section reader: %v", err)
+	}
+	if data, err := io.ReadAll(sr); err != nil || string(data) != "aaa" {
+		t.Errorf("Expected aaa, got %q, %v", data, err)
+	}
+
+	if _, ok := th.Source.(*mmapSource); !ok && mmapSupported(t, tarFilePath) {
+		t.Errorf("Expected the TAR to be mapped, got %T", th.Source)
+	}
+	if err := th.Close(); err != nil {
+		t.Errorf("Failed to close handle: %v", err)
+	}
+}
+
+// mmapSupported reports whether the system can map the file at path
+func mmapSupported(t *testing.T, path string) bool {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+	data, err := mmapFile(f)
+	if err != nil {
+		return false
+	}
+	munmap(data)
+	return true
+}
@@ -0,0 +1,68 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MultiTarixHandle presents several TAR volumes, each with its own index, as
+// one logical archive. Files are resolved by consulting the indexes in order.
+type MultiTarixHandle struct {
+	Handles []*TarixHandle
+}
+
+// NewMultiTarixHandle opens the TAR volumes in tarPaths with the matching indexes in indexPaths
+func NewMultiTarixHandle(tarPaths, indexPaths []string) (*MultiTarixHandle, error) {
+	if len(tarPaths) != len(indexPaths) {
+		return nil, fmt.Errorf("got %d tar files but %d index files", len(tarPaths), len(indexPaths))
+	}
+
+	mh := &MultiTarixHandle{}
+	for i := range tarPaths {
+		th, err := NewTarixHandle(tarPaths[i], indexPaths[i])
+		if err != nil {
+			mh.Close()
+			return nil, fmt.Errorf("failed to open volume %s: %w", tarPaths[i], err)
+		}
+		mh.Handles = append(mh.Handles, th)
+	}
+	return mh, nil
+}
+
+// handleFor returns the handle of the first volume whose index contains filePath
+func (mh *MultiTarixHandle) handleFor(filePath string) (*TarixHandle, error) {
+	for _, th := range mh.Handles {
+		if _, err := th.lookup(filePath); err == nil {
+			return th, nil
+		}
+	}
+	return nil, fmt.Errorf("file %s not found in any index", normalizePath(filePath, ""))
+}
+
+// ExtractBytesOfFile extracts filePath from the volume that holds it
+func (mh *MultiTarixHandle) ExtractBytesOfFile(filePath string) ([]byte, error) {
+	th, err := mh.handleFor(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return th.ExtractBytesOfFile(filePath)
+}
+
+// WriteFileTo streams filePath from the volume that holds it to w
+func (mh *MultiTarixHandle) WriteFileTo(filePath string, w io.Writer) (int64, error) {
+	th, err := mh.handleFor(filePath)
+	if err != nil {
+		return 0, err
+	}
+	return th.WriteFileTo(filePath, w)
+}
+
+// Close closes all volumes
+func (mh *MultiTarixHandle) Close() error {
+	var errs []error
+	for _, th := range mh.Handles {
+		errs = append(errs, th.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,44 @@
+package tarix
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMultiTarixHandle(t *testing.T) {
+	tar1, index1 := createIndexedTar(t, map[string]string{"part1.txt": "from volume one", "shared.txt": "first wins"})
+	tar2, index2 := createIndexedTar(t, map[string]string{"part2.txt": "from volume two", "shared.txt": "shadowed"})
+
+	mh, err := NewMultiTarixHandle([]string{tar1, tar2}, []string{index1, index2})
+	if err != nil {
+		t.Fatalf("Failed to open multi handle: %v", err)
+	}
+	defer mh.Close()
+
+	for name, content := range map[string]string{
+		"part1.txt":  "from volume one",
+		"part2.txt":  "from volume two",
+		"shared.txt": "first wins",
+	} {
+		data, err := mh.ExtractBytesOfFile(name)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := mh.WriteFileTo("part2.txt", &buf); err != nil || buf.String() != "from volume two" {
+		t.Errorf("Unexpected WriteFileTo result: %q, %v", buf.String(), err)
+	}
+
+	if _, err := mh.ExtractBytesOfFile("missing.txt"); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+
+	if _, err := NewMultiTarixHandle([]string{tar1}, nil); err == nil {
+		t.Errorf("Expected error for mismatched volume lists")
+	}
+}
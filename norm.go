@@ -0,0 +1,55 @@
+package tarix
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormForm is a Unicode normalization form paths are put in before keying,
+// so a path composed differently than in the archive still finds its file.
+// macOS, for example, stores accented names decomposed (NFD) while most
+// other systems type them composed (NFC).
+type NormForm string
+
+const (
+	// NormNone keys paths as they are, like indexes without a #normalize
+	// row, which older versions wrote
+	NormNone NormForm = "none"
+	// NFC composes characters where possible, as most systems write them.
+	// It is the default.
+	NFC NormForm = "nfc"
+	// NFD decomposes characters, as macOS filesystems store them
+	NFD NormForm = "nfd"
+)
+
+// normalizationRowKey starts the index row recording IndexOptions.Normalize
+const normalizationRowKey = "#normalize"
+
+// ParseNormForm returns the form named "nfc", "nfd" or "none", or "" for
+// the default, NFC
+func ParseNormForm(name string) (NormForm, error) {
+	switch f := NormForm(strings.ToLower(name)); f {
+	case "", NormNone, NFC, NFD:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown normalization form %q, expected nfc, nfd or none", name)
+}
+
+// normalizes reports whether f changes paths, which NormNone and the ""
+// of indexes without a #normalize row don't
+func (f NormForm) normalizes() bool {
+	return f == NFC || f == NFD
+}
+
+// apply returns s in the form f. Invalid UTF-8 is left as it is.
+func (f NormForm) apply(s string) string {
+	switch f {
+	case NFC:
+		return norm.NFC.String(s)
+	case NFD:
+		return norm.NFD.String(s)
+	}
+	return s
+}
@@ -0,0 +1,139 @@
+package tarix
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormForms(t *testing.T) {
+	// Expected forms are from Python's unicodedata
+	tests := []struct{ in, nfc, nfd string }{
+		{"plain/ascii.txt", "plain/ascii.txt", "plain/ascii.txt"},
+		{"cafe\u0301", "caf\u00e9", "cafe\u0301"},
+		{"caf\u00e9", "caf\u00e9", "cafe\u0301"},
+		{"A\u030angstro\u0308m", "\u00c5ngstr\u00f6m", "A\u030angstro\u0308m"},
+		{"\u1e9b\u0323", "\u1e9b\u0323", "\u017f\u0323\u0307"},
+		{"\ud55c\uad6d\uc5b4", "\ud55c\uad6d\uc5b4", "\u1112\u1161\u11ab\u1100\u116e\u11a8\u110b\u1165"},
+		{"\u1100\u1161\u11a8", "\uac01", "\u1100\u1161\u11a8"},
+		{"a\u0323\u0302", "\u1ead", "a\u0323\u0302"},
+		{"a\u0302\u0323", "\u1ead", "a\u0323\u0302"},
+		{"\u0344", "\u0308\u0301", "\u0308\u0301"},
+		{"\u212b", "\u00c5", "A\u030a"},
+		{"\ufb01", "\ufb01", "\ufb01"},
+		{"e\u0301\u0301", "\u00e9\u0301", "e\u0301\u0301"},
+		{"\u0958", "\u0915\u093c", "\u0915\u093c"},
+	}
+	for _, tt := range tests {
+		if got := NFC.apply(tt.in); got != tt.nfc {
+			t.Errorf("NFC(%+q) = %+q, want %+q", tt.in, got, tt.nfc)
+		}
+		if got := NFD.apply(tt.in); got != tt.nfd {
+			t.Errorf("NFD(%+q) = %+q, want %+q", tt.in, got, tt.nfd)
+		}
+		if got := NormNone.apply(tt.in); got != tt.in {
+			t.Errorf("NormNone changed %+q to %+q", tt.in, got)
+		}
+	}
+
+	// Invalid UTF-8 is kept as is
+	if got := NFC.apply("bad\xffname"); got != "bad\xffname" {
+		t.Errorf("NFC changed invalid UTF-8 to %+q", got)
+	}
+
+	if _, err := ParseNormForm("NFKC"); err == nil {
+		t.Error("Expected NFKC to be rejected")
+	}
+	if f, err := ParseNormForm("NFD"); err != nil || f != NFD {
+		t.Errorf("ParseNormForm(NFD) = %q, %v", f, err)
+	}
+	if f, err := ParseNormForm("none"); err != nil || f != NormNone {
+		t.Errorf("ParseNormForm(none) = %q, %v", f, err)
+	}
+}
+
+func TestNormalizedLookup(t *testing.T) {
+	const composed, decomposed = "caf\u00e9/r\u00e9sum\u00e9.txt", "cafe\u0301/re\u0301sume\u0301.txt"
+
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "names.tar")
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	tw := tar.NewWriter(tarFile)
+	// Stored decomposed, as archived on macOS
+	content := "bonjour"
+	if err := tw.WriteHeader(&tar.Header{Name: decomposed, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	tw.Write([]byte(content))
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+	tarFile.Close()
+
+	// NFC is the default
+	for name, form := range map[string]NormForm{"default": "", "nfc": NFC, "nfd": NFD} {
+		t.Run(name, func(t *testing.T) {
+			indexPath := filepath.Join(dir, name+".index")
+			if err := CreateTarIndexWithOptions(tarFilePath, indexPath, IndexOptions{Normalize: form, StorePaths: true}); err != nil {
+				t.Fatalf("Failed to create index: %v", err)
+			}
+			if form == "" {
+				form = NFC
+			}
+			data, err := os.ReadFile(indexPath)
+			if err != nil {
+				t.Fatalf("Failed to read index: %v", err)
+			}
+			if !strings.Contains(string(data), normalizationRowKey+","+string(form)+"\n") {
+				t.Errorf("Index doesn't record the form:\n%s", data)
+			}
+
+			th, err := NewTarixHandle(tarFilePath, indexPath)
+			if err != nil {
+				t.Fatalf("Failed to open handle: %v", err)
+			}
+			defer th.Close()
+			if th.Index.Normalize != form {
+				t.Errorf("Index form = %q, want %q", th.Index.Normalize, form)
+			}
+			if th.Index.Key(composed) != th.Index.Key(decomposed) {
+				t.Error("Composed and decomposed names have different keys")
+			}
+			for _, name := range []string{composed, decomposed} {
+				got, err := th.ExtractBytesOfFile(name)
+				if err != nil {
+					t.Fatalf("Failed to extract %+q: %v", name, err)
+				}
+				if string(got) != content {
+					t.Errorf("Extracted %q from %+q, want %q", got, name, content)
+				}
+			}
+			// The stored path is the one from the archive
+			if fi, ok := th.Index.Get(th.Index.Key(composed)); !ok || fi.Path != decomposed {
+				t.Errorf("Stored path = %+q, want %+q", fi.Path, decomposed)
+			}
+		})
+	}
+
+	// Without normalization only the archived form is found. No form is
+	// recorded, as in indexes of older versions, which keep their keys.
+	indexPath := filepath.Join(dir, "plain.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, indexPath, IndexOptions{Normalize: NormNone}); err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	if data, _ := os.ReadFile(indexPath); strings.Contains(string(data), normalizationRowKey) {
+		t.Errorf("Expected no form recorded without normalization:\n%s", data)
+	}
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if index.Contains(composed) || !index.Contains(decomposed) {
+		t.Error("Expected only the decomposed name in an index without normalization")
+	}
+}
@@ -0,0 +1,82 @@
+package tarix
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// occurrencesRowKey starts the index row marking indexes built with
+// IndexOptions.Occurrences
+const occurrencesRowKey = "#occurrences"
+
+// occurrencePath returns what the nth occurrence of the normalized filePath
+// is keyed by
+func occurrencePath(filePath string, n int) string {
+	return filePath + "#" + strconv.Itoa(n)
+}
+
+// OccurrenceKey returns the key of the nth occurrence, counting from 0 in
+// archive order, of filePath in an index built with IndexOptions.Occurrences
+func (ti *TarIndex) OccurrenceKey(filePath string, n int) string {
+	return ti.keyFunc()(occurrencePath(normalizePath(filePath, ti.Root), n))
+}
+
+// headerKeys returns the keys the index may have for a header naming
+// filePath: its key, or the keys of all its occurrences
+func (ti *TarIndex) headerKeys(filePath string) []string {
+	if !ti.Occurrences {
+		return []string{ti.Key(filePath)}
+	}
+	var keys []string
+	for n := 0; ; n++ {
+		key := ti.OccurrenceKey(filePath, n)
+		if _, ok := ti.lookup(key); !ok {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+}
+
+// occurrenceKeys keys the entries of a TAR read in archive order, counting
+// how many times each path occurred for indexes built with Occurrences
+type occurrenceKeys struct {
+	index *TarIndex
+	keys  KeyFunc
+	seen  map[string]int
+}
+
+func newOccurrenceKeys(index *TarIndex) *occurrenceKeys {
+	return &occurrenceKeys{index: index, keys: index.keyFunc(), seen: map[string]int{}}
+}
+
+// next returns the key of the next entry naming filePath, and whether a
+// later occurrence of the path is in the index too
+func (o *occurrenceKeys) next(filePath string) (string, bool) {
+	keyedPath, later := o.nextPath(filePath)
+	return o.keys(keyedPath), later
+}
+
+// nextPath is like next, returning the string the key is made of: the
+// normalized path with the index's Root stripped, numbered by occurrence
+// for indexes built with Occurrences
+func (o *occurrenceKeys) nextPath(filePath string) (string, bool) {
+	cleanPath := normalizePath(filePath, o.index.Root)
+	if !o.index.Occurrences {
+		return cleanPath, false
+	}
+	n := o.seen[cleanPath]
+	o.seen[cleanPath]++
+	_, later := o.index.lookup(o.keys(occurrencePath(cleanPath, n+1)))
+	return occurrencePath(cleanPath, n), later
+}
+
+// ExtractOccurrence returns the contents of the nth occurrence, counting from
+// 0 in archive order, of filePath in an archive storing it more than once.
+// The index must have been built with IndexOptions.Occurrences.
+func (th *TarixHandle) ExtractOccurrence(filePath string, n int) ([]byte, error) {
+	if !th.Index.Occurrences {
+		return nil, fmt.Errorf("index is not keyed by occurrence, build it with Occurrences")
+	}
+	return th.extractBytesByKey(th.Index.OccurrenceKey(filepath.Join(th.Root, filePath), n), filePath)
+}
@@ -0,0 +1,137 @@
+package tarix
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractOccurrence(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "versions.tar")
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	tw := tar.NewWriter(tarFile)
+	// Versions of a.txt appended over time, as with tar -r
+	for _, f := range []struct{ name, content string }{
+		{"a.txt", "version 0"},
+		{"b.txt", "other"},
+		{"./a.txt", "version 1, longer"},
+		{"a.txt", "v2"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f.content))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		tw.Write([]byte(f.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+	tarFile.Close()
+
+	tarIndexPath := filepath.Join(dir, "versions.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err == nil {
+		t.Fatal("Expected duplicate paths to fail without Occurrences")
+	}
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Occurrences: true, CheckpointEvery: 10}); err == nil {
+		t.Error("Expected Occurrences with CheckpointEvery to fail")
+	}
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Occurrences: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	if !th.Index.Occurrences {
+		t.Error("Expected the index to be marked as keyed by occurrence")
+	}
+	// Verification matches the header of each occurrence against its key
+	th.Verify = true
+
+	for _, want := range []struct {
+		name    string
+		n       int
+		content string
+	}{
+		{"a.txt", 0, "version 0"},
+		{"a.txt", 1, "version 1, longer"},
+		{"a.txt", 2, "v2"},
+		{"b.txt", 0, "other"},
+	} {
+		data, err := th.ExtractOccurrence(want.name, want.n)
+		if err != nil {
+			t.Fatalf("Failed to extract occurrence %d of %s: %v", want.n, want.name, err)
+		}
+		if string(data) != want.content {
+			t.Errorf("Unexpected content of occurrence %d of %s: %q", want.n, want.name, data)
+		}
+	}
+	if _, err := th.ExtractOccurrence("a.txt", 3); err == nil {
+		t.Error("Expected error for a missing occurrence")
+	}
+	if _, err := th.ExtractBytesOfFile("a.txt"); err == nil {
+		t.Error("Expected plain paths not to be keys")
+	}
+
+}
+
+func TestExtractAllOccurrences(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "versions.tar")
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	tw := tar.NewWriter(tarFile)
+	for _, f := range []struct{ name, content string }{
+		{"a.txt", "version 0"},
+		{"sub/b.txt", "other"},
+		{"a.txt", "version 1"},
+		{"./a.txt", "last"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f.content))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		tw.Write([]byte(f.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close TAR writer: %v", err)
+	}
+	tarFile.Close()
+	tarIndexPath := filepath.Join(dir, "versions.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Occurrences: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// The last occurrence is left, as tar x would
+	checkOutput := func(outputDir string) {
+		t.Helper()
+		for name, want := range map[string]string{"a.txt": "last", "sub/b.txt": "other"} {
+			if data, err := os.ReadFile(filepath.Join(outputDir, name)); err != nil || string(data) != want {
+				t.Errorf("Unexpected content of %s: %q, %v", name, data, err)
+			}
+		}
+	}
+
+	outputDir := filepath.Join(dir, "all")
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, outputDir, ExtractOptions{Concurrency: 4}); err != nil {
+		t.Fatalf("Failed to extract all: %v", err)
+	}
+	checkOutput(outputDir)
+
+	explodeDir := filepath.Join(dir, "exploded")
+	summary, err := Explode(tarFilePath, tarIndexPath, explodeDir, ExtractOptions{})
+	if err != nil {
+		t.Fatalf("Failed to explode: %v", err)
+	}
+	if summary.Files != 2 || summary.NotIndexed != 0 || len(summary.Skipped) != 2 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	checkOutput(explodeDir)
+}
@@ -0,0 +1,23 @@
+package tarix
+
+import "fmt"
+
+// DataRange is where the data of a file is in the TAR, for tools reading it
+// directly, like dd
+type DataRange struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"` // Offset of the first byte of data
+	Size   int64  `json:"size"`
+}
+
+// DataRange returns where the data of filePath is in the TAR, looking it up
+// like extraction does. For sparse files the data at Offset is their stored
+// fragments, not Size bytes of content.
+func (ti *TarIndex) DataRange(filePath string) (DataRange, error) {
+	key := ti.Key(filePath)
+	fileInfo, ok := ti.lookup(key)
+	if !ok {
+		return DataRange{}, fmt.Errorf("file %s not found in index", key)
+	}
+	return DataRange{Path: filePath, Offset: fileInfo.DataOffset(), Size: fileInfo.Size}, nil
+}
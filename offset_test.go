@@ -0,0 +1,46 @@
+package tarix
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDataRange(t *testing.T) {
+	dir := t.TempDir()
+	longName := strings.Repeat("long/", 30) + "b.txt"
+	files := map[string]string{"a.txt": "alpha", longName: "bravo"}
+	tarFilePath := filepath.Join(dir, "offset.tar")
+	writeTestTar(t, tarFilePath, files)
+	tarIndexPath := filepath.Join(dir, "offset.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	raw, err := os.ReadFile(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+
+	// The long name has extended headers before its own
+	for name, content := range files {
+		dataRange, err := index.DataRange(name)
+		if err != nil {
+			t.Fatalf("Failed to get range of %s: %v", name, err)
+		}
+		if dataRange.Path != name || dataRange.Size != int64(len(content)) {
+			t.Errorf("Unexpected range of %s: %+v", name, dataRange)
+		}
+		if got := string(raw[dataRange.Offset : dataRange.Offset+dataRange.Size]); got != content {
+			t.Errorf("Expected %q at the offset of %s, got %q", content, name, got)
+		}
+	}
+
+	if _, err := index.DataRange("missing.txt"); err == nil {
+		t.Error("Expected an error for a file not in the index")
+	}
+}
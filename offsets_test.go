@@ -0,0 +1,162 @@
+package tarix
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// compareOffsetMethods indexes the TAR at tarPath as CreateTarIndex does,
+// and again with a tar.Reader reading the whole archive through a
+// countingReader, and reports every file whose start, size or number of
+// extended header blocks differ between the two
+func compareOffsetMethods(tarPath string) error {
+	dir, err := os.MkdirTemp("", "tarix-offsets-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// Occurrence keys tell apart paths stored more than once
+	indexPath := filepath.Join(dir, "index.csv")
+	if err := CreateTarIndexWithOptions(tarPath, indexPath, IndexOptions{Occurrences: true}); err != nil {
+		return err
+	}
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Without Seek, tar.Reader reads everything, so cr.n is where it is
+	cr := &countingReader{r: file}
+	tr := tar.NewReader(cr)
+	var errs []error
+	var entryPos int64
+	occurrences := map[string]int{}
+	seen := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading tar header: %w", err)
+		}
+
+		// tar.Reader has read the extended headers and the entry's own header
+		start := cr.n - headerSize
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeGNUSparse {
+			cleanFilePath := normalizePath(header.Name, "")
+			want := FileIndex{Start: start, Size: header.Size, HeaderBlocks: int((start - entryPos) / headerSize)}
+			got, ok := index.Get(index.OccurrenceKey(cleanFilePath, occurrences[cleanFilePath]))
+			occurrences[cleanFilePath]++
+			seen++
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("%s at %d: not indexed", cleanFilePath, start))
+			case got.Start != want.Start || got.Size != want.Size || got.HeaderBlocks != want.HeaderBlocks:
+				errs = append(errs, fmt.Errorf("%s: indexed at %d (size %d, %d header blocks), tar.Reader found %d (size %d, %d header blocks)",
+					cleanFilePath, got.Start, got.Size, got.HeaderBlocks, want.Start, want.Size, want.HeaderBlocks))
+			}
+		}
+
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return fmt.Errorf("error reading tar data: %w", err)
+		}
+		entryPos = (cr.n + 511) & ^int64(511)
+	}
+	if seen != index.Len() {
+		errs = append(errs, fmt.Errorf("indexed %d files, tar.Reader found %d", index.Len(), seen))
+	}
+	return errors.Join(errs...)
+}
+
+func TestCompareOffsetMethods(t *testing.T) {
+	for _, fixture := range []string{"format-ustar.tar", "format-gnu.tar", "format-pax.tar", "format-bsdtar.tar", "sparse-gnu.tar", "pax-global.tar"} {
+		t.Run(fixture, func(t *testing.T) {
+			if err := compareOffsetMethods(filepath.Join("testdata", fixture)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	longName := strings.Repeat("long/", 30) + "name.txt"
+	data := func(n int) string { return strings.Repeat("x", n) }
+	for _, tc := range []struct {
+		name    string
+		headers []*tar.Header
+	}{
+		{"block sizes", []*tar.Header{
+			{Name: "empty.txt", Size: 0},
+			{Name: "511.txt", Size: 511},
+			{Name: "512.txt", Size: 512},
+			{Name: "513.txt", Size: 513},
+		}},
+		{"ustar prefix", []*tar.Header{
+			{Name: strings.Repeat("p", 90) + "/" + strings.Repeat("n", 90), Size: 10, Format: tar.FormatUSTAR},
+		}},
+		{"gnu long names", []*tar.Header{
+			{Name: longName, Size: 600, Format: tar.FormatGNU},
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: longName, Format: tar.FormatGNU},
+			{Name: "after.txt", Size: 3, Format: tar.FormatGNU},
+		}},
+		{"pax records", []*tar.Header{
+			{Name: longName, Size: 100, Format: tar.FormatPAX},
+			{Name: "attrs.txt", Size: 5, PAXRecords: map[string]string{"SCHILY.xattr.user.note": data(2000)}},
+			{Name: "global", Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": data(600)}},
+			{Name: "after.txt", Size: 3},
+		}},
+		{"mixed entries", []*tar.Header{
+			{Name: "dir/", Typeflag: tar.TypeDir},
+			{Name: "dir/a.txt", Size: 1000},
+			{Name: "dir/hard", Typeflag: tar.TypeLink, Linkname: "dir/a.txt"},
+			{Name: "dir/a.txt", Size: 20},
+			{Name: "./dir/b.txt", Size: 1},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tarFilePath := filepath.Join(t.TempDir(), "offsets.tar")
+			tarFile, err := os.Create(tarFilePath)
+			if err != nil {
+				t.Fatalf("Failed to create TAR: %v", err)
+			}
+			tw := tar.NewWriter(tarFile)
+			for _, header := range tc.headers {
+				switch header.Typeflag {
+				case tar.TypeXGlobalHeader:
+					// Global headers may only have records
+				case 0:
+					header.Typeflag = tar.TypeReg
+					fallthrough
+				default:
+					header.Mode = 0644
+				}
+				if err := tw.WriteHeader(header); err != nil {
+					t.Fatalf("Failed to write header: %v", err)
+				}
+				if _, err := tw.Write([]byte(data(int(header.Size)))); err != nil {
+					t.Fatalf("Failed to write data: %v", err)
+				}
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatalf("Failed to close TAR: %v", err)
+			}
+			tarFile.Close()
+
+			if err := compareOffsetMethods(tarFilePath); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
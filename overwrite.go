@@ -0,0 +1,74 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// OverwritePolicy is what extraction does with output files that already exist
+type OverwritePolicy int
+
+const (
+	// Overwrite truncates and rewrites existing files. It is the default.
+	Overwrite OverwritePolicy = iota
+	// OverwriteSkip leaves existing files untouched and reports them
+	OverwriteSkip
+	// OverwriteError fails the extraction at the first existing file
+	OverwriteError
+)
+
+// String returns the name of the policy as accepted by ParseOverwritePolicy
+func (p OverwritePolicy) String() string {
+	switch p {
+	case Overwrite:
+		return "overwrite"
+	case OverwriteSkip:
+		return "skip"
+	case OverwriteError:
+		return "error"
+	}
+	return fmt.Sprintf("OverwritePolicy(%d)", int(p))
+}
+
+// ParseOverwritePolicy returns the policy named "overwrite", "skip" or "error"
+func ParseOverwritePolicy(name string) (OverwritePolicy, error) {
+	for _, p := range []OverwritePolicy{Overwrite, OverwriteSkip, OverwriteError} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overwrite policy %q, expected overwrite, skip or error", name)
+}
+
+// StatFS is an FS that can also describe files. Extracting with an
+// OverwritePolicy other than Overwrite needs one to find existing files.
+type StatFS interface {
+	FS
+	Stat(name string) (os.FileInfo, error)
+}
+
+// checkOutput applies the overwrite policy to outputPath, reporting whether
+// the file exists and must be skipped. With OverwriteError an existing file
+// is an error matching fs.ErrExist.
+func (p OverwritePolicy) checkOutput(fsys FS, outputPath string) (bool, error) {
+	if p == Overwrite {
+		return false, nil
+	}
+	statFS, ok := fsys.(StatFS)
+	if !ok {
+		return false, fmt.Errorf("overwrite policy %s needs an FS implementing StatFS", p)
+	}
+	_, err := statFS.Stat(outputPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check output file: %w", err)
+	}
+	if p == OverwriteError {
+		return false, fmt.Errorf("output file %s: %w", outputPath, fs.ErrExist)
+	}
+	return true, nil
+}
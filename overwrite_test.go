@@ -0,0 +1,85 @@
+package tarix
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractAllOverwritePolicy(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "new a", "b.txt": "new b"})
+	existing := filepath.Join("out", "a.txt")
+
+	newFS := func() *MemFS {
+		fsys := NewMemFS()
+		fsys.Dirs["out"] = 0755
+		fsys.Files[existing] = &MemFile{Data: []byte("old a"), Mode: 0644}
+		return fsys
+	}
+
+	fsys := newFS()
+	var log bytes.Buffer
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, "out", ExtractOptions{FS: fsys, Log: &log, Overwrite: OverwriteSkip}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if data := string(fsys.Files[existing].Data); data != "old a" {
+		t.Errorf("Expected the existing file to be kept, got %q", data)
+	}
+	if f := fsys.Files[filepath.Join("out", "b.txt")]; f == nil || string(f.Data) != "new b" {
+		t.Errorf("Expected b.txt to be extracted, got %+v", f)
+	}
+	if !strings.Contains(log.String(), "Skipped a.txt") {
+		t.Errorf("Expected the skipped file to be reported, got %q", log.String())
+	}
+
+	fsys = newFS()
+	err := ExtractAllWithOptions(tarFilePath, tarIndexPath, "out", ExtractOptions{FS: fsys, Overwrite: OverwriteError})
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected fs.ErrExist, got %v", err)
+	}
+	if data := string(fsys.Files[existing].Data); data != "old a" {
+		t.Errorf("Expected the existing file to be kept, got %q", data)
+	}
+
+	fsys = newFS()
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, "out", ExtractOptions{FS: fsys}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if data := string(fsys.Files[existing].Data); data != "new a" {
+		t.Errorf("Expected the existing file to be overwritten by default, got %q", data)
+	}
+}
+
+func TestExtractFileOverwritePolicy(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "new a"})
+	outputPath := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(outputPath, []byte("old a"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "a.txt", outputPath, ExtractOptions{Overwrite: OverwriteError})
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected fs.ErrExist, got %v", err)
+	}
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "a.txt", outputPath, ExtractOptions{Overwrite: OverwriteSkip}); err != nil {
+		t.Errorf("Expected a skip, got %v", err)
+	}
+	if data, _ := os.ReadFile(outputPath); string(data) != "old a" {
+		t.Errorf("Expected the existing file to be kept, got %q", data)
+	}
+}
+
+func TestParseOverwritePolicy(t *testing.T) {
+	for _, p := range []OverwritePolicy{Overwrite, OverwriteSkip, OverwriteError} {
+		if parsed, err := ParseOverwritePolicy(p.String()); err != nil || parsed != p {
+			t.Errorf("Expected %v, got %v, %v", p, parsed, err)
+		}
+	}
+	if _, err := ParseOverwritePolicy("clobber"); err == nil {
+		t.Errorf("Expected an error for an unknown policy")
+	}
+}
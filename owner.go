@@ -0,0 +1,60 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sync/atomic"
+)
+
+// ChownFS is an FS that can also change the owner of files and directories.
+// ExtractOptions.PreserveOwner needs one.
+type ChownFS interface {
+	FS
+	Chown(name string, uid, gid int) error
+}
+
+// ownerSetter gives extracted files the numeric owners of their TAR entries
+// for ExtractOptions.PreserveOwner. A nil ownerSetter leaves owners alone.
+type ownerSetter struct {
+	fsys ChownFS
+	log  *slog.Logger
+	// denied is set once a change was refused, after which none are tried
+	denied atomic.Bool
+}
+
+// newOwnerSetter returns the ownerSetter for extracting with opts into fsys,
+// nil if owners are left to the current user
+func newOwnerSetter(fsys FS, opts ExtractOptions) *ownerSetter {
+	if !opts.PreserveOwner {
+		return nil
+	}
+	log := slogger(opts.Logger)
+	chownFS, ok := fsys.(ChownFS)
+	if !ok {
+		log.Warn("filesystem can't change owners, leaving files owned by the current user")
+		return nil
+	}
+	return &ownerSetter{fsys: chownFS, log: log}
+}
+
+// chown sets the owner of name to uid and gid. Without the privilege to, as
+// for users other than root, it warns once and leaves the owners of this and
+// all later files to the current user.
+func (s *ownerSetter) chown(name string, uid, gid int) error {
+	if s == nil || s.denied.Load() {
+		return nil
+	}
+	err := s.fsys.Chown(name, uid, gid)
+	if errors.Is(err, fs.ErrPermission) || errors.Is(err, errors.ErrUnsupported) {
+		if !s.denied.Swap(true) {
+			s.log.Warn("not permitted to change owners, leaving files owned by the current user", "path", name, "error", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set file owner: %w", err)
+	}
+	return nil
+}
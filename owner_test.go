@@ -0,0 +1,98 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// deniedChownFS is a MemFS refusing to change owners, like an OS filesystem
+// for users other than root
+type deniedChownFS struct {
+	*MemFS
+	calls int
+}
+
+func (d *deniedChownFS) Chown(name string, uid, gid int) error {
+	d.calls++
+	return &fs.PathError{Op: "chown", Path: name, Err: fs.ErrPermission}
+}
+
+// plainFS hides the optional interfaces of a MemFS
+type plainFS struct {
+	FS
+}
+
+func TestExtractPreserveOwner(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "owners.tar")
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0755, Uid: 1001, Gid: 1002},
+		{Name: "sub/a.txt", Typeflag: tar.TypeReg, Mode: 0644, Uid: 1003, Gid: 1004},
+		{Name: "b.txt", Typeflag: tar.TypeReg, Mode: 0600, Uid: 0, Gid: 0},
+	})
+	tarIndexPath := filepath.Join(dir, "owners.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	want := map[string]MemOwner{
+		filepath.Join("out", "sub"):       {1001, 1002},
+		filepath.Join("out", "sub/a.txt"): {1003, 1004},
+		filepath.Join("out", "b.txt"):     {0, 0},
+	}
+
+	extractors := map[string]func(opts ExtractOptions) error{
+		"ExtractAll": func(opts ExtractOptions) error {
+			return ExtractAllWithOptions(tarFilePath, tarIndexPath, "out", opts)
+		},
+		"Explode": func(opts ExtractOptions) error {
+			_, err := Explode(tarFilePath, tarIndexPath, "out", opts)
+			return err
+		},
+	}
+	for name, extract := range extractors {
+		t.Run(name, func(t *testing.T) {
+			memFS := NewMemFS()
+			if err := extract(ExtractOptions{FS: memFS, PreserveOwner: true}); err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			if !reflect.DeepEqual(memFS.Owners, want) {
+				t.Errorf("Expected owners %v, got %v", want, memFS.Owners)
+			}
+
+			// Owners are left alone by default
+			memFS = NewMemFS()
+			if err := extract(ExtractOptions{FS: memFS}); err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			if len(memFS.Owners) > 0 {
+				t.Errorf("Expected no owners set, got %v", memFS.Owners)
+			}
+
+			// Without the privilege, files are extracted with a single warning
+			var log bytes.Buffer
+			denied := &deniedChownFS{MemFS: NewMemFS()}
+			opts := ExtractOptions{FS: denied, PreserveOwner: true, Logger: slog.New(slog.NewTextHandler(&log, nil))}
+			if err := extract(opts); err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			if len(denied.Files) != 2 || denied.calls != 1 || strings.Count(log.String(), "not permitted to change owners") != 1 {
+				t.Errorf("Expected files and one warning, got %d files, %d calls, log %q", len(denied.Files), denied.calls, log.String())
+			}
+
+			log.Reset()
+			opts.FS = plainFS{NewMemFS()}
+			if err := extract(opts); err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			if !strings.Contains(log.String(), "filesystem can't change owners") {
+				t.Errorf("Expected a warning, got %q", log.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tarix
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestExtractPreserveOwnerOS(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "owners.tar")
+	writeTarWithDirs(t, tarFilePath, []*tar.Header{
+		{Name: "a.txt", Typeflag: tar.TypeReg, Mode: 0644, Uid: 4242, Gid: 4343},
+	})
+	tarIndexPath := filepath.Join(dir, "owners.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	outputDir := filepath.Join(dir, "out")
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, outputDir, ExtractOptions{PreserveOwner: true}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(outputDir, "a.txt"))
+	if err != nil {
+		t.Fatalf("Failed to stat extracted file: %v", err)
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+
+	// Only root may give files away; others keep them
+	wantUid, wantGid := 4242, 4343
+	if os.Getuid() != 0 {
+		wantUid, wantGid = os.Getuid(), int(stat.Gid)
+	}
+	if int(stat.Uid) != wantUid || int(stat.Gid) != wantGid {
+		t.Errorf("Expected owner %d:%d, got %d:%d", wantUid, wantGid, stat.Uid, stat.Gid)
+	}
+}
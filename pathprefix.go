@@ -0,0 +1,73 @@
+package tarix
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prefixRowKey starts the index rows of the path prefix table. The rows come
+// before the entries, and the nth of them holds the prefix with ID n.
+const prefixRowKey = "#prefix"
+
+// pathPrefixes returns the directories, with a trailing separator, holding
+// more than one stored path of the index, sorted so the same index always
+// gets the same table
+func pathPrefixes(index *TarIndex) []string {
+	counts := map[string]int{}
+	index.each(func(_ string, fileInfo FileIndex) {
+		if dir, _ := splitDir(fileInfo.Path); dir != "" {
+			counts[dir]++
+		}
+	})
+
+	var prefixes []string
+	for dir, n := range counts {
+		if n > 1 {
+			prefixes = append(prefixes, dir)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// splitDir splits filePath after its last separator
+func splitDir(filePath string) (dir, name string) {
+	i := strings.LastIndexAny(filePath, "/"+string(filepath.Separator))
+	return filePath[:i+1], filePath[i+1:]
+}
+
+// prefixTable maps the prefixes of an index to their IDs
+type prefixTable map[string]string
+
+func newPrefixTable(prefixes []string) prefixTable {
+	table := prefixTable{}
+	for id, prefix := range prefixes {
+		table[prefix] = strconv.Itoa(id)
+	}
+	return table
+}
+
+// split returns the ID of the prefix of filePath and the rest of the path,
+// or an empty ID and filePath if its directory has no prefix
+func (t prefixTable) split(filePath string) (id, suffix string) {
+	dir, name := splitDir(filePath)
+	if id, ok := t[dir]; ok {
+		return id, name
+	}
+	return "", filePath
+}
+
+// joinPrefix reconstructs a path stored as the prefix with ID id and suffix
+func joinPrefix(prefixes []string, id, suffix string) (string, error) {
+	if id == "" {
+		return suffix, nil
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 0 || n >= len(prefixes) {
+		return "", fmt.Errorf("%w: unknown path prefix %q", ErrIndexCorrupt, id)
+	}
+	return prefixes[n] + suffix, nil
+}
@@ -0,0 +1,73 @@
+package tarix
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexPathPrefixes(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "prefixes.tar")
+	files := map[string]string{
+		"data/deep/tree/a.txt": "a",
+		"data/deep/tree/b.txt": "bb",
+		"data/deep/tree/c.txt": "ccc",
+		"data/other/d.txt":     "dddd",
+		"top.txt":              "eeeee",
+	}
+	writeTestTar(t, tarFilePath, files)
+
+	plainPath := filepath.Join(dir, "plain.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, plainPath, IndexOptions{StorePaths: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	prefixedPath := filepath.Join(dir, "prefixed.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, prefixedPath, IndexOptions{StorePaths: true, PathPrefixes: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	raw, err := os.ReadFile(prefixedPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	// Only the directory shared by several files gets a prefix
+	if n := strings.Count(string(raw), prefixRowKey+","); n != 1 {
+		t.Errorf("Expected 1 prefix row, got %d in %s", n, raw)
+	}
+	if strings.Count(string(raw), "data/deep/tree/") != 1 {
+		t.Errorf("Expected the shared directory to be stored once, got %s", raw)
+	}
+	plainRaw, _ := os.ReadFile(plainPath)
+	if len(raw) >= len(plainRaw) {
+		t.Errorf("Expected the prefixed index to be smaller, got %d bytes vs %d", len(raw), len(plainRaw))
+	}
+
+	plain, err := ReadTarIndex(plainPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	prefixed, err := ReadTarIndex(prefixedPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	for name := range files {
+		key := hashFilePath(name)
+		if got := prefixed.Files[key].Path; got != name || got != plain.Files[key].Path {
+			t.Errorf("Expected path %s, got %q", name, got)
+		}
+	}
+}
+
+func TestIndexUnknownPathPrefix(t *testing.T) {
+	indexPath := filepath.Join(t.TempDir(), "bad.index.json")
+	content := "key,start,size,path,prefix\n" + prefixRowKey + ",dir/\n" + hashFilePath("dir/a.txt") + ",0,1,a.txt,1\n"
+	if err := os.WriteFile(indexPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if _, err := ReadTarIndex(indexPath); !errors.Is(err, ErrIndexCorrupt) {
+		t.Errorf("Expected ErrIndexCorrupt, got %v", err)
+	}
+}
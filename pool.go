@@ -0,0 +1,53 @@
+package tarix
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// extractPool runs extractions on at most a fixed number of goroutines at
+// once, so extracting many files doesn't open as many outputs, and collects
+// their errors
+type extractPool struct {
+	slots chan struct{}
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	errs  []error
+}
+
+// newExtractPool returns a pool running up to n extractions at once
+func newExtractPool(n int) *extractPool {
+	return &extractPool{slots: make(chan struct{}, n)}
+}
+
+// run calls fn in a new goroutine once a slot is free, blocking until then
+func (p *extractPool) run(fn func() error) {
+	p.slots <- struct{}{}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.slots
+			p.wg.Done()
+		}()
+		if err := fn(); err != nil {
+			p.mu.Lock()
+			p.errs = append(p.errs, err)
+			p.mu.Unlock()
+		}
+	}()
+}
+
+// wait waits for the running extractions and returns their errors joined
+func (p *extractPool) wait() error {
+	p.wg.Wait()
+	return errors.Join(p.errs...)
+}
+
+// concurrency returns the number of files to extract at once
+func (o ExtractOptions) concurrency() int {
+	if o.Concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.Concurrency
+}
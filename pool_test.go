@@ -0,0 +1,112 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// boundedFS is a MemFS recording how many files are open for writing at
+// once, and failing to create the files named in fail
+type boundedFS struct {
+	*MemFS
+	fail map[string]bool
+
+	mu      sync.Mutex
+	open    int
+	maxOpen int
+}
+
+func (b *boundedFS) Create(name string) (io.WriteCloser, error) {
+	if b.fail[filepath.Base(name)] {
+		return nil, fmt.Errorf("create %s: %w", name, errors.ErrUnsupported)
+	}
+	w, err := b.MemFS.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	b.open++
+	b.maxOpen = max(b.maxOpen, b.open)
+	b.mu.Unlock()
+	// Stay open a while so extractions overlap
+	time.Sleep(5 * time.Millisecond)
+	return &boundedWriter{WriteCloser: w, fs: b}, nil
+}
+
+type boundedWriter struct {
+	io.WriteCloser
+	fs     *boundedFS
+	closed bool
+}
+
+func (w *boundedWriter) Close() error {
+	if !w.closed {
+		w.closed = true
+		w.fs.mu.Lock()
+		w.fs.open--
+		w.fs.mu.Unlock()
+	}
+	return w.WriteCloser.Close()
+}
+
+func TestExtractConcurrency(t *testing.T) {
+	files := map[string]string{}
+	var paths []string
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("file%02d.txt", i)
+		files[name] = strings.Repeat(name, i+1)
+		paths = append(paths, name)
+	}
+	tarFilePath, tarIndexPath := createIndexedTar(t, files)
+
+	extractors := map[string]func(opts ExtractOptions) error{
+		"ExtractMany": func(opts ExtractOptions) error {
+			return ExtractMany(tarFilePath, tarIndexPath, paths, "out", opts)
+		},
+		"ExtractAll": func(opts ExtractOptions) error {
+			return ExtractAllWithOptions(tarFilePath, tarIndexPath, "out", opts)
+		},
+	}
+	for name, extract := range extractors {
+		t.Run(name, func(t *testing.T) {
+			fsys := &boundedFS{MemFS: NewMemFS(), fail: map[string]bool{"file03.txt": true, "file07.txt": true}}
+			var progress []Progress
+			err := extract(ExtractOptions{FS: fsys, Concurrency: 3, Progress: func(p Progress) { progress = append(progress, p) }})
+
+			// Both failures are reported, and the other files extracted
+			if !errors.Is(err, errors.ErrUnsupported) || !strings.Contains(err.Error(), "file03.txt") || !strings.Contains(err.Error(), "file07.txt") {
+				t.Errorf("Expected the errors of both failed files, got %v", err)
+			}
+			for _, p := range paths {
+				f, ok := fsys.Files[filepath.Join("out", p)]
+				if fsys.fail[p] {
+					continue
+				}
+				if !ok || string(f.Data) != files[p] {
+					t.Errorf("File %s not extracted", p)
+				}
+			}
+			if fsys.maxOpen > 3 {
+				t.Errorf("%d files open at once, want at most 3", fsys.maxOpen)
+			}
+			if len(progress) != len(paths)-2 || progress[len(progress)-1].FilesDone != int64(len(paths)-2) {
+				t.Errorf("Unexpected progress %+v", progress)
+			}
+
+			// One at a time
+			fsys = &boundedFS{MemFS: NewMemFS()}
+			if err := extract(ExtractOptions{FS: fsys, Concurrency: 1}); err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			if fsys.maxOpen != 1 {
+				t.Errorf("%d files open at once, want 1", fsys.maxOpen)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package tarix
+
+import "time"
+
+// ThrottleProgress returns a ProgressFunc calling fn at most once per
+// interval, for displays that can't keep up with an update per entry.
+// Updates completing the operation are always passed on, so the last one
+// shown is final.
+func ThrottleProgress(fn ProgressFunc, interval time.Duration) ProgressFunc {
+	var last time.Time
+	return func(p Progress) {
+		done := p.BytesTotal > 0 && p.BytesDone >= p.BytesTotal ||
+			p.FilesTotal > 0 && p.FilesDone >= p.FilesTotal
+		if now := time.Now(); done || now.Sub(last) >= interval {
+			last = now
+			fn(p)
+		}
+	}
+}
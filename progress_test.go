@@ -0,0 +1,66 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestThrottleProgress(t *testing.T) {
+	var calls []Progress
+	fn := ThrottleProgress(func(p Progress) { calls = append(calls, p) }, time.Hour)
+	for i := int64(1); i <= 100; i++ {
+		fn(Progress{FilesDone: i, BytesDone: i, BytesTotal: 100})
+	}
+	if len(calls) != 2 {
+		t.Fatalf("Expected the first and final updates, got %d", len(calls))
+	}
+	if calls[1].BytesDone != 100 {
+		t.Errorf("Expected the final update last, got %+v", calls[1])
+	}
+}
+
+func TestIndexProgressEmptyTar(t *testing.T) {
+	dir := t.TempDir()
+	emptyPath := filepath.Join(dir, "empty.tar")
+	if err := os.WriteFile(emptyPath, nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// A tar with no entries, only the end-of-archive marker
+	var buf bytes.Buffer
+	if err := tar.NewWriter(&buf).Close(); err != nil {
+		t.Fatalf("Failed to write tar: %v", err)
+	}
+	noEntriesPath := filepath.Join(dir, "noentries.tar")
+	if err := os.WriteFile(noEntriesPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	for _, tarPath := range []string{emptyPath, noEntriesPath} {
+		var out bytes.Buffer
+		indexPath := tarPath + ".index.json"
+		if err := CreateTarIndexWithOptions(tarPath, indexPath, IndexOptions{Progress: printIndexProgress(&out)}); err != nil {
+			t.Fatalf("Failed to index %s: %v", tarPath, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("Expected no progress for %s, got %q", tarPath, out.String())
+		}
+		index, err := ReadTarIndex(indexPath)
+		if err != nil {
+			t.Fatalf("Failed to read index: %v", err)
+		}
+		if n := index.count(); n != 0 {
+			t.Errorf("Expected no files in the index of %s, got %d", tarPath, n)
+		}
+	}
+
+	// An update with no known total prints nothing rather than dividing by zero
+	var out bytes.Buffer
+	printIndexProgress(&out)(Progress{FilesDone: 1})
+	if out.Len() != 0 {
+		t.Errorf("Expected no progress without a total, got %q", out.String())
+	}
+}
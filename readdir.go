@@ -0,0 +1,105 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DirEntry is a file or directory listed by ReadDir. It implements fs.DirEntry.
+type DirEntry struct {
+	name string
+	dir  bool
+	size int64
+}
+
+func (e DirEntry) Name() string { return e.name }
+
+func (e DirEntry) IsDir() bool { return e.dir }
+
+func (e DirEntry) Type() fs.FileMode { return e.mode().Type() }
+
+func (e DirEntry) Info() (fs.FileInfo, error) { return fileInfo{e}, nil }
+
+// mode returns a read-only mode, as the archive can't be written through the index
+func (e DirEntry) mode() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// fileInfo is the fs.FileInfo of a DirEntry
+type fileInfo struct {
+	e DirEntry
+}
+
+func (fi fileInfo) Name() string       { return fi.e.name }
+func (fi fileInfo) Size() int64        { return fi.e.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.e.mode() }
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return fi.e.dir }
+func (fi fileInfo) Sys() any           { return nil }
+
+// ReadDir returns the files and subdirectories directly under dir, sorted by
+// name, like os.ReadDir. dir is relative to th.Root; "" and "." list the top
+// level. Directories are derived from the stored paths of the files, so the
+// index must have been built with IndexOptions.StorePaths.
+func (th *TarixHandle) ReadDir(dir string) ([]DirEntry, error) {
+	dir = filepath.ToSlash(normalizePath(filepath.Join(th.Root, dir), th.Index.Root))
+	dir = strings.TrimPrefix(dir, "/")
+	if dir == "" {
+		dir = "."
+	}
+
+	hasPaths := false
+	children := map[string]DirEntry{}
+	var notDir error
+	th.Index.each(func(_ string, fileInfo FileIndex) {
+		if fileInfo.Path == "" {
+			return
+		}
+		hasPaths = true
+
+		filePath := strings.TrimPrefix(filepath.ToSlash(fileInfo.Path), "/")
+		if filePath == dir {
+			notDir = fmt.Errorf("%s is not a directory", dir)
+			return
+		}
+		rel := filePath
+		if dir != "." {
+			var ok bool
+			if rel, ok = strings.CutPrefix(filePath, dir+"/"); !ok {
+				return
+			}
+		}
+
+		// Deeper files only show as the subdirectory they are in
+		if name, _, isDeeper := strings.Cut(rel, "/"); isDeeper {
+			children[name] = DirEntry{name: name, dir: true}
+			return
+		}
+		children[rel] = DirEntry{name: path.Base(rel), size: fileInfo.Size}
+	})
+
+	switch {
+	case !hasPaths && th.Index.count() > 0:
+		return nil, errors.New("index has no stored paths, build it with StorePaths")
+	case notDir != nil:
+		return nil, notDir
+	case len(children) == 0 && dir != ".":
+		return nil, fmt.Errorf("directory %s: %w", dir, fs.ErrNotExist)
+	}
+
+	entries := make([]DirEntry, 0, len(children))
+	for _, entry := range children {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries, nil
+}
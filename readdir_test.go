@@ -0,0 +1,80 @@
+package tarix
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "dir.tar")
+	// No explicit directory headers, directories come from the file paths
+	writeTestTar(t, tarFilePath, map[string]string{
+		"top.txt":           "top",
+		"a/one.txt":         "1",
+		"a/two.txt":         "22",
+		"a/sub/deep.txt":    "deep",
+		"a/sub/deeper/x.go": "x",
+		"b/c/d.txt":         "d",
+	})
+	tarIndexPath := filepath.Join(dir, "dir.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{StorePaths: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	type entry struct {
+		name string
+		dir  bool
+		size int64
+	}
+	tests := map[string][]entry{
+		"":      {{"a", true, 0}, {"b", true, 0}, {"top.txt", false, 3}},
+		"a":     {{"one.txt", false, 1}, {"sub", true, 0}, {"two.txt", false, 2}},
+		"./a/":  {{"one.txt", false, 1}, {"sub", true, 0}, {"two.txt", false, 2}},
+		"a/sub": {{"deep.txt", false, 4}, {"deeper", true, 0}},
+		"b":     {{"c", true, 0}},
+	}
+	for dirName, expected := range tests {
+		entries, err := th.ReadDir(dirName)
+		if err != nil {
+			t.Errorf("Failed to read %q: %v", dirName, err)
+			continue
+		}
+		if len(entries) != len(expected) {
+			t.Errorf("Expected %d entries in %q, got %d", len(expected), dirName, len(entries))
+			continue
+		}
+		for i, e := range expected {
+			got := entries[i]
+			info, _ := got.Info()
+			if got.Name() != e.name || got.IsDir() != e.dir || info.Size() != e.size {
+				t.Errorf("Expected %+v in %q, got %s (dir %v, size %d)", e, dirName, got.Name(), got.IsDir(), info.Size())
+			}
+			if got.IsDir() != got.Type().IsDir() {
+				t.Errorf("Type of %s doesn't match IsDir", got.Name())
+			}
+		}
+	}
+
+	if _, err := th.ReadDir("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := th.ReadDir("top.txt"); err == nil {
+		t.Error("Expected error listing a file")
+	}
+
+	// Listing relative to the handle's root
+	th.Root = "a"
+	entries, err := th.ReadDir("sub")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "deep.txt" {
+		t.Errorf("Expected a/sub listed relative to the root, got %v, %v", entries, err)
+	}
+}
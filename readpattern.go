@@ -0,0 +1,49 @@
+package tarix
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ReadPattern tells the OS how a TAR is about to be read, so it can read
+// ahead to suit. It is only a hint, given with posix_fadvise where the
+// platform has it and ignored elsewhere.
+type ReadPattern int
+
+const (
+	// ReadDefault leaves reading ahead to the OS
+	ReadDefault ReadPattern = iota
+	// ReadSequential is for reading through the archive in order, as
+	// ExtractAll does. The OS reads further ahead.
+	ReadSequential
+	// ReadRandom is for reading scattered files, as extracting single files
+	// does. The OS doesn't read ahead of them.
+	ReadRandom
+)
+
+// ParseReadPattern returns the ReadPattern named "sequential", "random" or
+// "default"; "" is the default too
+func ParseReadPattern(name string) (ReadPattern, error) {
+	switch strings.ToLower(name) {
+	case "", "default":
+		return ReadDefault, nil
+	case "sequential":
+		return ReadSequential, nil
+	case "random":
+		return ReadRandom, nil
+	}
+	return ReadDefault, fmt.Errorf("unknown read pattern %q, expected sequential, random or default", name)
+}
+
+// adviseRead gives the OS the hint of pattern for the whole of f. Failing to
+// is only logged, as reads work as well without it.
+func adviseRead(f *os.File, pattern ReadPattern, log *slog.Logger) {
+	if pattern == ReadDefault {
+		return
+	}
+	if err := fadvise(f, pattern); err != nil {
+		log.Debug("failed to advise the read pattern", "tar", f.Name(), "error", err)
+	}
+}
@@ -0,0 +1,51 @@
+package tarix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseReadPattern(t *testing.T) {
+	for name, want := range map[string]ReadPattern{"": ReadDefault, "default": ReadDefault, "sequential": ReadSequential, "Random": ReadRandom} {
+		got, err := ParseReadPattern(name)
+		if err != nil || got != want {
+			t.Errorf("ParseReadPattern(%q) = %v, %v, expected %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseReadPattern("backwards"); err == nil {
+		t.Error("Expected an error for an unknown read pattern")
+	}
+}
+
+func TestExtractReadPattern(t *testing.T) {
+	files := map[string]string{"a.txt": "a", "dir/b.txt": "bee"}
+	tarFilePath := filepath.Join(t.TempDir(), "archive.tar")
+	writeTestTar(t, tarFilePath, files)
+	tarIndexPath := tarFilePath + ".index.csv"
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	// The hints don't change what is read
+	for _, pattern := range []ReadPattern{ReadDefault, ReadSequential, ReadRandom} {
+		outputDir := t.TempDir()
+		if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, outputDir, ExtractOptions{ReadPattern: pattern}); err != nil {
+			t.Fatalf("Failed to extract all with pattern %v: %v", pattern, err)
+		}
+		for name, content := range files {
+			if data, err := os.ReadFile(filepath.Join(outputDir, name)); err != nil || string(data) != content {
+				t.Errorf("Unexpected content of %s with pattern %v: %q, %v", name, pattern, data, err)
+			}
+		}
+
+		th, err := NewTarixHandleWithOptions(tarFilePath, tarIndexPath, HandleOptions{ReadPattern: pattern})
+		if err != nil {
+			t.Fatalf("Failed to open handle with pattern %v: %v", pattern, err)
+		}
+		if data, err := th.ExtractBytesOfFile("dir/b.txt"); err != nil || string(data) != "bee" {
+			t.Errorf("Unexpected content with pattern %v: %q, %v", pattern, data, err)
+		}
+		th.Close()
+	}
+}
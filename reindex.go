@@ -0,0 +1,113 @@
+package tarix
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// extractReindexed rebuilds the stale index at indexPath into a temporary
+// directory and extracts filePath again with the new index, for
+// ExtractOptions.AutoReindex. With ExtractOptions.ReplaceStaleIndex the new
+// index then replaces the old one.
+func extractReindexed(fsys FS, tarPath, indexPath, filePath, outputPath string, opts ExtractOptions, staleErr error) error {
+	fmt.Fprintf(logWriter(opts.Log), "Index is stale (%v), reindexing %s\n", staleErr, tarPath)
+	slogger(opts.Logger).Warn("index is stale, reindexing", "tar", tarPath, "index", indexPath, "error", staleErr)
+
+	indexOpts, err := reindexOptions(indexPath)
+	if err != nil {
+		return err
+	}
+	indexOpts.Logger = opts.Logger
+
+	dir, err := os.MkdirTemp("", "tarix-reindex-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	newIndexPath := filepath.Join(dir, filepath.Base(indexPath))
+	if err := CreateTarIndexWithOptions(tarPath, newIndexPath, indexOpts); err != nil {
+		return fmt.Errorf("failed to reindex tar file: %w", err)
+	}
+
+	opts.AutoReindex = false
+	if err := extractFileFromTar(fsys, tarPath, newIndexPath, filePath, outputPath, opts); err != nil {
+		return err
+	}
+	if !opts.ReplaceStaleIndex {
+		return nil
+	}
+
+	// The temporary directory may be on another filesystem, so the index is
+	// copied rather than renamed into place
+	newIndex, err := os.Open(newIndexPath)
+	if err != nil {
+		return fmt.Errorf("failed to open index file: %w", err)
+	}
+	defer newIndex.Close()
+	err = writeIndexFile(indexPath, func(w io.Writer) error {
+		if _, err := io.Copy(w, newIndex); err != nil {
+			return fmt.Errorf("failed to write index file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slogger(opts.Logger).Info("replaced stale index", "index", indexPath)
+	return nil
+}
+
+// reindexOptions returns the IndexOptions rebuilding the index at indexPath
+// as it was built, as far as the index tells: with its delimiter, key
+// scheme, root, occurrence keys, normalization and path prefixes, and with
+// stored paths, content hashes and MIME types if any file has them
+func reindexOptions(indexPath string) (IndexOptions, error) {
+	sc, err := OpenIndexScanner(indexPath, LoadOptions{})
+	if err != nil {
+		return IndexOptions{}, err
+	}
+	defer sc.Close()
+	// The rows of a stale index may well start past the end of the archive
+	sc.lenient = true
+
+	var opts IndexOptions
+	byKey, byStart := true, true
+	var lastKey string
+	var lastStart int64
+	rows := 0
+	for sc.Scan() {
+		key, fileInfo := sc.Entry()
+		opts.StorePaths = opts.StorePaths || fileInfo.Path != ""
+		opts.ContentHash = opts.ContentHash || fileInfo.ContentHash != ""
+		opts.MIMETypes = opts.MIMETypes || fileInfo.MIMEType != ""
+		if rows > 0 {
+			byKey = byKey && key >= lastKey
+			byStart = byStart && fileInfo.Start >= lastStart
+		}
+		lastKey, lastStart = key, fileInfo.Start
+		rows++
+	}
+	if err := sc.Err(); err != nil {
+		return IndexOptions{}, err
+	}
+	index, err := sc.Index()
+	if err != nil {
+		return IndexOptions{}, err
+	}
+
+	if sc.reader.Comma != ',' {
+		opts.Delimiter = sc.reader.Comma
+	}
+	opts.ByOffset = byStart && !byKey
+	opts.PathPrefixes = len(sc.prefixes) > 0
+	opts.KeyScheme = KeyScheme{Name: index.KeyScheme}
+	opts.Root = index.Root
+	opts.Occurrences = index.Occurrences
+	opts.Normalize = index.Normalize
+	if !index.Normalize.normalizes() {
+		opts.Normalize = NormNone
+	}
+	return opts, nil
+}
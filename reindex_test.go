@@ -0,0 +1,111 @@
+package tarix
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractAutoReindex(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "archive.tar")
+	tarIndexPath := filepath.Join(dir, "archive.tar.index.csv")
+	writeTestTar(t, tarFilePath, map[string]string{"b.txt": "bee"})
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{StorePaths: true, Delimiter: '\t'}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	staleIndex, err := os.ReadFile(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	// A file added in front moves b.txt to another offset
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "aaaa", "b.txt": "bee"})
+	outputPath := filepath.Join(dir, "out.txt")
+
+	err = ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "b.txt", outputPath, ExtractOptions{Verify: true})
+	if !errors.Is(err, ErrIndexStale) {
+		t.Fatalf("Expected ErrIndexStale without AutoReindex, got %v", err)
+	}
+
+	t.Run("keep stale index", func(t *testing.T) {
+		var log bytes.Buffer
+		if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "b.txt", outputPath, ExtractOptions{AutoReindex: true, Log: &log}); err != nil {
+			t.Fatalf("Failed to extract with AutoReindex: %v", err)
+		}
+		if data, _ := os.ReadFile(outputPath); string(data) != "bee" {
+			t.Errorf("Unexpected content: %q", data)
+		}
+		if !bytes.Contains(log.Bytes(), []byte("Index is stale")) {
+			t.Errorf("Expected the reindex to be logged, got %q", log.String())
+		}
+		if data, _ := os.ReadFile(tarIndexPath); !bytes.Equal(data, staleIndex) {
+			t.Errorf("Expected the stale index to be kept without ReplaceStaleIndex")
+		}
+	})
+
+	t.Run("replace stale index", func(t *testing.T) {
+		opts := ExtractOptions{AutoReindex: true, ReplaceStaleIndex: true}
+		if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "b.txt", outputPath, opts); err != nil {
+			t.Fatalf("Failed to extract with AutoReindex: %v", err)
+		}
+		index, err := ReadTarIndex(tarIndexPath)
+		if err != nil {
+			t.Fatalf("Failed to read replaced index: %v", err)
+		}
+		if index.Len() != 2 {
+			t.Errorf("Expected the replaced index to have 2 files, got %d", index.Len())
+		}
+		// The rebuilt index keeps the options of the old one
+		fileInfo, ok := index.Get(hashFilePath("b.txt"))
+		if !ok || fileInfo.Start != 1024 || fileInfo.Path != "b.txt" {
+			t.Errorf("Unexpected entry of b.txt in the replaced index: %+v", fileInfo)
+		}
+		if data, _ := os.ReadFile(tarIndexPath); !bytes.Contains(data, []byte("key\tstart\tsize\tpath")) {
+			t.Errorf("Expected a tab-delimited index with paths, got %q", data)
+		}
+
+		if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "b.txt", outputPath, ExtractOptions{Verify: true}); err != nil {
+			t.Errorf("Failed to extract with the replaced index: %v", err)
+		}
+	})
+}
+
+func TestExtractAutoReindexOtherErrors(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "a"})
+	err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "missing.txt", filepath.Join(t.TempDir(), "out"), ExtractOptions{AutoReindex: true, ReplaceStaleIndex: true})
+	if err == nil || errors.Is(err, ErrIndexStale) {
+		t.Errorf("Expected a missing file error, got %v", err)
+	}
+}
+
+func TestReindexOptions(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "archive.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"top/a/1.txt": "1", "top/a/2.txt": "2", "top/b.txt": "b"})
+
+	for _, want := range []IndexOptions{
+		{},
+		{Delimiter: ';', StorePaths: true, PathPrefixes: true, ByOffset: true},
+		{ContentHash: true, MIMETypes: true, Root: "top", Normalize: NFC},
+		{KeyScheme: SHA256KeyScheme, Occurrences: true},
+		{Normalize: NormNone},
+	} {
+		indexPath := filepath.Join(dir, "index.csv")
+		if err := CreateTarIndexWithOptions(tarFilePath, indexPath, want); err != nil {
+			t.Fatalf("Failed to create TAR index: %v", err)
+		}
+		got, err := reindexOptions(indexPath)
+		if err != nil {
+			t.Fatalf("Failed to read options: %v", err)
+		}
+		if got.Delimiter != want.Delimiter || got.StorePaths != want.StorePaths || got.PathPrefixes != want.PathPrefixes ||
+			got.ByOffset != want.ByOffset || got.ContentHash != want.ContentHash || got.MIMETypes != want.MIMETypes ||
+			got.Root != want.Root || got.normForm() != want.normForm() || got.KeyScheme.Name != want.KeyScheme.Name ||
+			got.Occurrences != want.Occurrences {
+			t.Errorf("Expected options %+v, got %+v", want, got)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package tarix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPReaderAt reads a file served over HTTP, such as an S3 object, with one
+// Range request per ReadAt. Use it with ExtractBytesFromReaderAt.
+type HTTPReaderAt struct {
+	URL string
+	// Client sends the requests; nil uses http.DefaultClient
+	Client *http.Client
+	// Timeout bounds each range request, including reading the response body.
+	// Zero means no limit other than the context's.
+	Timeout time.Duration
+
+	ctx context.Context
+}
+
+// NewHTTPReaderAt creates an HTTPReaderAt for url
+func NewHTTPReaderAt(url string) *HTTPReaderAt {
+	return &HTTPReaderAt{URL: url}
+}
+
+// WithContext returns a copy of r whose requests are canceled with ctx
+func (r *HTTPReaderAt) WithContext(ctx context.Context) *HTTPReaderAt {
+	r2 := *r
+	r2.ctx = ctx
+	return &r2
+}
+
+// ReadAt reads len(p) bytes at off. A request that exceeds the timeout or
+// the context's deadline fails with an error wrapping context.DeadlineExceeded.
+func (r *HTTPReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	n, err := r.readRange(ctx, p, off)
+	if err != nil && ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+		// Canceled reads of the body don't always report the context's error
+		err = fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return n, fmt.Errorf("range request for %d bytes at offset %d of %s timed out: %w", len(p), off, r.URL, err)
+	}
+	return n, err
+}
+
+func (r *HTTPReaderAt) readRange(ctx context.Context, p []byte, off int64) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create range request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send range request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, io.EOF
+	case http.StatusOK:
+		return 0, fmt.Errorf("server ignored the range request for %s", r.URL)
+	default:
+		return 0, fmt.Errorf("range request for %s failed: %s", r.URL, resp.Status)
+	}
+
+	n, err := io.ReadFull(resp.Body, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		// The range ran past the end of the file
+		return n, io.EOF
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to read range response: %w", err)
+	}
+	return n, nil
+}
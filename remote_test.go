@@ -0,0 +1,65 @@
+package tarix
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHTTPReaderAt(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "remote a", "b.txt": "remote b"})
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := os.Open(tarFilePath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		http.ServeContent(w, r, "archive.tar", time.Time{}, f)
+	}))
+	defer server.Close()
+
+	r := NewHTTPReaderAt(server.URL)
+	r.Timeout = 5 * time.Second
+	data, err := ExtractBytesFromReaderAt(index, r, "b.txt")
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if string(data) != "remote b" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+}
+
+func TestHTTPReaderAtTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	r := NewHTTPReaderAt(server.URL)
+	r.Timeout = 50 * time.Millisecond
+	if _, err := r.ReadAt(make([]byte, 10), 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+
+	// A context deadline applies too
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := NewHTTPReaderAt(server.URL).WithContext(ctx).ReadAt(make([]byte, 10), 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a timeout from the context, got %v", err)
+	}
+}
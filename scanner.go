@@ -0,0 +1,295 @@
+package tarix
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IndexScanner reads the entries of a CSV index one row at a time, without
+// holding them all in memory like ReadTarIndex, for passes over every entry
+// of huge indexes. Entries come in the order of the file: by key, or by
+// offset for indexes written with IndexOptions.ByOffset. The checksum of the
+// index is verified when the last row is read, so an index that fails it
+// ends the scan with ErrIndexCorrupt after its entries were yielded.
+//
+//	sc, err := tarix.OpenIndexScanner(indexPath, tarix.LoadOptions{})
+//	...
+//	defer sc.Close()
+//	for sc.Scan() {
+//		key, fi := sc.Entry()
+//		...
+//	}
+//	if err := sc.Err(); err != nil {
+//		...
+//	}
+type IndexScanner struct {
+	file    io.Closer
+	reader  *csv.Reader
+	opts    LoadOptions
+	header  []string
+	columns indexColumns
+
+	checksum         *indexChecksum
+	checksumVerified bool
+	// version is the format version the index declares, 0 if none
+	version  int
+	prefixes []string
+	// lenient accepts rows after the checksum and skips entries starting
+	// past the end of the archive, counting them in pastEnd, for CompactIndex
+	lenient bool
+	pastEnd int
+
+	// index holds the metadata read so far, and no entries
+	index *TarIndex
+	key   string
+	entry FileIndex
+	err   error
+}
+
+// OpenIndexScanner opens the index at indexPath for scanning. opts.Sorted
+// doesn't apply.
+func OpenIndexScanner(indexPath string, opts LoadOptions) (*IndexScanner, error) {
+	file, err := os.Open(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index file: %w", err)
+	}
+	sc, err := NewIndexScanner(file, opts)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	sc.file = file
+	return sc, nil
+}
+
+// NewIndexScanner reads an index from r, starting with its header row
+func NewIndexScanner(r io.Reader, opts LoadOptions) (*IndexScanner, error) {
+	br := bufio.NewReader(r)
+	skipBOM(br)
+	delimiter := opts.Delimiter
+	if delimiter == 0 {
+		delimiter = detectDelimiter(br)
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = delimiter
+	// The checksum trailer has fewer fields than the other rows
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	// Read the header, which determines the number of columns
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	header = append([]string{}, header...)
+	columns, err := parseColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &IndexScanner{
+		reader:   reader,
+		opts:     opts,
+		header:   header,
+		columns:  columns,
+		checksum: newIndexChecksum(),
+		index:    &TarIndex{},
+	}
+	sc.checksum.add(header)
+	return sc, nil
+}
+
+// Scan advances to the next entry, reporting whether there is one. It
+// returns false at the end of the index or on an error, see Err.
+func (sc *IndexScanner) Scan() bool {
+	if sc.err != nil {
+		return false
+	}
+	for {
+		record, err := sc.reader.Read()
+		if err == io.EOF {
+			// Removing the trailer mustn't turn off the checksum
+			if sc.version >= 1 && !sc.checksumVerified && !sc.lenient && !sc.opts.SkipChecksum {
+				sc.err = fmt.Errorf("%w: missing checksum", ErrIndexCorrupt)
+			}
+			return false
+		}
+		if err != nil {
+			sc.err = fmt.Errorf("failed to read CSV record: %w", err)
+			return false
+		}
+		entry, err := sc.parseRecord(record)
+		if err != nil {
+			sc.err = err
+			return false
+		}
+		if entry {
+			return true
+		}
+	}
+}
+
+// Entry returns the key and entry Scan advanced to
+func (sc *IndexScanner) Entry() (string, FileIndex) {
+	return sc.key, sc.entry
+}
+
+// Err returns the error that ended the scan, nil at the end of the index
+func (sc *IndexScanner) Err() error {
+	return sc.err
+}
+
+// Index returns the metadata of the index, like its Root and KeyScheme, with
+// no entries. The metadata rows precede the entries, so it is complete once
+// Scan returned the first entry. Key works on it as on the full index.
+func (sc *IndexScanner) Index() (*TarIndex, error) {
+	// Lookups must key paths the way the index was built
+	keyScheme, err := lookupKeyScheme(sc.index.KeyScheme)
+	if err != nil {
+		return nil, err
+	}
+	sc.index.keys = keyScheme.Func
+	return sc.index, nil
+}
+
+// Close closes the index file opened by OpenIndexScanner
+func (sc *IndexScanner) Close() error {
+	if sc.file == nil {
+		return nil
+	}
+	return sc.file.Close()
+}
+
+// parseRecord reads a row of the index into the scanner, reporting whether
+// it was an entry rather than metadata
+func (sc *IndexScanner) parseRecord(record []string) (bool, error) {
+	index := sc.index
+	if sc.checksumVerified {
+		return false, fmt.Errorf("%w: rows after checksum", ErrIndexCorrupt)
+	}
+
+	if len(record) == 2 && record[0] == checksumRowKey {
+		if sc.lenient {
+			return false, nil
+		}
+		if !sc.opts.SkipChecksum && record[1] != sc.checksum.sum() {
+			return false, fmt.Errorf("%w: checksum mismatch", ErrIndexCorrupt)
+		}
+		sc.checksumVerified = true
+		return false, nil
+	}
+
+	var err error
+	// Metadata rows hold a name and a value; unknown names are ignored
+	if len(record) == 2 && strings.HasPrefix(record[0], "#") {
+		sc.checksum.add(record)
+		if err := unescapeFields(record); err != nil {
+			return false, err
+		}
+		switch record[0] {
+		case versionRowKey:
+			if sc.version, err = strconv.Atoi(record[1]); err != nil {
+				return false, fmt.Errorf("invalid index version: %w", err)
+			}
+		case formatRowKey:
+			index.Format = parseFormat(record[1])
+		case keySchemeRowKey:
+			index.KeyScheme = record[1]
+		case rootRowKey:
+			index.Root = record[1]
+		case trailerRowKey:
+			index.Trailer = record[1] == "found"
+		case archiveNameRowKey:
+			index.ArchiveName = record[1]
+		case archiveSizeRowKey:
+			if index.ArchiveSize, err = parseInt64(record[1]); err != nil {
+				return false, fmt.Errorf("invalid archive size: %w", err)
+			}
+		case occurrencesRowKey:
+			index.Occurrences = record[1] == "numbered"
+		case normalizationRowKey:
+			if index.Normalize, err = ParseNormForm(record[1]); err != nil {
+				return false, err
+			}
+		case prefixRowKey:
+			sc.prefixes = append(sc.prefixes, record[1])
+		case entryCountRowKey:
+			if index.EntryCount, err = parseInt64(record[1]); err != nil {
+				return false, fmt.Errorf("invalid entry count: %w", err)
+			}
+		}
+		return false, nil
+	}
+
+	// Every row has the columns of the header
+	if len(record) != len(sc.header) {
+		line, _ := sc.reader.FieldPos(0)
+		return false, fmt.Errorf("unexpected CSV format on line %d: expected %d fields, got %d", line, len(sc.header), len(record))
+	}
+	sc.checksum.add(record)
+	if err := unescapeFields(record); err != nil {
+		return false, err
+	}
+
+	columns := sc.columns
+	start, err := parseInt64(columns.get(record, "start"))
+	if err != nil {
+		return false, fmt.Errorf("invalid start value: %w", err)
+	}
+
+	size, err := parseInt64(columns.get(record, "size"))
+	if err != nil {
+		return false, fmt.Errorf("invalid size value: %w", err)
+	}
+
+	key := columns.get(record, "key")
+	if sc.lenient && index.ArchiveSize > 0 && start > index.ArchiveSize-headerSize {
+		sc.pastEnd++
+		return false, nil
+	}
+	if err := validateEntry(key, start, size, index.ArchiveSize); err != nil {
+		return false, err
+	}
+	fileInfo := FileIndex{
+		Start:       start,
+		Size:        size,
+		Path:        columns.get(record, "path"),
+		ContentHash: columns.get(record, "checksum"),
+		MIMEType:    columns.get(record, "mime"),
+	}
+	if fileInfo.Path, err = joinPrefix(sc.prefixes, columns.get(record, "prefix"), fileInfo.Path); err != nil {
+		return false, err
+	}
+	if compressed := columns.get(record, "compressed"); compressed != "" {
+		if fileInfo.Compressed, err = strconv.ParseBool(compressed); err != nil {
+			return false, fmt.Errorf("invalid compressed value: %w", err)
+		}
+	}
+	if nonExtractable := columns.get(record, "nonextractable"); nonExtractable != "" {
+		if fileInfo.NonExtractable, err = strconv.ParseBool(nonExtractable); err != nil {
+			return false, fmt.Errorf("invalid nonextractable value: %w", err)
+		}
+	}
+	if sparse := columns.get(record, "sparse"); sparse != "" {
+		if fileInfo.Sparse, err = strconv.ParseBool(sparse); err != nil {
+			return false, fmt.Errorf("invalid sparse value: %w", err)
+		}
+	}
+	if headers := columns.get(record, "headers"); headers != "" {
+		if fileInfo.HeaderBlocks, err = strconv.Atoi(headers); err != nil {
+			return false, fmt.Errorf("invalid headers value: %w", err)
+		}
+		if fileInfo.HeaderBlocks < 0 || int64(fileInfo.HeaderBlocks) > start/headerSize {
+			return false, fmt.Errorf("%w: header blocks of %s start before the archive", ErrIndexCorrupt, key)
+		}
+	}
+
+	sc.key, sc.entry = key, fileInfo
+	return true, nil
+}
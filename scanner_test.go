@@ -0,0 +1,115 @@
+package tarix
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexScanner(t *testing.T) {
+	tarFilePath, _ := createIndexedTar(t, map[string]string{
+		"a.txt": "alpha",
+		"b.txt": "beta",
+		"c.txt": "gamma",
+	})
+	indexPath := filepath.Join(t.TempDir(), "index.csv")
+	opts := IndexOptions{StorePaths: true, PathPrefixes: true, ByOffset: true, Root: ".", Delimiter: ';'}
+	if err := CreateTarIndexWithOptions(tarFilePath, indexPath, opts); err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	sc, err := OpenIndexScanner(indexPath, LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open scanner: %v", err)
+	}
+	defer sc.Close()
+	var lastStart int64 = -1
+	scanned := 0
+	for sc.Scan() {
+		key, fi := sc.Entry()
+		if want, ok := index.Get(key); !ok || want != fi {
+			t.Errorf("Scanned %s %+v, index has %+v", key, fi, want)
+		}
+		// The index was written by offset
+		if fi.Start <= lastStart {
+			t.Errorf("Entry %s at %d after one at %d", fi.Path, fi.Start, lastStart)
+		}
+		lastStart = fi.Start
+		scanned++
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if scanned != index.Len() {
+		t.Errorf("Scanned %d entries, index has %d", scanned, index.Len())
+	}
+	meta, err := sc.Index()
+	if err != nil {
+		t.Fatalf("Failed to get metadata: %v", err)
+	}
+	if meta.ArchiveSize != index.ArchiveSize || meta.Len() != 0 || meta.Key("b.txt") != index.Key("b.txt") {
+		t.Errorf("Unexpected metadata %+v", meta)
+	}
+}
+
+func TestIndexScannerChecksum(t *testing.T) {
+	_, indexPath := createIndexedTar(t, map[string]string{"a.txt": "alpha", "b.txt": "beta"})
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	// Change a size so the rows no longer match the checksum trailer
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if fields := strings.Split(line, ","); len(fields) == 3 && !strings.HasPrefix(line, "#") && fields[0] != "key" {
+			fields[2] = "1"
+			lines[i] = strings.Join(fields, ",")
+			break
+		}
+	}
+	if err := os.WriteFile(indexPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	sc, err := NewIndexScanner(strings.NewReader(strings.Join(lines, "\n")), LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open scanner: %v", err)
+	}
+	scanned := 0
+	for sc.Scan() {
+		scanned++
+	}
+	// The entries come before the checksum fails
+	if scanned != 2 || !errors.Is(sc.Err(), ErrIndexCorrupt) {
+		t.Errorf("Scanned %d entries with error %v, want 2 and ErrIndexCorrupt", scanned, sc.Err())
+	}
+	if sc.Scan() {
+		t.Error("Expected no entries after an error")
+	}
+	if _, err := ReadTarIndex(indexPath); !errors.Is(err, ErrIndexCorrupt) {
+		t.Errorf("Expected ReadTarIndex to fail the checksum too, got %v", err)
+	}
+
+	// Without the trailer the changed rows are caught all the same
+	trailerless := strings.Join(lines[:len(lines)-2], "\n") + "\n"
+	if strings.Contains(trailerless, checksumRowKey) {
+		t.Fatalf("Expected the trailer on the last line, got %q", lines[len(lines)-2])
+	}
+	sc, err = NewIndexScanner(strings.NewReader(trailerless), LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open scanner: %v", err)
+	}
+	scanned = 0
+	for sc.Scan() {
+		scanned++
+	}
+	if scanned != 2 || !errors.Is(sc.Err(), ErrIndexCorrupt) {
+		t.Errorf("Scanned %d entries with error %v, want 2 and ErrIndexCorrupt", scanned, sc.Err())
+	}
+}
@@ -0,0 +1,32 @@
+package tarix
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"0":       0,
+		"512":     512,
+		"512B":    512,
+		"10KB":    10000,
+		"10kb":    10000,
+		"10K":     10240,
+		"10MB":    10000000,
+		"1MiB":    1 << 20,
+		"1GiB":    1 << 30,
+		"1.5GB":   1500000000,
+		"2 TiB":   2 << 40,
+		" 100mb ": 100000000,
+	}
+	for input, expected := range tests {
+		size, err := ParseSize(input)
+		if err != nil || size != expected {
+			t.Errorf("ParseSize(%q) = %d, %v; expected %d", input, size, err, expected)
+		}
+	}
+
+	for _, input := range []string{"", "MB", "10XB", "-1", "1.2.3KB", "99999999TB"} {
+		if size, err := ParseSize(input); err == nil {
+			t.Errorf("Expected an error for %q, got %d", input, size)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package tarix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Source is what a TarixHandle reads the TAR from: a local file by default,
+// or anything else readable at offsets, like an HTTPReaderAt or a
+// bytes.Reader wrapped with NewReaderSource
+type Source interface {
+	io.ReaderAt
+	io.Closer
+	// Size is the size of the TAR in bytes
+	Size() int64
+}
+
+// FileSource is a Source reading a local file
+type FileSource struct {
+	*os.File
+	size int64
+}
+
+// ErrNotSeekable is returned for TARs that can only be read in order, like
+// pipes, as reading files at their indexed offsets needs random access
+var ErrNotSeekable = errors.New("tar is not seekable")
+
+// NewFileSource returns a Source reading f, which it closes on Close. f must
+// be seekable, not a pipe or socket, or ErrNotSeekable is returned.
+func NewFileSource(f *os.File) (*FileSource, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if err := checkSeekable(f, info); err != nil {
+		return nil, err
+	}
+	return &FileSource{File: f, size: info.Size()}, nil
+}
+
+// checkSeekable fails with ErrNotSeekable if f, described by info, can't be
+// read at arbitrary offsets
+func checkSeekable(f *os.File, info os.FileInfo) error {
+	if info.Mode()&(os.ModeNamedPipe|os.ModeSocket|os.ModeCharDevice) == 0 && seekable(f) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is a stream; save it to a file, or read it with ExtractFromTarDirect, which needs no index", ErrNotSeekable, f.Name())
+}
+
+// Size returns the size of the file when the source was created
+func (s *FileSource) Size() int64 {
+	return s.size
+}
+
+// readerSource is a Source over an io.ReaderAt with nothing to close
+type readerSource struct {
+	io.ReaderAt
+	size int64
+}
+
+// NewReaderSource returns a Source reading the first size bytes of r. Its
+// Close does nothing; r is closed by the caller if it needs closing.
+func NewReaderSource(r io.ReaderAt, size int64) Source {
+	return readerSource{ReaderAt: r, size: size}
+}
+
+func (s readerSource) Size() int64 {
+	return s.size
+}
+
+func (readerSource) Close() error {
+	return nil
+}
+
+// mmapSource is a Source reading a file mapped into memory with mmapFile
+type mmapSource struct {
+	*bytes.Reader
+	data []byte
+	file *os.File
+}
+
+// newMmapSource maps f into memory; on success f is closed by Close
+func newMmapSource(f *os.File) (*mmapSource, error) {
+	data, err := mmapFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return &mmapSource{Reader: bytes.NewReader(data), data: data, file: f}, nil
+}
+
+// Close unmaps and closes the file
+func (s *mmapSource) Close() error {
+	if err := munmap(s.data); err != nil {
+		s.file.Close()
+		return fmt.Errorf("failed to unmap tar file: %w", err)
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,81 @@
+package tarix
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleFromSource(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "aaa", "b.txt": "bbbbb"})
+	data, err := os.ReadFile(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	source := NewReaderSource(bytes.NewReader(data), int64(len(data)))
+	if source.Size() != int64(len(data)) {
+		t.Errorf("Expected size %d, got %d", len(data), source.Size())
+	}
+	th := NewTarixHandleFromSource(source, index)
+	th.Verify = true
+	defer th.Close()
+
+	if got, err := th.ExtractBytesOfFile("b.txt"); err != nil || string(got) != "bbbbb" {
+		t.Errorf("Expected bbbbb, got %q, %v", got, err)
+	}
+	var buf bytes.Buffer
+	if _, err := th.WriteFileTo("a.txt", &buf); err != nil || buf.String() != "aaa" {
+		t.Errorf("Expected aaa, got %q, %v", buf.String(), err)
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	source, err := NewFileSource(f)
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	if source.Size() != 5 {
+		t.Errorf("Expected size 5, got %d", source.Size())
+	}
+	if data, err := io.ReadAll(io.NewSectionReader(source, 1, 3)); err != nil || string(data) != "ell" {
+		t.Errorf("Expected ell, got %q, %v", data, err)
+	}
+	if err := source.Close(); err != nil {
+		t.Errorf("Failed to close source: %v", err)
+	}
+	if _, err := f.Stat(); err == nil {
+		t.Error("Expected Close to close the file")
+	}
+}
+
+func TestFileSourceNotSeekable(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if _, err := NewFileSource(r); !errors.Is(err, ErrNotSeekable) {
+		t.Errorf("Expected ErrNotSeekable for a pipe, got %v", err)
+	}
+	if _, err := ExtractBytesFromTarWithIndex(&TarIndex{Files: map[string]FileIndex{}}, r, "a.txt"); !errors.Is(err, ErrNotSeekable) {
+		t.Errorf("Expected ErrNotSeekable for a pipe, got %v", err)
+	}
+}
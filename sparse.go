@@ -0,0 +1,98 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+)
+
+// sparseBlockSize is the granularity at which runs of zeros become holes,
+// the block size of most filesystems
+const sparseBlockSize = 4096
+
+// sparseFile is an output that holes can be left in, like an *os.File
+type sparseFile interface {
+	io.WriteSeeker
+	Truncate(size int64) error
+}
+
+// seekable reports whether s can really seek; pipes and terminals can't
+func seekable(s io.Seeker) bool {
+	_, err := s.Seek(0, io.SeekCurrent)
+	return err == nil
+}
+
+// readSparse reads the sparse entry of fileInfo from the TAR in r through its
+// headers, with its holes filled with zeros
+func readSparse(r io.ReaderAt, fileInfo FileIndex) ([]byte, error) {
+	offset := fileInfo.Start - int64(fileInfo.HeaderBlocks)*headerSize
+	tr := tar.NewReader(io.NewSectionReader(r, offset, math.MaxInt64-offset))
+	if _, err := tr.Next(); err != nil {
+		return nil, fmt.Errorf("%w: no valid header at offset %d: %v", ErrIndexStale, fileInfo.Start, err)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file data: %w", err)
+	}
+	return data, nil
+}
+
+// openEntry returns a reader of the contents of the entry of key, read
+// through its headers if it is a sparse one
+func (th *TarixHandle) openEntry(key string, fileInfo FileIndex) (io.Reader, error) {
+	if !fileInfo.Sparse {
+		return io.NewSectionReader(th.Source, fileInfo.DataOffset(), fileInfo.Size), nil
+	}
+	_, tr, err := th.readEntry(key, fileInfo)
+	if err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// copySparse copies src to dst, seeking over blocks of zeros instead of
+// writing them so that they become holes on filesystems supporting them.
+// tar.Reader fills the holes of sparse entries with zeros, so the holes of
+// the original file are restored, along with any other zero blocks.
+func copySparse(dst sparseFile, src io.Reader, bufferSize int) (int64, error) {
+	if bufferSize < sparseBlockSize {
+		bufferSize = defaultBufferSize
+	}
+	buf := make([]byte, bufferSize/sparseBlockSize*sparseBlockSize)
+	zeros := make([]byte, sparseBlockSize)
+	start, err := dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get output position: %w", err)
+	}
+
+	var n int64
+	for {
+		nr, err := io.ReadFull(src, buf)
+		for off := 0; off < nr; off += sparseBlockSize {
+			block := buf[off:min(off+sparseBlockSize, nr)]
+			if bytes.Equal(block, zeros[:len(block)]) {
+				if _, err := dst.Seek(int64(len(block)), io.SeekCurrent); err != nil {
+					return n, fmt.Errorf("failed to skip hole: %w", err)
+				}
+			} else if _, err := dst.Write(block); err != nil {
+				return n, err
+			}
+			n += int64(len(block))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+
+	// Seeking past the end doesn't extend the file, so a trailing hole needs
+	// the size set explicitly
+	if err := dst.Truncate(start + n); err != nil {
+		return n, fmt.Errorf("failed to set file size: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,142 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// memSparseFile is an in-memory sparseFile recording the bytes written
+type memSparseFile struct {
+	data    []byte
+	pos     int64
+	written int64
+}
+
+func (f *memSparseFile) Write(p []byte) (int, error) {
+	if end := f.pos + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	copy(f.data[f.pos:], p)
+	f.pos += int64(len(p))
+	f.written += int64(len(p))
+	return len(p), nil
+}
+
+func (f *memSparseFile) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekCurrent {
+		return 0, errors.New("unsupported whence")
+	}
+	f.pos += offset
+	return f.pos, nil
+}
+
+func (f *memSparseFile) Truncate(size int64) error {
+	if size > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, size-int64(len(f.data)))...)
+	}
+	f.data = f.data[:size]
+	return nil
+}
+
+func TestCopySparse(t *testing.T) {
+	// Data, a hole, data not ending on a block boundary, then a trailing hole
+	content := make([]byte, 5*sparseBlockSize+100)
+	copy(content, "head")
+	copy(content[3*sparseBlockSize:], "middle")
+
+	f := &memSparseFile{}
+	n, err := copySparse(f, bytes.NewReader(content), 0)
+	if err != nil {
+		t.Fatalf("Failed to copy: %v", err)
+	}
+	if n != int64(len(content)) {
+		t.Errorf("Expected %d bytes copied, got %d", len(content), n)
+	}
+	if !bytes.Equal(f.data, content) {
+		t.Errorf("Expected the output to match the input")
+	}
+	if f.written != 2*sparseBlockSize {
+		t.Errorf("Expected only the 2 data blocks to be written, got %d bytes", f.written)
+	}
+}
+
+func TestExtractSparseDense(t *testing.T) {
+	// See TestCreateTarIndexSparse for the contents of sparse-gnu.tar
+	tarFilePath := filepath.Join("testdata", "sparse-gnu.tar")
+	tarFile, err := os.Open(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open TAR: %v", err)
+	}
+	defer tarFile.Close()
+	var want []byte
+	tr := tar.NewReader(tarFile)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			t.Fatalf("Failed to find sparse.img: %v", err)
+		}
+		if header.Name == "sparse.img" {
+			want, _ = io.ReadAll(tr)
+			break
+		}
+	}
+	if len(want) != 1024*1024 {
+		t.Fatalf("Expected 1 MiB of sparse.img, got %d bytes", len(want))
+	}
+
+	tarIndexPath := filepath.Join(t.TempDir(), "sparse-gnu.tar.index")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	outputPath := filepath.Join(t.TempDir(), "sparse.img")
+	if err := ExtractFileFromTar(tarFilePath, tarIndexPath, "sparse.img", outputPath); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if data, err := os.ReadFile(outputPath); err != nil || !bytes.Equal(data, want) {
+		t.Errorf("Unexpected content of the extracted file: %v", err)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	if fileInfo, _, err := th.Locate("sparse.img"); err != nil || !fileInfo.Sparse {
+		t.Errorf("Expected sparse.img to be marked sparse: %+v, %v", fileInfo, err)
+	}
+	if data, err := th.ExtractBytesOfFile("sparse.img"); err != nil || !bytes.Equal(data, want) {
+		t.Errorf("Unexpected content from ExtractBytesOfFile: %v", err)
+	}
+	var buf bytes.Buffer
+	if n, err := th.WriteFileTo("sparse.img", &buf); err != nil || n != int64(len(want)) || !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Unexpected content from WriteFileTo: %d bytes, %v", n, err)
+	}
+	if rc, err := th.OpenFileDecompressed("sparse.img"); err != nil {
+		t.Errorf("Failed to open: %v", err)
+	} else if data, err := io.ReadAll(rc); err != nil || !bytes.Equal(data, want) {
+		t.Errorf("Unexpected content from OpenFileDecompressed: %v", err)
+	}
+	if _, err := th.SectionReaderOf("sparse.img"); err == nil {
+		t.Error("Expected SectionReaderOf to fail for a sparse file")
+	}
+	if data, err := ExtractBytesFromReaderAt(th.Index, tarFile, "sparse.img"); err != nil || !bytes.Equal(data, want) {
+		t.Errorf("Unexpected content from ExtractBytesFromReaderAt: %v", err)
+	}
+
+	// Served whole, also when a range is asked for
+	req := httptest.NewRequest(http.MethodGet, "/sparse.img", nil)
+	req.Header.Set("Range", "bytes=0-9")
+	rec := httptest.NewRecorder()
+	Handler(th).ServeHTTP(rec, req)
+	resp := rec.Result()
+	if data, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || resp.ContentLength != int64(len(want)) || !bytes.Equal(data, want) {
+		t.Errorf("Unexpected response for a sparse file: %d, %d bytes", resp.StatusCode, len(data))
+	}
+}
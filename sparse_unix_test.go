@@ -0,0 +1,56 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tarix
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestExtractRestoreSparse(t *testing.T) {
+	// See TestCreateTarIndexSparse for the contents of sparse-gnu.tar
+	tarFilePath := filepath.Join("testdata", "sparse-gnu.tar")
+	dir := t.TempDir()
+	tarIndexPath := filepath.Join(dir, "sparse-gnu.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	denseDir := filepath.Join(dir, "dense")
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, denseDir, ExtractOptions{}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	dense, err := os.ReadFile(filepath.Join(denseDir, "sparse.img"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+
+	sparseDir := filepath.Join(dir, "sparse")
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, sparseDir, ExtractOptions{RestoreSparse: true}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	singlePath := filepath.Join(dir, "single.img")
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "sparse.img", singlePath, ExtractOptions{RestoreSparse: true}); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+
+	for _, path := range []string{filepath.Join(sparseDir, "sparse.img"), singlePath} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if !bytes.Equal(data, dense) {
+			t.Errorf("Expected %s to match the dense extraction", path)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat file: %v", err)
+		}
+		if allocated := info.Sys().(*syscall.Stat_t).Blocks * 512; allocated >= info.Size() {
+			t.Errorf("Expected %s to have holes, %d bytes allocated of %d", path, allocated, info.Size())
+		}
+	}
+}
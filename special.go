@@ -0,0 +1,57 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// ErrSpecialFile is returned when an output path is an existing FIFO, device
+// or socket and ExtractOptions.NoSpecialFiles is set
+var ErrSpecialFile = errors.New("output is not a regular file")
+
+// OpenFS is an FS that can open an existing file for writing as it is,
+// without creating or truncating it, as FIFOs and devices need
+type OpenFS interface {
+	FS
+	OpenWriter(name string) (io.WriteCloser, error)
+}
+
+func (OSFS) OpenWriter(name string) (io.WriteCloser, error) {
+	return os.OpenFile(name, os.O_WRONLY, 0)
+}
+
+// isSpecial reports whether mode is that of a FIFO, device or socket
+func isSpecial(mode fs.FileMode) bool {
+	return mode&(fs.ModeNamedPipe|fs.ModeDevice|fs.ModeCharDevice|fs.ModeSocket) != 0
+}
+
+// createOutput opens outputPath in fsys for writing file data. An existing
+// FIFO or device is opened for writing as it is, so data can be piped to
+// another process, and reported as special; anything else is created or
+// truncated. Special outputs need fsys to implement StatFS and OpenFS to be
+// recognized, and fail with ErrSpecialFile if o.NoSpecialFiles is set.
+func (o ExtractOptions) createOutput(fsys FS, outputPath string) (io.WriteCloser, bool, error) {
+	if statFS, ok := fsys.(StatFS); ok {
+		if info, err := statFS.Stat(outputPath); err == nil && isSpecial(info.Mode()) {
+			if o.NoSpecialFiles {
+				return nil, false, fmt.Errorf("output file %s: %w", outputPath, ErrSpecialFile)
+			}
+			if openFS, ok := fsys.(OpenFS); ok {
+				w, err := openFS.OpenWriter(outputPath)
+				if err != nil {
+					return nil, false, fmt.Errorf("failed to open output file: %w", err)
+				}
+				return w, true, nil
+			}
+		}
+	}
+
+	w, err := fsys.Create(outputPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create output file: %w", err)
+	}
+	return w, false, nil
+}
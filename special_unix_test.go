@@ -0,0 +1,48 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tarix
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestExtractToFIFO(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "piped content"})
+	fifoPath := filepath.Join(t.TempDir(), "fifo")
+	if err := syscall.Mkfifo(fifoPath, 0600); err != nil {
+		t.Skipf("Failed to create FIFO: %v", err)
+	}
+
+	// Opening the FIFO for writing blocks until it has a reader
+	received := make(chan string)
+	go func() {
+		f, err := os.Open(fifoPath)
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer f.Close()
+		data, _ := io.ReadAll(f)
+		received <- string(data)
+	}()
+
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "a.txt", fifoPath, ExtractOptions{}); err != nil {
+		t.Fatalf("Failed to extract to FIFO: %v", err)
+	}
+	if data := <-received; data != "piped content" {
+		t.Errorf("Expected the file through the FIFO, got %q", data)
+	}
+	if info, err := os.Lstat(fifoPath); err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("Expected the FIFO to be kept, got %v, %v", info, err)
+	}
+
+	err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "a.txt", fifoPath, ExtractOptions{NoSpecialFiles: true})
+	if !errors.Is(err, ErrSpecialFile) {
+		t.Errorf("Expected ErrSpecialFile, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package tarix
+
+import (
+	"archive/tar"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IndexStats summarizes an index
+type IndexStats struct {
+	Format      tar.Format // Format of the TAR, tar.FormatUnknown if not recorded
+	Files       int        // Number of indexed files
+	ContentSize int64      // Total size of the indexed files in bytes
+	// Extensions breaks the files with stored paths down by extension,
+	// largest total size first
+	Extensions []ExtensionStats
+}
+
+// ExtensionStats aggregates the files with one extension
+type ExtensionStats struct {
+	Ext       string // Lowercase extension including the dot, "" for files without one
+	Count     int
+	TotalSize int64
+}
+
+// Stats summarizes the index without touching the TAR
+func (ti *TarIndex) Stats() IndexStats {
+	stats := IndexStats{Format: ti.Format, Files: ti.count()}
+	byExt := map[string]*ExtensionStats{}
+	ti.each(func(_ string, fileInfo FileIndex) {
+		stats.ContentSize += fileInfo.Size
+
+		if fileInfo.Path == "" {
+			return
+		}
+		ext := strings.ToLower(filepath.Ext(fileInfo.Path))
+		es, ok := byExt[ext]
+		if !ok {
+			es = &ExtensionStats{Ext: ext}
+			byExt[ext] = es
+		}
+		es.Count++
+		es.TotalSize += fileInfo.Size
+	})
+
+	for _, es := range byExt {
+		stats.Extensions = append(stats.Extensions, *es)
+	}
+	sort.Slice(stats.Extensions, func(i, j int) bool {
+		a, b := stats.Extensions[i], stats.Extensions[j]
+		if a.TotalSize != b.TotalSize {
+			return a.TotalSize > b.TotalSize
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Ext < b.Ext
+	})
+	return stats
+}
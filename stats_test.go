@@ -0,0 +1,56 @@
+package tarix
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexStatsExtensions(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "stats.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"a.parquet":     "0123456789",
+		"dir/b.PARQUET": "0123456789",
+		"c.txt":         "12",
+		"d.txt":         "34",
+		"e.txt":         "56",
+		"Makefile":      "all:",
+	})
+
+	tarIndexPath := filepath.Join(dir, "stats.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{StorePaths: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+
+	stats := index.Stats()
+	if stats.Files != 6 || stats.ContentSize != 30 {
+		t.Errorf("Expected 6 files of 30 bytes, got %d files of %d bytes", stats.Files, stats.ContentSize)
+	}
+	expected := []ExtensionStats{
+		{Ext: ".parquet", Count: 2, TotalSize: 20},
+		{Ext: ".txt", Count: 3, TotalSize: 6},
+		{Ext: "", Count: 1, TotalSize: 4},
+	}
+	if len(stats.Extensions) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, stats.Extensions)
+	}
+	for i, es := range expected {
+		if stats.Extensions[i] != es {
+			t.Errorf("Expected %v at %d, got %v", es, i, stats.Extensions[i])
+		}
+	}
+
+	// Without stored paths there is nothing to break down
+	_, hashedIndexPath := createIndexedTar(t, map[string]string{"x.txt": "x"})
+	hashed, err := ReadTarIndex(hashedIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if exts := hashed.Stats().Extensions; len(exts) != 0 {
+		t.Errorf("Expected no extensions without paths, got %v", exts)
+	}
+}
@@ -0,0 +1,57 @@
+package tarix
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"math/rand"
+	"path"
+)
+
+// TarShape describes a synthetic TAR written by WriteSyntheticTar
+type TarShape struct {
+	Files    int   // Number of regular files
+	FileSize int64 // Size of each file in bytes
+	// FilesPerDir spreads the files over directories holding this many each;
+	// 0 puts them all at the top level
+	FilesPerDir int
+	// Seed seeds the pseudo-random file contents, so the same shape and seed
+	// always produce the same archive
+	Seed int64
+}
+
+// WriteSyntheticTar writes a TAR of the given shape to w and returns the paths
+// of its files in archive order. It is meant for tests and benchmarks.
+func WriteSyntheticTar(w io.Writer, shape TarShape) ([]string, error) {
+	rnd := rand.New(rand.NewSource(shape.Seed))
+	tw := tar.NewWriter(w)
+	data := make([]byte, shape.FileSize)
+	paths := make([]string, 0, shape.Files)
+
+	for i := 0; i < shape.Files; i++ {
+		dir := ""
+		if shape.FilesPerDir > 0 {
+			dir = fmt.Sprintf("dir%05d", i/shape.FilesPerDir)
+			if i%shape.FilesPerDir == 0 {
+				if err := tw.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+					return nil, fmt.Errorf("failed to write header: %w", err)
+				}
+			}
+		}
+
+		name := path.Join(dir, fmt.Sprintf("file%06d.bin", i))
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: shape.FileSize}); err != nil {
+			return nil, fmt.Errorf("failed to write header: %w", err)
+		}
+		rnd.Read(data)
+		if _, err := tw.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write file data: %w", err)
+		}
+		paths = append(paths, name)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close tar writer: %w", err)
+	}
+	return paths, nil
+}
@@ -0,0 +1,55 @@
+package tarix
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSyntheticTar(t *testing.T) {
+	shape := TarShape{Files: 25, FileSize: 700, FilesPerDir: 10, Seed: 1}
+
+	var first, second bytes.Buffer
+	paths, err := WriteSyntheticTar(&first, shape)
+	if err != nil {
+		t.Fatalf("Failed to write synthetic TAR: %v", err)
+	}
+	if _, err := WriteSyntheticTar(&second, shape); err != nil {
+		t.Fatalf("Failed to write synthetic TAR: %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("Expected the same shape and seed to produce the same archive")
+	}
+	if len(paths) != shape.Files || paths[0] != "dir00000/file000000.bin" || paths[24] != "dir00002/file000024.bin" {
+		t.Errorf("Unexpected paths: %v", paths)
+	}
+
+	tarFilePath := filepath.Join(t.TempDir(), "synthetic.tar")
+	if err := os.WriteFile(tarFilePath, first.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write TAR: %v", err)
+	}
+	tarIndexPath := tarFilePath + ".index.json"
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	// 25 files and a directory entry for each 10 of them
+	if len(th.Index.Files) != shape.Files || th.Index.EntryCount != int64(shape.Files+3) {
+		t.Errorf("Expected %d files in %d entries, got %d in %d", shape.Files, shape.Files+3, len(th.Index.Files), th.Index.EntryCount)
+	}
+	for _, p := range paths {
+		data, err := th.ExtractBytesOfFile(p)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", p, err)
+		}
+		if int64(len(data)) != shape.FileSize {
+			t.Errorf("Expected %d bytes in %s, got %d", shape.FileSize, p, len(data))
+		}
+	}
+}
@@ -2,28 +2,312 @@ package tarix
 
 import (
 	"archive/tar"
+	"bufio"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/csv"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"log/slog"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 const HashLen = 16
 
 var headerSize = int64(512)
 
+// padToBlock rounds n up to a whole number of 512-byte TAR blocks
+func padToBlock(n int64) int64 {
+	return (n + 511) &^ 511
+}
+
 func hashFilePath(filePath string) string {
 	h := md5.New() // or use sha256.New() for stronger hashing
 	h.Write([]byte(filePath))
 	return hex.EncodeToString(h.Sum(nil))[:HashLen]
 }
 
+// rootRowKey starts the index row recording the IndexOptions.Root
+const rootRowKey = "#root"
+
+// versionRowKey starts the first metadata row, declaring the version of the
+// index format. Indexes of version 1 and later end with a checksum trailer,
+// so readers reject them without one. Older indexes declare no version.
+const (
+	versionRowKey = "#version"
+	indexVersion  = 1
+)
+
+// archiveNameRowKey, archiveSizeRowKey and entryCountRowKey start the index
+// rows recording the base name of the TAR, its size and its number of entries
+const (
+	archiveNameRowKey = "#archive"
+	archiveSizeRowKey = "#size"
+	entryCountRowKey  = "#entries"
+)
+
+// normalizePath turns a path from the archive or from a lookup into the form
+// that gets hashed:
+//   - the path is cleaned, which also drops a leading "./" and trailing slashes
+//   - leading slashes are dropped, as tar does when extracting
+//   - if root is set and the path lies under it, the root prefix is stripped
+//
+// So "./a/b", "a/b", "/a/b" and "a//b/" all normalize to "a/b", and with root
+// "/data" or "data" so do "/data/a/b" and "data/a/b".
+func normalizePath(filePath, root string) string {
+	cleanFilePath := trimLeadingSeparators(filepath.Clean(filePath))
+	root = trimLeadingSeparators(filepath.Clean(root))
+	if root == "." {
+		return cleanFilePath
+	}
+	if rel, ok := strings.CutPrefix(cleanFilePath, root+string(filepath.Separator)); ok {
+		return rel
+	}
+	return cleanFilePath
+}
+
+// trimLeadingSeparators makes a cleaned path relative, turning "/" into "."
+func trimLeadingSeparators(cleanPath string) string {
+	if rel := strings.TrimLeft(cleanPath, string(filepath.Separator)); rel != "" {
+		return rel
+	}
+	return "."
+}
+
+// IndexOptions controls which entries CreateTarIndexWithOptions records
+type IndexOptions struct {
+	// Include lists glob patterns; when non-empty only matching files are indexed
+	Include []string
+	// Exclude lists glob patterns of files to leave out of the index
+	Exclude []string
+	// Progress, if set, is called after each entry with bytes of the archive processed
+	Progress ProgressFunc
+	// Delimiter separates fields in the CSV index (default: comma)
+	Delimiter rune
+	// Root is a leading directory stripped from archive paths before hashing,
+	// so files are looked up relative to it. Filters also see the stripped path.
+	Root string
+	// ContentHash records a SHA-256 of each file's content. This reads all file
+	// data instead of just the headers.
+	ContentHash bool
+	// MIMETypes records the MIME type of each file, sniffed from its first
+	// 512 bytes with http.DetectContentType. Like ContentHash it reads file
+	// data instead of just the headers, though only the start of each file.
+	MIMETypes bool
+	// Dedup computes content hashes and points files with identical content at
+	// the copy with the lowest offset
+	Dedup bool
+	// KeyScheme turns normalized paths into index keys. The zero value uses
+	// MD5KeyScheme; a scheme with only a Name is looked up among the registered
+	// ones. Readers of the index need the scheme registered under the same name.
+	KeyScheme KeyScheme
+	// StorePaths records each file's path (after normalization) in the index
+	// next to its hash, for display and listing
+	StorePaths bool
+	// CheckpointEvery, if positive, appends indexed files to a partial index
+	// next to the index and saves a checkpoint after every CheckpointEvery
+	// files, so an interrupted run can be continued with ResumeTarIndex
+	CheckpointEvery int
+	// HashWorkers bounds how many goroutines compute content hashes. Zero
+	// uses one per CPU; one hashes inline while reading the archive.
+	HashWorkers int
+	// Concatenated continues past end-of-archive trailers, indexing archives
+	// appended after them as with cat a.tar b.tar > c.tar
+	Concatenated bool
+	// SkipBadHeaders continues past malformed headers instead of aborting,
+	// scanning forward block by block for the next valid header, past any
+	// zero blocks in the damaged region. The result is a partial index.
+	SkipBadHeaders bool
+	// OnSkip, if set, is called with the offset of each unreadable region
+	// skipped because of SkipBadHeaders
+	OnSkip func(offset int64, err error)
+	// Log receives informational messages; nil discards them
+	Log io.Writer
+	// Logger, if set, receives the same messages as structured records:
+	// the indexed and skipped files at debug level, the start and end of
+	// the run at info level, and damage to the archive at warn level
+	Logger *slog.Logger
+	// Occurrences keys each file by its path and how many times the path
+	// occurred before it ("a.txt#0", "a.txt#1", ...), so every version of a
+	// file appended to the archive more than once can be read with
+	// ExtractOccurrence. Plain paths are not keys of such an index. It can't
+	// be combined with CheckpointEvery.
+	Occurrences bool
+	// MaxEntries, if positive, aborts indexing with ErrTooManyEntries once the
+	// archive has more entries than this, directories and links included. It
+	// bounds the memory used on untrusted archives.
+	MaxEntries int64
+	// ByOffset writes the rows of the index in archive order, by Start,
+	// instead of by key, for tools reading the CSV directly to extract files
+	// with sequential reads. Readers of the index don't depend on the order.
+	ByOffset bool
+	// PathPrefixes stores the directories shared by several stored paths once,
+	// in a table at the top of the index, and each path as the ID of its
+	// directory and its name. This shrinks indexes of deep trees built with
+	// StorePaths; ReadTarIndex restores the full paths.
+	PathPrefixes bool
+	// Normalize puts paths in a Unicode normalization form before keying
+	// them, and records the form so lookups do the same. With NFC, the
+	// default, or NFD a name typed composed finds a file stored decomposed
+	// and the other way around. NormNone keys paths as they are. Stored
+	// paths keep their original form.
+	Normalize NormForm
+	// Verbose writes "indexed <path> @<start> (<size> bytes)" to Log for each
+	// indexed file, for debugging offsets in odd archives
+	Verbose bool
+}
+
+// ErrTooManyEntries is returned when an archive has more entries than
+// IndexOptions.MaxEntries allows
+var ErrTooManyEntries = errors.New("too many entries")
+
+// matchesAny reports whether filePath matches one of the glob patterns. Patterns
+// containing a slash are matched against the whole path, others against the base name.
+func matchesAny(patterns []string, filePath string) bool {
+	for _, pattern := range patterns {
+		target := path.Base(filePath)
+		if strings.Contains(pattern, "/") {
+			target = filePath
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether filePath passes the Include and Exclude filters
+func (o IndexOptions) included(filePath string) bool {
+	if len(o.Include) > 0 && !matchesAny(o.Include, filePath) {
+		return false
+	}
+	return !matchesAny(o.Exclude, filePath)
+}
+
+func (o IndexOptions) validate() error {
+	if _, err := o.keyScheme(); err != nil {
+		return err
+	}
+	for _, pattern := range append(append([]string{}, o.Include...), o.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	switch o.Delimiter {
+	case '"', '\r', '\n', utf8.RuneError:
+		return fmt.Errorf("invalid delimiter %q", o.Delimiter)
+	}
+	if o.Occurrences && o.CheckpointEvery > 0 {
+		return fmt.Errorf("occurrence keys can't be checkpointed")
+	}
+	if _, err := ParseNormForm(string(o.Normalize)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// hashWorkers returns the number of goroutines to compute content hashes with
+func (o IndexOptions) hashWorkers() int {
+	if o.HashWorkers <= 0 {
+		return runtime.NumCPU()
+	}
+	return o.HashWorkers
+}
+
+// normForm resolves the configured Normalize, NFC by default. Indexes
+// record no form for NormNone, like those of older versions.
+func (o IndexOptions) normForm() NormForm {
+	switch f := NormForm(strings.ToLower(string(o.Normalize))); f {
+	case "":
+		return NFC
+	case NormNone:
+		return ""
+	default:
+		return f
+	}
+}
+
+// keyScheme resolves the configured KeyScheme
+func (o IndexOptions) keyScheme() (KeyScheme, error) {
+	switch {
+	case o.KeyScheme.Func != nil && o.KeyScheme.Name == "":
+		return KeyScheme{}, fmt.Errorf("key scheme needs a name")
+	case o.KeyScheme.Func != nil:
+		return o.KeyScheme, nil
+	default:
+		return lookupKeyScheme(o.KeyScheme.Name)
+	}
+}
+
 // CreateTarIndex creates an index for an existing TAR file
 func CreateTarIndex(tarPath, indexPath string) error {
+	return CreateTarIndexWithOptions(tarPath, indexPath, IndexOptions{Progress: printIndexProgress(os.Stdout), Log: os.Stdout})
+}
+
+// progressInterval is how often progress is printed at most
+const progressInterval = 200 * time.Millisecond
+
+// printIndexProgress returns a ProgressFunc printing the indexing percentage
+// to w. Nothing is printed for empty archives.
+func printIndexProgress(w io.Writer) ProgressFunc {
+	var lastPercent int64 = -1
+	return ThrottleProgress(func(p Progress) {
+		if p.BytesTotal <= 0 {
+			return
+		}
+		percentDone := (p.BytesDone * 100) / p.BytesTotal
+		if percentDone != lastPercent {
+			fmt.Fprintf(w, "\rIndexing: %d%% complete", percentDone)
+			lastPercent = percentDone
+		}
+	}, progressInterval)
+}
+
+// logWriter returns w, or a writer discarding everything when w is nil
+func logWriter(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
+
+// CreateTarIndexWithOptions creates an index for an existing TAR file, honoring opts
+func CreateTarIndexWithOptions(tarPath, indexPath string, opts IndexOptions) error {
+	return createTarIndex(tarPath, indexPath, opts, nil)
+}
+
+// ResumeTarIndex continues an interrupted CreateTarIndexWithOptions from the
+// last checkpoint it saved. opts must be the options of the interrupted run.
+// Files indexed after the checkpoint are indexed again.
+func ResumeTarIndex(tarPath, indexPath string, opts IndexOptions) error {
+	cp, err := readCheckpoint(indexPath)
+	if err != nil {
+		return err
+	}
+	return createTarIndex(tarPath, indexPath, opts, cp)
+}
+
+// createTarIndex indexes the TAR, from the start or, if cp is set, from a checkpoint
+func createTarIndex(tarPath, indexPath string, opts IndexOptions, cp *checkpoint) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if cp != nil && opts.Occurrences {
+		return fmt.Errorf("occurrence keys can't be checkpointed")
+	}
+
 	// Open the TAR file
 	file, err := os.Open(tarPath)
 	if err != nil {
@@ -39,282 +323,1742 @@ func CreateTarIndex(tarPath, indexPath string) error {
 
 	// Create a tar reader
 	tr := tar.NewReader(file)
+	log := slogger(opts.Logger)
 
 	// Create index
+	keyScheme, _ := opts.keyScheme()
 	index := TarIndex{
-		Files: map[string]FileIndex{},
+		Files:       map[string]FileIndex{},
+		Root:        opts.Root,
+		Occurrences: opts.Occurrences,
+		Normalize:   opts.normForm(),
+		keys:        keyScheme.Func,
+	}
+	occurrences := map[string]int{}
+	if keyScheme.Name != MD5KeyScheme.Name {
+		index.KeyScheme = keyScheme.Name
 	}
+	keyFunc := index.keyFunc()
 
 	var currentPos int64 = 0
-	var lastPercent int64 = -1
+	var lastBadPos int64 = -1
+	// multiVolume is set once a volume label or a continued file marks the
+	// archive as a volume of a GNU multi-volume archive
+	multiVolume := false
+	// resyncing is set while looking for a valid header after a bad one
+	resyncing := false
+	var formats tar.Format
+	var entries int64
+
+	var checkpoints *checkpointer
+	if cp != nil && cp.TarSize != fileInfo.Size() {
+		return fmt.Errorf("tar file has size %d, checkpoint was taken of one with size %d", fileInfo.Size(), cp.TarSize)
+	}
+	if cp != nil || opts.CheckpointEvery > 0 {
+		every := opts.CheckpointEvery
+		if every <= 0 {
+			every = defaultCheckpointEvery
+		}
+		checkpoints, err = newCheckpointer(indexPath, every, fileInfo.Size(), cp, &index)
+		if err != nil {
+			return err
+		}
+		defer checkpoints.close()
+	}
+	var hashes *hashPool
+	if workers := opts.hashWorkers(); (opts.ContentHash || opts.Dedup) && workers > 1 {
+		hashes = newHashPool(file, workers)
+		defer hashes.close()
+	}
+
+	// finish completes pending files in archive order, filling in content
+	// hashes and saving them to the checkpoint. Unless wait is set, it stops
+	// at the first hash that isn't ready while few files are pending.
+	var pending []pendingFile
+	finish := func(wait bool) error {
+		for len(pending) > 0 {
+			p := pending[0]
+			if p.hash != nil {
+				var result hashResult
+				if wait || len(pending) > hashes.limit {
+					result = <-p.hash
+				} else {
+					select {
+					case result = <-p.hash:
+					default:
+						return nil
+					}
+				}
+				if result.err != nil {
+					return result.err
+				}
+				fileIndex := index.Files[p.key]
+				fileIndex.ContentHash = result.sum
+				index.Files[p.key] = fileIndex
+			}
+			if checkpoints != nil {
+				if err := checkpoints.add(index.Files[p.key], p); err != nil {
+					return err
+				}
+			}
+			pending = pending[1:]
+		}
+		return nil
+	}
+
+	if cp != nil {
+		currentPos = cp.Offset
+		formats = cp.Formats
+		entries = cp.Entries
+		if _, err := file.Seek(currentPos, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek to file position: %w", err)
+		}
+		fmt.Fprintf(logWriter(opts.Log), "Resuming at offset %d with %d files indexed\n", currentPos, len(index.Files))
+		log.Info("resuming index", "tar", tarPath, "offset", currentPos, "files", len(index.Files))
+	}
 
 	// Iterate through the TAR archive
 	for {
 		headerPos := currentPos
 
 		header, err := tr.Next()
+		if err == io.EOF && resyncing {
+			// Zero blocks in a damaged region, as in the data of a file
+			// whose header was unreadable, don't end the archive
+			next, ok, err := nextNonZeroBlock(file, headerPos)
+			if err != nil {
+				return err
+			}
+			if ok {
+				// Report the blocks after them with the same region
+				lastBadPos = next - headerSize
+				currentPos = next
+				if _, err := file.Seek(currentPos, io.SeekStart); err != nil {
+					return fmt.Errorf("failed to seek to file position: %w", err)
+				}
+				tr = tar.NewReader(file)
+				continue
+			}
+		}
 		if err == io.EOF {
-			break
+			// tar.Reader reports a missing trailer as EOF too
+			trailer, err := isTrailer(file, headerPos)
+			if err != nil {
+				return err
+			}
+			index.Trailer = trailer
+			if !trailer {
+				fmt.Fprintf(logWriter(opts.Log), "\nWarning: no end-of-archive trailer at offset %d, the archive may be truncated\n", headerPos)
+				log.Warn("no end-of-archive trailer, the archive may be truncated", "tar", tarPath, "offset", headerPos)
+				break
+			}
+			if !opts.Concatenated {
+				break
+			}
+
+			// Continue with the archive appended after the trailer, if any
+			next, ok, err := nextNonZeroBlock(file, headerPos+2*headerSize)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+			currentPos = next
+			if _, err := file.Seek(currentPos, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to seek to file position: %w", err)
+			}
+			tr = tar.NewReader(file)
+			continue
 		}
 		if err != nil {
-			return fmt.Errorf("error reading tar header: %w", err)
+			if !opts.SkipBadHeaders || !errors.Is(err, tar.ErrHeader) {
+				return fmt.Errorf("error reading tar header: %w", err)
+			}
+
+			// Report only the first block of a run of unreadable blocks
+			if lastBadPos != headerPos-headerSize {
+				fmt.Fprintf(logWriter(opts.Log), "\nWarning: skipping unreadable entry at offset %d: %v\n", headerPos, err)
+				log.Warn("skipping unreadable entry", "tar", tarPath, "offset", headerPos, "error", err)
+				if opts.OnSkip != nil {
+					opts.OnSkip(headerPos, err)
+				}
+			}
+			lastBadPos = headerPos
+			resyncing = true
+
+			// tar.Reader keeps failing after an error, so resume with a new
+			// reader at the next block, looking for a valid header
+			currentPos = headerPos + headerSize
+			if _, err := file.Seek(currentPos, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to seek to file position: %w", err)
+			}
+			tr = tar.NewReader(file)
+			continue
+		}
+
+		resyncing = false
+
+		// Offsets are taken from the entry's own header, after any extended headers
+		entryPos, format, err := entryHeader(file, headerPos)
+		if err != nil {
+			return err
+		}
+		formats |= format | header.Format
+		entries++
+		if opts.MaxEntries > 0 && entries > opts.MaxEntries {
+			return fmt.Errorf("%w: archive has more than %d entries", ErrTooManyEntries, opts.MaxEntries)
 		}
 
-		if header.Typeflag != tar.TypeReg {
+		if header.Typeflag == typeGNUVolume || header.Typeflag == typeGNUMultiVolume {
+			multiVolume = true
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeGNUSparse && header.Typeflag != typeGNUMultiVolume {
 			fileSize := header.Size
-			paddedSize := (fileSize + 511) & ^int64(511)
-			currentPos = headerPos + headerSize + paddedSize
+			if header.Typeflag == tar.TypeXGlobalHeader {
+				// tar.Reader consumes the records of global headers and
+				// reports them with no size
+				if fileSize, err = headerSizeAt(file, entryPos); err != nil {
+					return err
+				}
+			}
+			paddedSize := padToBlock(fileSize)
+			currentPos = entryPos + headerSize + paddedSize
 			continue
 		}
 
-		cleanFilePath := filepath.Clean(header.Name)
-		cleanFilePathHash := hashFilePath(cleanFilePath)
+		cleanFilePath := normalizePath(header.Name, opts.Root)
+		included := opts.included(filepath.ToSlash(cleanFilePath))
+
+		// The rest of an archive ending within the data of a file is that data
+		truncated := !isSparse(header) && entryPos+headerSize+header.Size > fileInfo.Size()
+		// Of files split across volumes, continuations hold the rest of a
+		// file and the last file of a volume runs past its end. Neither can
+		// be read at its offset like a whole file. Archives not marked as
+		// volumes are merely truncated, and their last file is indexed as is,
+		// to be read with TruncatedError or Partial.
+		continued := truncated && multiVolume
+		split := header.Typeflag == typeGNUMultiVolume || continued
+		if included && split {
+			reason := "continues a file of the previous volume"
+			if continued {
+				reason = "runs past the end of the volume of a multi-volume archive"
+			}
+			fmt.Fprintf(logWriter(opts.Log), "\nWarning: %s at offset %d %s, indexing it as non-extractable\n", cleanFilePath, entryPos, reason)
+			log.Warn("file is only partly in the archive, indexing it as non-extractable", "tar", tarPath, "path", cleanFilePath, "offset", entryPos, "reason", reason)
+		} else if included && truncated {
+			fmt.Fprintf(logWriter(opts.Log), "\nWarning: %s at offset %d runs past the end of the archive, which is truncated\n", cleanFilePath, entryPos)
+			log.Warn("file runs past the end of the archive, which is truncated", "tar", tarPath, "path", cleanFilePath, "offset", entryPos)
+		}
+
+		// Sniffing consumes the start of the entry, so data is read on
+		// from content. Neither it nor hashing applies to partial data.
+		var mimeType string
+		var content io.Reader = tr
+		if included && opts.MIMETypes && !split && !truncated {
+			if mimeType, content, err = sniffContent(tr); err != nil {
+				return err
+			}
+		}
+
+		var contentHash string
+		var pendingHash <-chan hashResult
+		if included && (opts.ContentHash || opts.Dedup) && !split && !truncated {
+			if hashes != nil && !isSparse(header) {
+				// Hash in the background while tar.Reader seeks past the data
+				pendingHash = hashes.submit(entryPos+headerSize, header.Size)
+			} else {
+				contentHash, err = hashContent(content)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		paddedSize := padToBlock(header.Size)
+		if isSparse(header) {
+			// header.Size is the logical size of a sparse file, but only the data
+			// fragments are stored, so find where they end to locate the next header
+			endPos, err := sparseDataEnd(file, tr)
+			if err != nil {
+				return err
+			}
+			paddedSize = padToBlock(endPos) - entryPos - headerSize
+		}
+
+		if !included {
+			log.Debug("skipping filtered file", "path", cleanFilePath, "offset", entryPos)
+			if truncated {
+				break
+			}
+			// Skipped entries still occupy space in the archive
+			currentPos = entryPos + headerSize + paddedSize
+			continue
+		}
+		cleanFilePathHash := keyFunc(cleanFilePath)
+		if opts.Occurrences {
+			cleanFilePathHash = keyFunc(occurrencePath(cleanFilePath, occurrences[cleanFilePath]))
+			occurrences[cleanFilePath]++
+		}
 
 		fileIndex := FileIndex{
-			Start: headerPos,
-			Size:  header.Size,
+			Start:          entryPos,
+			Size:           header.Size,
+			ContentHash:    contentHash,
+			Compressed:     isCompressed(cleanFilePath),
+			HeaderBlocks:   int((entryPos - headerPos) / headerSize),
+			MIMEType:       mimeType,
+			NonExtractable: split,
+			Sparse:         isSparse(header),
+		}
+		if opts.StorePaths {
+			fileIndex.Path = cleanFilePath
 		}
 
 		if _, exists := index.Files[cleanFilePathHash]; exists {
+			log.Error("key collision", "path", cleanFilePath, "key", cleanFilePathHash, "offset", entryPos)
 			return fmt.Errorf("duplicate file path found for path %s: %s", cleanFilePath, cleanFilePathHash)
 		}
 
 		index.Files[cleanFilePathHash] = fileIndex
+		log.Debug("indexed file", "path", cleanFilePath, "key", cleanFilePathHash, "start", entryPos, "size", header.Size)
+		if opts.Verbose {
+			fmt.Fprintf(logWriter(opts.Log), "indexed %s @%d (%d bytes)\n", cleanFilePath, entryPos, header.Size)
+		}
 
-		paddedSize := (header.Size + 511) & ^int64(511)
-		currentPos = headerPos + headerSize + paddedSize
+		currentPos = entryPos + headerSize + paddedSize
 
-		percentDone := (currentPos * 100) / fileInfo.Size()
-		if percentDone != lastPercent {
-			fmt.Printf("\rIndexing: %d%% complete", percentDone)
-			lastPercent = percentDone
+		pending = append(pending, pendingFile{
+			key:     cleanFilePathHash,
+			offset:  currentPos,
+			files:   len(index.Files),
+			entries: entries,
+			formats: formats,
+			hash:    pendingHash,
+		})
+		if err := finish(false); err != nil {
+			return err
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(Progress{
+				FilesDone:  int64(len(index.Files)),
+				BytesDone:  currentPos,
+				BytesTotal: fileInfo.Size(),
+			})
+		}
+
+		// The rest of the archive or volume is the data of the file
+		if truncated {
+			break
 		}
 	}
 
-	// Open the output file for writing CSV
-	outFile, err := os.Create(indexPath)
-	if err != nil {
-		return fmt.Errorf("failed to create index file: %w", err)
+	if err := finish(true); err != nil {
+		return err
 	}
-	defer outFile.Close()
 
-	// Create a CSV writer
-	writer := csv.NewWriter(outFile)
-	defer writer.Flush()
+	index.Format = archiveFormat(formats)
+	index.ArchiveName = filepath.Base(tarPath)
+	index.ArchiveSize = fileInfo.Size()
+	index.EntryCount = entries
 
-	// Write CSV header
-	writer.Write([]string{"key", "start", "size"})
-
-	// Write file entries to CSV
-	for hsh, fileInfo := range index.Files {
-		writer.Write([]string{
-			hsh,
-			fmt.Sprintf("%d", fileInfo.Start),
-			fmt.Sprintf("%d", fileInfo.Size),
-		})
+	if opts.Dedup {
+		dedupContent(&index)
+	}
+
+	enc := indexEncoding{delimiter: opts.Delimiter, byOffset: opts.ByOffset, pathPrefixes: opts.PathPrefixes}
+	if err := writeTarIndex(&index, indexPath, enc); err != nil {
+		return err
+	}
+	if checkpoints != nil {
+		if err := checkpoints.finish(); err != nil {
+			return err
+		}
 	}
 
-	fmt.Printf("\nCreated index with %d files\n", len(index.Files))
-	fmt.Printf("Index saved to %s\n", indexPath)
+	fmt.Fprintf(logWriter(opts.Log), "\nCreated index with %d files\n", len(index.Files))
+	fmt.Fprintf(logWriter(opts.Log), "Index saved to %s\n", indexPath)
+	log.Info("created index", "tar", tarPath, "index", indexPath, "files", len(index.Files), "entries", index.EntryCount)
 
 	return nil
 }
 
-func ExtractBytesFromTarWithIndex(tindex *TarIndex, tarFile *os.File, filePath string) ([]byte, error) {
-
-	// Replace cleanFilePath with its hash
-	cleanFilePathHash := hashFilePath(filePath)
-
-	// Find the file in the index using hash
-	fileInfo, ok := tindex.Files[cleanFilePathHash]
-	if !ok {
-		return nil, fmt.Errorf("file %s not found in index", cleanFilePathHash)
+// hashContent returns the hex SHA-256 of the rest of the data of r
+func hashContent(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to read file data: %w", err)
 	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
 
-	// Seek to the file data position (after the header)
-	dataPos := fileInfo.Start + headerSize
-	if _, err := tarFile.Seek(dataPos, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("failed to seek to file position: %w", err)
+// dedupContent points every file at the first copy of identical content in the archive
+func dedupContent(index *TarIndex) {
+	type content struct {
+		hash string
+		size int64
 	}
-
-	// Read the file data
-	data := make([]byte, fileInfo.Size)
-	if _, err := io.ReadFull(tarFile, data); err != nil {
-		return nil, fmt.Errorf("failed to read file data: %w", err)
+	first := map[content]int64{}
+	for _, fileInfo := range index.Files {
+		c := content{fileInfo.ContentHash, fileInfo.Size}
+		if start, ok := first[c]; !ok || fileInfo.Start < start {
+			first[c] = fileInfo.Start
+		}
+	}
+	for key, fileInfo := range index.Files {
+		fileInfo.Start = first[content{fileInfo.ContentHash, fileInfo.Size}]
+		index.Files[key] = fileInfo
 	}
+}
 
-	return data, nil
+// WriteTarIndex saves index to indexPath in CSV format
+func WriteTarIndex(index *TarIndex, indexPath string) error {
+	return writeTarIndex(index, indexPath, indexEncoding{})
 }
 
-type TarixHandle struct {
-	TarFile *os.File
-	Index   *TarIndex
+// indexEncoding controls how an index is written
+type indexEncoding struct {
+	// delimiter separates fields; zero means comma
+	delimiter rune
+	// byOffset orders the rows by offset instead of by key
+	byOffset bool
+	// pathPrefixes stores paths against a table of shared directories
+	pathPrefixes bool
 }
 
-func NewTarixHandle(tarPath, indexPath string) (*TarixHandle, error) {
-	index, err := ReadTarIndex(indexPath)
+// writeTarIndex saves index to indexPath as enc says. It is written to a
+// temporary file renamed over indexPath when complete, so readers never see
+// a partial index.
+func writeTarIndex(index *TarIndex, indexPath string, enc indexEncoding) error {
+	return writeIndexFile(indexPath, func(w io.Writer) error {
+		return encodeTarIndex(w, index, enc)
+	})
+}
+
+// writeIndexFile writes an index to indexPath with encode, through a
+// temporary file renamed over indexPath when complete. An existing index
+// keeps its mode, and if indexPath is a symbolic link the file it points to
+// is replaced instead of the link. New indexes get mode 0644.
+func writeIndexFile(indexPath string, encode func(w io.Writer) error) (err error) {
+	indexPath, err = resolveLinks(indexPath)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(indexPath); err == nil {
+		mode = info.Mode().Perm()
 	}
 
-	tarFile, err := os.Open(tarPath)
+	// The temporary file must be in the same directory for the rename to be atomic
+	outFile, err := os.CreateTemp(filepath.Dir(indexPath), filepath.Base(indexPath)+".tmp*")
 	if err != nil {
-		return nil, fmt.Errorf("failed to open tar file: %w", err)
+		return fmt.Errorf("failed to create index file: %w", err)
 	}
-	return &TarixHandle{
-		TarFile: tarFile,
-		Index:   index,
-	}, nil
-}
-
-func (th *TarixHandle) ExtractBytesOfFile(filePath string) ([]byte, error) {
-	// Replace cleanFilePath with its hash
-	cleanFilePathHash := hashFilePath(filePath)
+	defer func() {
+		if err != nil {
+			outFile.Close()
+			os.Remove(outFile.Name())
+		}
+	}()
 
-	// Find the file in the index using hash
-	fileInfo, ok := th.Index.Files[cleanFilePathHash]
-	if !ok {
-		return nil, fmt.Errorf("file %s not found in index", cleanFilePathHash)
+	if err := encode(outFile); err != nil {
+		return err
 	}
+	if err := outFile.Chmod(mode); err != nil {
+		return fmt.Errorf("failed to set index file mode: %w", err)
+	}
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("failed to close index file: %w", err)
+	}
+	if err := os.Rename(outFile.Name(), indexPath); err != nil {
+		return fmt.Errorf("failed to move index file into place: %w", err)
+	}
+	return nil
+}
 
-	// Seek to the file data position (after the header)
-	dataPos := fileInfo.Start + headerSize
-	if _, err := th.TarFile.Seek(dataPos, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("failed to seek to file position: %w", err)
+// resolveLinks follows path through symbolic links to the file they point
+// to, which needn't exist yet
+func resolveLinks(path string) (string, error) {
+	for range 255 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			// Not a link, or nothing there
+			return path, nil
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
 	}
+	return "", fmt.Errorf("failed to resolve index path %s: too many symbolic links", path)
+}
 
-	// Read the file data
-	data := make([]byte, fileInfo.Size)
-	if _, err := io.ReadFull(th.TarFile, data); err != nil {
-		return nil, fmt.Errorf("failed to read file data: %w", err)
+// encodeTarIndex writes index to w in CSV format, flushing it before returning
+func encodeTarIndex(w io.Writer, index *TarIndex, enc indexEncoding) error {
+	// Create a CSV writer
+	writer := csv.NewWriter(w)
+	if enc.delimiter != 0 {
+		writer.Comma = enc.delimiter
 	}
-	return data, nil
 
-}
+	// Optional columns are only written when some entry has a value for them
+	var columns optionalColumns
+	index.each(func(_ string, fileInfo FileIndex) {
+		columns.add(fileInfo)
+	})
 
-// ExtractFileFromTar extracts a file from TAR using the index and writes it to a file
-func ExtractFileFromTar(tarPath, indexPath, filePath, outputPath string) error {
-	tarixHandle, err := NewTarixHandle(tarPath, indexPath)
-	if err != nil {
-		return err
+	// Write CSV header
+	var prefixes []string
+	if columns.path && enc.pathPrefixes {
+		prefixes = pathPrefixes(index)
 	}
-	defer tarixHandle.TarFile.Close()
+	header := columns.header(len(prefixes) > 0)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write index file: %w", err)
+	}
+	checksum := newIndexChecksum()
+	checksum.add(header)
 
-	// Extract file data as bytes
-	data, err := tarixHandle.ExtractBytesOfFile(filePath)
-	if err != nil {
-		return err
+	// Metadata rows follow the header
+	for _, record := range metadataRows(index, prefixes) {
+		record = escapeFields(record)
+		writer.Write(record)
+		checksum.add(record)
 	}
 
-	// Write the data to the specified output
-	var output io.Writer
-	if outputPath == "-" {
-		output = os.Stdout
-	} else {
-		outFile, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
-		}
-		defer outFile.Close()
-		output = outFile
+	var table prefixTable
+	if len(prefixes) > 0 {
+		table = newPrefixTable(prefixes)
 	}
 
-	if _, err := output.Write(data); err != nil {
-		return fmt.Errorf("failed to write file data: %w", err)
+	// Write file entries to CSV, in key order so the same index is always
+	// written the same way, or in archive order
+	each := index.eachSorted
+	if enc.byOffset {
+		each = index.eachByOffset
 	}
+	each(func(hsh string, fileInfo FileIndex) {
+		record := escapeFields(columns.record(hsh, fileInfo, table))
+		writer.Write(record)
+		checksum.add(record)
+	})
 
-	if outputPath != "-" {
-		fmt.Printf("Extracted %s to %s (size: %d bytes)\n", filePath, outputPath, len(data))
+	// Trailing row with the checksum of everything above it
+	writer.Write([]string{checksumRowKey, checksum.sum()})
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write index file: %w", err)
 	}
 
 	return nil
 }
 
-// ListFilesInTar lists files in the TAR using the index
-func ListFilesInTar(indexPath string) error {
-	// Use the new function to read the index
-	index, err := ReadTarIndex(indexPath)
-	if err != nil {
-		return err
-	}
+// optionalColumns records which optional columns an index has, those some
+// entry has a value for
+type optionalColumns struct {
+	path, checksum, compressed, headers, mime, nonExtractable, sparse bool
+}
 
-	fmt.Printf("TAR archive contains %d files\n", len(index.Files))
+// add includes the columns fileInfo has values for
+func (c *optionalColumns) add(fileInfo FileIndex) {
+	c.mime = c.mime || fileInfo.MIMEType != ""
+	c.nonExtractable = c.nonExtractable || fileInfo.NonExtractable
+	c.sparse = c.sparse || fileInfo.Sparse
+	c.path = c.path || fileInfo.Path != ""
+	c.checksum = c.checksum || fileInfo.ContentHash != ""
+	c.compressed = c.compressed || fileInfo.Compressed
+	c.headers = c.headers || fileInfo.HeaderBlocks > 0
+}
 
-	// Calculate total size of files
-	var totalSize int64
-	for _, fileInfo := range index.Files {
-		totalSize += fileInfo.Size
+// header returns the header row, with a prefix column after the path if
+// paths are stored against a prefix table
+func (c optionalColumns) header(prefixed bool) []string {
+	header := append([]string{}, requiredColumns...)
+	if c.path {
+		header = append(header, "path")
+	}
+	if prefixed {
+		header = append(header, "prefix")
+	}
+	if c.checksum {
+		header = append(header, "checksum")
+	}
+	if c.compressed {
+		header = append(header, "compressed")
+	}
+	if c.headers {
+		header = append(header, "headers")
+	}
+	if c.mime {
+		header = append(header, "mime")
 	}
+	if c.nonExtractable {
+		header = append(header, "nonextractable")
+	}
+	if c.sparse {
+		header = append(header, "sparse")
+	}
+	return header
+}
 
-	fmt.Printf("Total content size: %d bytes\n\n", totalSize)
-	fmt.Println("Files:")
+// record returns the row of the entry of key, unescaped. With a table, its
+// path is stored as the rest after its prefix and the prefix's ID.
+func (c optionalColumns) record(key string, fileInfo FileIndex, table prefixTable) []string {
+	record := []string{
+		key,
+		fmt.Sprintf("%d", fileInfo.Start),
+		fmt.Sprintf("%d", fileInfo.Size),
+	}
+	if table != nil {
+		id, suffix := table.split(fileInfo.Path)
+		record = append(record, suffix, id)
+	} else if c.path {
+		record = append(record, fileInfo.Path)
+	}
+	if c.checksum {
+		record = append(record, fileInfo.ContentHash)
+	}
+	if c.compressed {
+		record = append(record, strconv.FormatBool(fileInfo.Compressed))
+	}
+	if c.headers {
+		record = append(record, strconv.Itoa(fileInfo.HeaderBlocks))
+	}
+	if c.mime {
+		record = append(record, fileInfo.MIMEType)
+	}
+	if c.nonExtractable {
+		record = append(record, strconv.FormatBool(fileInfo.NonExtractable))
+	}
+	if c.sparse {
+		record = append(record, strconv.FormatBool(fileInfo.Sparse))
+	}
+	return record
+}
 
-	for hsh, fileInfo := range index.Files {
-		// Format modification time for display
-		fmt.Printf("- %s (%d bytes)\n", hsh, fileInfo.Size)
+// metadataRows returns the metadata rows of index, unescaped, with the
+// path prefix table prefixes
+func metadataRows(index *TarIndex, prefixes []string) [][]string {
+	metadata := [][]string{{versionRowKey, strconv.Itoa(indexVersion)}}
+	if index.Format != tar.FormatUnknown {
+		metadata = append(metadata, []string{formatRowKey, index.Format.String()})
+	}
+	if index.KeyScheme != "" && index.KeyScheme != MD5KeyScheme.Name {
+		metadata = append(metadata, []string{keySchemeRowKey, index.KeyScheme})
+	}
+	if index.Root != "" {
+		metadata = append(metadata, []string{rootRowKey, index.Root})
 	}
+	if index.Trailer {
+		metadata = append(metadata, []string{trailerRowKey, "found"})
+	}
+	if index.ArchiveName != "" {
+		metadata = append(metadata, []string{archiveNameRowKey, index.ArchiveName})
+	}
+	if index.ArchiveSize > 0 {
+		metadata = append(metadata, []string{archiveSizeRowKey, strconv.FormatInt(index.ArchiveSize, 10)})
+	}
+	if index.EntryCount > 0 {
+		metadata = append(metadata, []string{entryCountRowKey, strconv.FormatInt(index.EntryCount, 10)})
+	}
+	if index.Occurrences {
+		metadata = append(metadata, []string{occurrencesRowKey, "numbered"})
+	}
+	if index.Normalize.normalizes() {
+		metadata = append(metadata, []string{normalizationRowKey, string(index.Normalize)})
+	}
+	for _, prefix := range prefixes {
+		metadata = append(metadata, []string{prefixRowKey, prefix})
+	}
+	return metadata
+}
 
-	return nil
+// isSparse reports whether header describes a GNU sparse file, either in the
+// old GNU format or through GNU.sparse PAX records
+func isSparse(header *tar.Header) bool {
+	if header.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range header.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
 }
 
-func ReadTarIndex(indexPath string) (*TarIndex, error) {
-	// Open the index file
-	file, err := os.Open(indexPath)
+// sparseDataEnd reads through the current sparse entry of tr and returns the
+// archive offset right after its stored data. tar.Reader reads headers and
+// data directly from file, so the file offset tracks the reader's position.
+func sparseDataEnd(file *os.File, tr *tar.Reader) (int64, error) {
+	if _, err := io.Copy(io.Discard, tr); err != nil {
+		return 0, fmt.Errorf("error reading sparse entry: %w", err)
+	}
+	endPos, err := file.Seek(0, io.SeekCurrent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open index file: %w", err)
+		return 0, fmt.Errorf("failed to get position in tar file: %w", err)
 	}
-	defer file.Close()
+	return endPos, nil
+}
 
-	// Create a CSV reader
-	reader := csv.NewReader(file)
+func ExtractBytesFromTarWithIndex(tindex *TarIndex, tarFile *os.File, filePath string) ([]byte, error) {
+	info, err := tarFile.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tar file info: %w", err)
+	}
+	if err := checkSeekable(tarFile, info); err != nil {
+		return nil, err
+	}
+
+	// Replace cleanFilePath with its hash
+	cleanFilePathHash := tindex.Key(filePath)
+
+	// Find the file in the index using hash
+	fileInfo, ok := tindex.lookup(cleanFilePathHash)
+	if !ok {
+		return nil, fmt.Errorf("file %s not found in index", cleanFilePathHash)
+	}
+
+	if fileInfo.Sparse {
+		return readSparse(tarFile, fileInfo)
+	}
+
+	// Seek to the file data position (after the header)
+	dataPos := fileInfo.DataOffset()
+	if err := tindex.checkFits(fileInfo); err != nil {
+		return nil, err
+	}
+	if _, err := tarFile.Seek(dataPos, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to file position: %w", err)
+	}
+
+	// Read the file data
+	data := make([]byte, fileInfo.Size)
+	if _, err := io.ReadFull(tarFile, data); err != nil {
+		return nil, fmt.Errorf("failed to read file data: %w", err)
+	}
+
+	return data, nil
+}
+
+// ExtractBytesFromReaderAt reads filePath from a TAR accessed through r, such
+// as an HTTPReaderAt for a remote archive
+func ExtractBytesFromReaderAt(tindex *TarIndex, r io.ReaderAt, filePath string) ([]byte, error) {
+	key := tindex.Key(filePath)
+	fileInfo, ok := tindex.lookup(key)
+	if !ok {
+		return nil, fmt.Errorf("file %s not found in index", key)
+	}
+	if fileInfo.Sparse {
+		return readSparse(r, fileInfo)
+	}
+
+	if err := tindex.checkFits(fileInfo); err != nil {
+		return nil, err
+	}
+	data := make([]byte, fileInfo.Size)
+	if _, err := io.ReadFull(io.NewSectionReader(r, fileInfo.DataOffset(), fileInfo.Size), data); err != nil {
+		return nil, fmt.Errorf("failed to read file data: %w", err)
+	}
+	return data, nil
+}
+
+type TarixHandle struct {
+	// Source is what the TAR is read from
+	Source Source
+	Index  *TarIndex
+	// Verify checks the TAR header at each file's Start before reading its
+	// data, failing with ErrIndexStale if it doesn't match the index. This
+	// costs one extra header read per file and is recommended for indexes
+	// that may not match the TAR.
+	Verify bool
+	// Root is a directory of the archive that paths looked up through the
+	// handle are relative to, so with Root "data" the file "foo.txt" is
+	// "data/foo.txt" in the archive. It is not prepended twice if the index
+	// was built with the same IndexOptions.Root.
+	Root string
+	// Transform, if set, is applied to file data written by WriteFileTo
+	Transform Transform
+	// BufferSize is the size of the buffer WriteFileTo copies through; zero
+	// or negative uses 32KB. See ExtractOptions.BufferSize.
+	BufferSize int
+	// Logger, if set, receives a warning for each file failing Verify
+	Logger *slog.Logger
+	// Partial makes ExtractBytesOfFile and ExtractBytesByKey return the bytes
+	// of a file that are in the TAR when it is truncated, along with the
+	// TruncatedError, to salvage what is left of damaged archives
+	Partial bool
+	// RestoreSparse makes WriteFileTo leave holes in outputs that can seek
+	// and truncate, like an *os.File, for sparse entries instead of writing
+	// their zeros. It is ignored with a Transform.
+	RestoreSparse bool
+}
+
+// ErrIndexStale is returned when the TAR doesn't match what the index says is in it
+var ErrIndexStale = errors.New("index is stale")
 
-	// Read and discard the header
-	_, err = reader.Read()
+// HandleOptions controls how NewTarixHandleWithOptions opens the TAR
+type HandleOptions struct {
+	// NoLock skips the shared advisory lock on the TAR, for filesystems that
+	// don't support flock
+	NoLock bool
+	// Logger, if set, is the handle's Logger
+	Logger *slog.Logger
+	// UseMmap maps the TAR into memory and serves reads from the mapping,
+	// which saves a system call per read when reading many small files. If
+	// the TAR can't be mapped, e.g. because it is larger than the address
+	// space, the handle reads the file as usual. The TAR must not be
+	// truncated while mapped; reading a truncated part crashes the process.
+	UseMmap bool
+	// Decompress reads a gzip-compressed TAR, recognized by its first bytes,
+	// with an index of the uncompressed TAR inside it. The TAR is decompressed
+	// once when the handle is opened, into a temporary file removed on Close,
+	// and read from there. This costs a full decompression on every open and
+	// disk space for the whole uncompressed TAR. Uncompressed TARs are read as
+	// usual; UseMmap doesn't apply to decompressed ones.
+	Decompress bool
+	// DecompressInMemory keeps the TAR decompressed with Decompress in memory
+	// instead of a temporary file, which costs memory for the whole
+	// uncompressed TAR for the lifetime of the handle
+	DecompressInMemory bool
+	// TempDir is the directory of the temporary file of Decompress (default:
+	// os.TempDir())
+	TempDir string
+	// ReadPattern hints to the OS how the TAR will be read, e.g. ReadRandom
+	// for a handle serving scattered files. It is ignored on platforms
+	// without posix_fadvise.
+	ReadPattern ReadPattern
+}
+
+// NewTarixHandle opens the TAR read-only with the index at indexPath. It
+// holds a shared advisory lock (flock) on the TAR until Close, so a process
+// replacing or rewriting the archive under an exclusive lock waits for the
+// handle to be closed instead of causing torn reads. Locks are advisory:
+// writers that don't take one are not stopped.
+func NewTarixHandle(tarPath, indexPath string) (*TarixHandle, error) {
+	return NewTarixHandleWithOptions(tarPath, indexPath, HandleOptions{})
+}
+
+// NewTarixHandleWithOptions is like NewTarixHandle with options
+func NewTarixHandleWithOptions(tarPath, indexPath string, opts HandleOptions) (*TarixHandle, error) {
+	index, err := ReadTarIndex(indexPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		return nil, err
 	}
 
-	// Initialize the index
-	index := &TarIndex{
-		Files: map[string]FileIndex{},
+	tarFile, err := os.OpenFile(tarPath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar file: %w", err)
 	}
+	if !opts.NoLock {
+		if err := lockShared(tarFile); err != nil {
+			tarFile.Close()
+			return nil, fmt.Errorf("failed to lock tar file: %w", err)
+		}
+	}
+	adviseRead(tarFile, opts.ReadPattern, slogger(opts.Logger))
+	var source Source
+	compressed := false
+	if opts.Decompress {
+		if compressed, err = isGzip(tarFile); err != nil {
+			tarFile.Close()
+			return nil, err
+		}
+	}
+	if compressed {
+		if source, err = newDecompressedSource(tarFile, opts); err != nil {
+			tarFile.Close()
+			return nil, err
+		}
+	} else if opts.UseMmap {
+		if mapped, err := newMmapSource(tarFile); err != nil {
+			slogger(opts.Logger).Info("reading the tar file without mmap", "tar", tarPath, "error", err)
+		} else {
+			source = mapped
+		}
+	}
+	if source == nil {
+		if source, err = NewFileSource(tarFile); err != nil {
+			tarFile.Close()
+			return nil, err
+		}
+	}
+	th := NewTarixHandleFromSource(source, index)
+	th.Logger = opts.Logger
+	th.checkArchive(tarPath, compressed)
+	return th, nil
+}
 
-	// Read each record from the CSV
-	for {
-		record, err := reader.Read()
+// checkArchive warns through th.Logger if the TAR at tarPath doesn't have
+// the name and size recorded in the index, as when opening an index with
+// another archive than the one it was built from. Renamed or appended to
+// archives warn too, so it is not an error. The TAR inside a compressed
+// archive, like a.tar in a.tar.gz, may have either name.
+func (th *TarixHandle) checkArchive(tarPath string, compressed bool) {
+	log := slogger(th.Logger)
+	base := filepath.Base(tarPath)
+	if name := th.Index.ArchiveName; name != "" && name != base && !(compressed && name == decompressedName(base)) {
+		log.Warn("tar file name doesn't match the index", "tar", tarPath, "indexed", name)
+	}
+	if size := th.Index.ArchiveSize; size > 0 && size != th.Source.Size() {
+		log.Warn("tar file size doesn't match the index", "tar", tarPath, "size", th.Source.Size(), "indexed", size)
+	}
+}
+
+// NewTarixHandleFromSource returns a handle reading the TAR indexed by index
+// from source, which Close closes
+func NewTarixHandleFromSource(source Source, index *TarIndex) *TarixHandle {
+	return &TarixHandle{Source: source, Index: index}
+}
+
+// Close closes the source of the TAR, releasing the lock and mapping of a file
+func (th *TarixHandle) Close() error {
+	return th.Source.Close()
+}
+
+func (th *TarixHandle) ExtractBytesOfFile(filePath string) ([]byte, error) {
+	// Replace cleanFilePath with its hash
+	return th.extractBytesByKey(th.key(filePath), filePath)
+}
+
+// ExtractBytesByKey extracts a file using its index key directly, skipping path hashing
+func (th *TarixHandle) ExtractBytesByKey(key string) ([]byte, error) {
+	if th.Index.KeyScheme == "" {
+		if err := validateKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return th.extractBytesByKey(key, key)
+}
+
+// key returns the index key of filePath, which is relative to th.Root
+func (th *TarixHandle) key(filePath string) string {
+	return th.Index.Key(filepath.Join(th.Root, filePath))
+}
+
+// lookup finds the index entry of filePath
+func (th *TarixHandle) lookup(filePath string) (FileIndex, error) {
+	key := th.key(filePath)
+	fileInfo, ok := th.Index.lookup(key)
+	if !ok {
+		return FileIndex{}, fmt.Errorf("file %s not found in index", key)
+	}
+	return fileInfo, nil
+}
+
+// Contains reports whether filePath, relative to th.Root, is in the index.
+// The TAR is not read.
+func (th *TarixHandle) Contains(filePath string) bool {
+	_, ok := th.Index.lookup(th.key(filePath))
+	return ok
+}
+
+// fileEntry finds the index entry of key, verifying it against the TAR if th.Verify is set
+func (th *TarixHandle) fileEntry(key string) (FileIndex, error) {
+	fileInfo, ok := th.Index.lookup(key)
+	if !ok {
+		return FileIndex{}, fmt.Errorf("file %s not found in index", key)
+	}
+	if err := checkExtractable(key, fileInfo); err != nil {
+		return FileIndex{}, err
+	}
+	if th.Verify {
+		if err := th.verifyHeader(key, fileInfo); err != nil {
+			slogger(th.Logger).Warn("file failed verification", "key", key, "start", fileInfo.Start, "error", err)
+			return FileIndex{}, err
+		}
+	}
+	return fileInfo, nil
+}
+
+// verifyHeader checks that a single regular file header sits at fileInfo.Start
+// and that it names the file of key, or one the index points at the same data
+// (as with deduplicated files).
+func (th *TarixHandle) verifyHeader(key string, fileInfo FileIndex) error {
+	_, _, err := th.readEntry(key, fileInfo)
+	return err
+}
+
+// readEntry verifies the header at fileInfo.Start like verifyHeader and
+// returns the header and a tar.Reader positioned at the start of its content
+func (th *TarixHandle) readEntry(key string, fileInfo FileIndex) (*tar.Header, *tar.Reader, error) {
+	if err := checkExtractable(key, fileInfo); err != nil {
+		return nil, nil, err
+	}
+	// Include extended headers, which hold names too long for the header itself
+	offset := fileInfo.Start - int64(fileInfo.HeaderBlocks)*headerSize
+	sr := io.NewSectionReader(th.Source, offset, math.MaxInt64-offset)
+	tr := tar.NewReader(sr)
+	header, err := tr.Next()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: no valid header at offset %d: %v", ErrIndexStale, fileInfo.Start, err)
+	}
+	if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeGNUSparse {
+		return nil, nil, fmt.Errorf("%w: header at offset %d is not a regular file", ErrIndexStale, fileInfo.Start)
+	}
+	if header.Size != fileInfo.Size {
+		return nil, nil, fmt.Errorf("%w: header at offset %d has size %d, index has %d", ErrIndexStale, fileInfo.Start, header.Size, fileInfo.Size)
+	}
+
+	headerKeys := th.Index.headerKeys(header.Name)
+	for _, headerKey := range headerKeys {
+		if headerKey == key {
+			return header, tr, nil
+		}
+	}
+	for _, headerKey := range headerKeys {
+		if other, ok := th.Index.lookup(headerKey); ok && other.Start == fileInfo.Start {
+			return header, tr, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: header at offset %d is for %s, not %s", ErrIndexStale, fileInfo.Start, normalizePath(header.Name, th.Index.Root), key)
+}
+
+// SectionReaderOf returns a reader over the data of filePath within the TAR.
+// It reads with ReadAt, so several readers can be used concurrently. The
+// data of sparse files isn't contiguous, so they can't be read this way.
+func (th *TarixHandle) SectionReaderOf(filePath string) (*io.SectionReader, error) {
+	fileInfo, err := th.fileEntry(th.key(filePath))
+	if err != nil {
+		return nil, err
+	}
+	if fileInfo.Sparse {
+		return nil, fmt.Errorf("%s is a sparse file, whose data isn't contiguous in the TAR", filePath)
+	}
+	return io.NewSectionReader(th.Source, fileInfo.DataOffset(), fileInfo.Size), nil
+}
+
+// WriteFileTo streams the contents of filePath to w and returns the number of bytes written
+func (th *TarixHandle) WriteFileTo(filePath string, w io.Writer) (int64, error) {
+	return th.writeKeyTo(th.key(filePath), filePath, w)
+}
+
+// writeKeyTo streams the contents of the file of key, named name in errors,
+// to w
+func (th *TarixHandle) writeKeyTo(key, name string, w io.Writer) (int64, error) {
+	fileInfo, err := th.fileEntry(key)
+	if err != nil {
+		return 0, err
+	}
+	// Indexes of older versions don't mark sparse files, so with RestoreSparse
+	// the header is read to find them
+	ws, ok := w.(sparseFile)
+	holes := ok && th.RestoreSparse && th.Transform == nil && seekable(ws)
+	if fileInfo.Sparse || holes {
+		if n, sparse, err := th.writeSparse(key, fileInfo, w, holes); sparse {
+			return n, err
+		}
+	}
+
+	sr := io.NewSectionReader(th.Source, fileInfo.DataOffset(), fileInfo.Size)
+
+	var r io.Reader = sr
+	if th.Transform != nil {
+		r = th.Transform(&truncatedReader{r: sr, n: sr.Size()})
+	}
+	n, err := copyBuffer(w, r, th.BufferSize)
+	if err != nil {
+		return n, fmt.Errorf("failed to copy file data: %w", err)
+	}
+	if th.Transform == nil && n != sr.Size() {
+		return n, &TruncatedError{Name: name, Size: sr.Size(), Available: n}
+	}
+	return n, nil
+}
+
+// writeSparse writes the entry of key to w if it is a sparse one, reporting
+// whether it was. Its data is read through the TAR header, which holds the
+// map of its data fragments, and its holes are filled with zeros, or left
+// as holes in w if holes is set.
+func (th *TarixHandle) writeSparse(key string, fileInfo FileIndex, w io.Writer, holes bool) (int64, bool, error) {
+	header, tr, err := th.readEntry(key, fileInfo)
+	if err != nil {
+		return 0, true, err
+	}
+	if !isSparse(header) {
+		return 0, false, nil
+	}
+	var n int64
+	if holes {
+		n, err = copySparse(w.(sparseFile), tr, th.BufferSize)
+	} else {
+		var r io.Reader = tr
+		if th.Transform != nil {
+			r = th.Transform(tr)
+		}
+		n, err = copyBuffer(w, r, th.BufferSize)
+	}
+	if err != nil {
+		return n, true, fmt.Errorf("failed to copy file data: %w", err)
+	}
+	return n, true, nil
+}
+
+// extractBytesByKey reads the file of key, named name in errors
+func (th *TarixHandle) extractBytesByKey(key, name string) ([]byte, error) {
+	// Find the file in the index using hash
+	fileInfo, err := th.fileEntry(key)
+	if err != nil {
+		return nil, err
+	}
+	if fileInfo.Sparse {
+		r, err := th.openEntry(key, fileInfo)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file data: %w", err)
+		}
+		return data, nil
+	}
+
+	// A TAR cut short is reported before allocating for data it doesn't have
+	size := fileInfo.Size
+	var truncated error
+	if available := th.available(fileInfo); available < size {
+		truncated = &TruncatedError{Name: name, Size: size, Available: available}
+		if !th.Partial {
+			return nil, truncated
+		}
+		size = available
+	} else if err := th.Index.checkFits(fileInfo); err != nil {
+		return nil, err
+	}
+
+	// Read the file data, after the header
+	data := make([]byte, size)
+	sr := io.NewSectionReader(th.Source, fileInfo.DataOffset(), size)
+	if n, err := io.ReadFull(sr, data); err != nil {
+		if err != io.ErrUnexpectedEOF || !th.Partial {
+			return nil, fmt.Errorf("failed to read file data: %w", err)
+		}
+		return data[:n], &TruncatedError{Name: name, Size: fileInfo.Size, Available: int64(n)}
+	}
+	return data, truncated
+}
+
+// validateKey checks that key looks like a key produced by hashFilePath
+func validateKey(key string) error {
+	if len(key) != HashLen {
+		return fmt.Errorf("invalid key %q: expected %d characters, got %d", key, HashLen, len(key))
+	}
+	for _, c := range key {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return fmt.Errorf("invalid key %q: expected lowercase hex characters", key)
+		}
+	}
+	return nil
+}
+
+// ExtractFileFromTar extracts a file from TAR using the index and writes it to a file
+func ExtractFileFromTar(tarPath, indexPath, filePath, outputPath string) error {
+	return ExtractFileFromTarWithOptions(tarPath, indexPath, filePath, outputPath, ExtractOptions{Log: os.Stdout})
+}
+
+// ExtractFileFromTarWithOptions extracts a file from TAR using the index and
+// writes it to outputPath, or to stdout if outputPath is "-"
+func ExtractFileFromTarWithOptions(tarPath, indexPath, filePath, outputPath string, opts ExtractOptions) error {
+	err := extractFileFromTar(opts.fs(), tarPath, indexPath, filePath, outputPath, opts)
+	if opts.AutoReindex && errors.Is(err, ErrIndexStale) {
+		return extractReindexed(opts.fs(), tarPath, indexPath, filePath, outputPath, opts, err)
+	}
+	return err
+}
+
+func extractFileFromTar(fsys FS, tarPath, indexPath, filePath, outputPath string, opts ExtractOptions) error {
+	tarixHandle, err := NewTarixHandleWithOptions(tarPath, indexPath, HandleOptions{NoLock: opts.NoLock, Logger: opts.Logger, ReadPattern: opts.ReadPattern})
+	if err != nil {
+		return err
+	}
+	defer tarixHandle.Close()
+	tarixHandle.Verify = opts.Verify || opts.AutoReindex
+	tarixHandle.Root = opts.Root
+	tarixHandle.Transform = opts.Transform
+	tarixHandle.BufferSize = opts.BufferSize
+	tarixHandle.RestoreSparse = opts.RestoreSparse
+
+	// Fail before creating the output if the file is not in the index
+	if _, err := tarixHandle.fileEntry(tarixHandle.key(filePath)); err != nil {
+		return err
+	}
+
+	// Write the data to the specified output
+	var output io.Writer
+	if outputPath == "-" {
+		output = os.Stdout
+	} else {
+		if opts.AllowedRoot != "" {
+			if err := checkAllowedRoot(opts.AllowedRoot, outputPath); err != nil {
+				return err
+			}
+		}
+		skip, err := opts.Overwrite.checkOutput(fsys, outputPath)
+		if err != nil {
+			return err
+		}
+		if skip {
+			fmt.Fprintf(logWriter(opts.Log), "Skipped %s: %s already exists\n", filePath, outputPath)
+			slogger(opts.Logger).Info("skipped existing file", "path", filePath, "output", outputPath)
+			return nil
+		}
+		if opts.CreateDirs {
+			if err := fsys.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+		}
+		outFile, _, err := opts.createOutput(fsys, outputPath)
+		if err != nil {
+			return err
+		}
+		defer outFile.Close()
+		output = outFile
+	}
+
+	n, err := tarixHandle.WriteFileTo(filePath, output)
+	if err != nil {
+		return err
+	}
+
+	if outputPath != "-" {
+		if err := output.(io.Closer).Close(); err != nil {
+			return fmt.Errorf("failed to close output file: %w", err)
+		}
+		fmt.Fprintf(logWriter(opts.Log), "Extracted %s to %s (size: %d bytes)\n", filePath, outputPath, n)
+		slogger(opts.Logger).Info("extracted file", "path", filePath, "output", outputPath, "size", n)
+	}
+
+	return nil
+}
+
+// ExtractOptions controls ExtractFileFromTarWithOptions and ExtractAllWithOptions
+type ExtractOptions struct {
+	// Progress, if set, is called after each extracted file
+	Progress ProgressFunc
+	// Root is the directory of the archive that files are extracted relative
+	// to, and that file paths given to ExtractFileFromTarWithOptions are
+	// relative to (see TarixHandle.Root). It defaults to the IndexOptions.Root
+	// the index was built with.
+	Root string
+	// Log receives informational messages; nil discards them
+	Log io.Writer
+	// Logger, if set, receives the same messages as structured records: the
+	// file extracted by ExtractFileFromTarWithOptions at info level, and each
+	// file extracted by ExtractAll at debug level
+	Logger *slog.Logger
+	// Verify checks the TAR header of the file before extracting it (see
+	// TarixHandle.Verify). ExtractAll always reads headers and ignores it.
+	Verify bool
+	// FS is the filesystem files are written to; nil means the OS filesystem
+	FS FS
+	// Transform, if set, is applied to the data of each extracted file
+	Transform Transform
+	// BufferSize is the size of the buffer file data is copied through; zero
+	// or negative uses 32KB. Smaller buffers save memory, larger ones mean
+	// fewer reads and writes, which can be faster on fast disks. It is not
+	// used when the output can copy from the TAR directly, like an *os.File
+	// from an untransformed file.
+	BufferSize int
+	// NoLock skips the shared advisory lock ExtractFileFromTarWithOptions
+	// holds on the TAR while reading it (see HandleOptions.NoLock)
+	NoLock bool
+	// Overwrite is what to do with output files that already exist; the
+	// default overwrites them. Policies other than Overwrite need FS to
+	// implement StatFS, as the built-in ones do.
+	Overwrite OverwritePolicy
+	// RestoreSparse leaves holes in the output files of sparse entries instead
+	// of writing their zeros, so disk images take only the space of their
+	// data (see TarixHandle.RestoreSparse). It needs files created by FS to
+	// seek and truncate, as those of OSFS do, and is ignored with a Transform.
+	RestoreSparse bool
+	// NoSpecialFiles fails with ErrSpecialFile when an output path is an
+	// existing FIFO, device or socket. By default such outputs are opened for
+	// writing as they are, e.g. to pipe a file to another process through a
+	// FIFO, where creating the file would truncate or replace it. Recognizing
+	// them needs FS to implement StatFS and OpenFS, as OSFS does.
+	NoSpecialFiles bool
+	// PreserveOwner gives the files and directories written by ExtractAll and
+	// Explode the numeric owners (uid and gid) of their entries in the TAR,
+	// like tar --same-owner --numeric-owner, which needs FS to implement
+	// ChownFS, as OSFS does. Without the privilege to change owners, as for
+	// users other than root, a warning is logged to Logger and files stay
+	// owned by the current user, as they do by default. Symbolic links are
+	// owned by the current user either way.
+	PreserveOwner bool
+	// Concurrency bounds how many files ExtractMany and ExtractAll write at
+	// once; zero uses GOMAXPROCS. A file that fails doesn't stop the others,
+	// and the errors of all failed files are returned joined. Progress is
+	// called from one goroutine at a time.
+	Concurrency int
+	// AutoReindex makes ExtractFileFromTarWithOptions recover from a stale
+	// index, as left by changing the archive without reindexing it: the
+	// header of the file is checked as with Verify, and if it doesn't match
+	// (ErrIndexStale) the archive is indexed again into a temporary
+	// directory and the file extracted once more with the new index. The new
+	// index is built with the options the old one records, like its key
+	// scheme, root and stored paths; Include and Exclude filters aren't kept.
+	AutoReindex bool
+	// ReplaceStaleIndex, with AutoReindex, writes the rebuilt index over the
+	// stale one once the file was extracted with it
+	ReplaceStaleIndex bool
+	// AllowedRoot, if set, makes ExtractFileFromTarWithOptions fail with
+	// ErrOutsideRoot unless the output path is within this directory, after
+	// cleaning it and resolving its symlinks on the OS filesystem, so a
+	// caller-supplied output can't write elsewhere through ".." or a link.
+	// Links are resolved before the file is created, so one swapped in
+	// meanwhile isn't caught.
+	AllowedRoot string
+	// ReadPattern hints to the OS how the TAR will be read (see
+	// HandleOptions.ReadPattern). ExtractAll and Explode read it in order, so
+	// large runs gain from ReadSequential.
+	ReadPattern ReadPattern
+	// UnsafeLinks makes Explode recreate symbolic links whatever their
+	// targets, like tar does, for trusted archives such as system images
+	// with absolute links. By default links that are absolute or lead out of
+	// the output directory, also through other links, are skipped. Files are
+	// never written through links either way.
+	UnsafeLinks bool
+	// CreateDirs makes ExtractFileFromTarWithOptions create the missing
+	// parent directories of the output path, once the file was found in the
+	// index, so a missing file leaves no empty directories behind
+	CreateDirs bool
+}
+
+// defaultBufferSize is the copy buffer size when none is configured, the
+// same as io.Copy's
+const defaultBufferSize = 32 * 1024
+
+// copyBuffer copies src to dst through a buffer of size bytes, or of
+// defaultBufferSize if size isn't positive
+func copyBuffer(dst io.Writer, src io.Reader, size int) (int64, error) {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+	return io.CopyBuffer(dst, src, make([]byte, size))
+}
+
+// fs returns the filesystem to extract into
+func (o ExtractOptions) fs() FS {
+	if o.FS == nil {
+		return OSFS{}
+	}
+	return o.FS
+}
+
+// ExtractAll extracts every file in the index into outputDir, recreating paths
+func ExtractAll(tarPath, indexPath, outputDir string) error {
+	return ExtractAllWithOptions(tarPath, indexPath, outputDir, ExtractOptions{})
+}
+
+// ExtractAllWithOptions extracts every file in the index into outputDir. The
+// index only holds hashed paths, so the TAR is read sequentially and each
+// entry whose path is found in the index is written out. Up to
+// opts.Concurrency files are written at once, each read from the TAR with
+// ReadAt while the headers are read on. Directories created for the files
+// get the mode and times of their entries in the TAR, if any, after all
+// files are written. Symbolic and hard links are not recreated, so hostile
+// link entries can't lead files out of outputDir; Explode recreates them.
+// Of a path stored more than once in an index built with
+// IndexOptions.Occurrences, the last occurrence is extracted, as tar leaves it.
+func ExtractAllWithOptions(tarPath, indexPath, outputDir string, opts ExtractOptions) error {
+	return extractAll(opts.fs(), tarPath, indexPath, outputDir, opts)
+}
+
+func extractAll(fsys FS, tarPath, indexPath, outputDir string, opts ExtractOptions) error {
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		return err
+	}
+
+	root := opts.Root
+	if root == "" {
+		root = index.Root
+	}
+
+	progress := Progress{FilesTotal: int64(index.count())}
+	index.each(func(_ string, fileInfo FileIndex) {
+		progress.BytesTotal += fileInfo.Size
+	})
+
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return fmt.Errorf("failed to open tar file: %w", err)
+	}
+	defer file.Close()
+	adviseRead(file, opts.ReadPattern, slogger(opts.Logger))
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to get tar file info: %w", err)
+	}
+	// Files are read through their own readers, independent of tr
+	th := NewTarixHandleFromSource(NewReaderSource(file, info.Size()), index)
+
+	log := slogger(opts.Logger)
+	owners := newOwnerSetter(fsys, opts)
+	// Directory modes and times are restored once their files are written
+	dirs := map[string]dirMetadata{}
+	created := map[string]bool{}
+	// mu guards progress, created and opts.Log, used by the extracting goroutines
+	var mu sync.Mutex
+	pool := newExtractPool(opts.concurrency())
+	// A path found more than once is written once, from the entry in the
+	// index, or the last of its occurrences in an index keyed by them
+	dispatched := map[string]bool{}
+	keys := newOccurrenceKeys(index)
+
+	tr := tar.NewReader(file)
+	for int64(len(dispatched)) < progress.FilesTotal {
+		header, err := tr.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to read CSV record: %w", err)
+			return errors.Join(fmt.Errorf("error reading tar header: %w", err), pool.wait())
 		}
 
-		// Expecting the format: key, start, size
-		if len(record) != 3 {
-			return nil, fmt.Errorf("unexpected CSV format")
+		if header.Typeflag == tar.TypeDir {
+			if cleanDirPath := normalizePath(header.Name, root); filepath.IsLocal(cleanDirPath) {
+				dirs[filepath.Join(outputDir, cleanDirPath)] = newDirMetadata(header)
+			}
+			continue
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeGNUSparse {
+			continue
 		}
 
-		start, err := parseInt64(record[1])
+		key, superseded := keys.next(header.Name)
+		fileInfo, ok := index.lookup(key)
+		if !ok || dispatched[key] {
+			continue
+		}
+		dispatched[key] = true
+		cleanFilePath := normalizePath(header.Name, root)
+		if superseded {
+			mu.Lock()
+			progress.FilesDone++
+			if opts.Progress != nil {
+				opts.Progress(progress)
+			}
+			mu.Unlock()
+			log.Debug("skipped replaced occurrence", "path", cleanFilePath, "key", key)
+			continue
+		}
+
+		if !filepath.IsLocal(cleanFilePath) {
+			return errors.Join(fmt.Errorf("refusing to extract %s outside of output directory", header.Name), pool.wait())
+		}
+
+		outputPath := filepath.Join(outputDir, cleanFilePath)
+		skip, err := opts.Overwrite.checkOutput(fsys, outputPath)
 		if err != nil {
-			return nil, fmt.Errorf("invalid start value: %w", err)
+			return errors.Join(err, pool.wait())
+		}
+		if skip {
+			mu.Lock()
+			fmt.Fprintf(logWriter(opts.Log), "Skipped %s: %s already exists\n", cleanFilePath, outputPath)
+			progress.FilesDone++
+			if opts.Progress != nil {
+				opts.Progress(progress)
+			}
+			mu.Unlock()
+			log.Info("skipped existing file", "path", cleanFilePath, "output", outputPath)
+			continue
+		}
+
+		pool.run(func() error {
+			entryHeader, r, err := th.readEntry(key, fileInfo)
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", cleanFilePath, err)
+			}
+			sparse := opts.RestoreSparse && opts.Transform == nil && isSparse(entryHeader)
+			n, err := extractEntry(fsys, opts.Transform.apply(r), outputPath, entryHeader, sparse, owners, opts)
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", cleanFilePath, err)
+			}
+			log.Debug("extracted file", "path", cleanFilePath, "output", outputPath, "size", n)
+
+			mu.Lock()
+			defer mu.Unlock()
+			addParents(created, outputDir, outputPath)
+			progress.FilesDone++
+			progress.BytesDone += n
+			if opts.Progress != nil {
+				opts.Progress(progress)
+			}
+			return nil
+		})
+	}
+	if err := pool.wait(); err != nil {
+		return err
+	}
+
+	// A directory that can't be restored is reported, but its files are kept
+	for _, err := range restoreDirs(fsys, dirs, created, owners) {
+		fmt.Fprintf(logWriter(opts.Log), "Warning: %v\n", err)
+		log.Warn("failed to restore directory", "error", err)
+	}
+	return nil
+}
+
+// extractEntry writes r, the data of the entry of header, to outputPath in
+// fsys through a buffer of opts.BufferSize bytes, creating parent
+// directories, and gives it the mode of header and its owner with owners. If
+// sparse is set and the output can seek and truncate, blocks of zeros are
+// left as holes. The mode and owner of FIFOs and devices written to are left
+// as they are.
+func extractEntry(fsys FS, r io.Reader, outputPath string, header *tar.Header, sparse bool, owners *ownerSetter, opts ExtractOptions) (int64, error) {
+	if err := fsys.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	outFile, special, err := opts.createOutput(fsys, outputPath)
+	if err != nil {
+		return 0, err
+	}
+	defer outFile.Close()
+
+	var n int64
+	if ws, ok := outFile.(sparseFile); ok && sparse && seekable(ws) {
+		n, err = copySparse(ws, r, opts.BufferSize)
+	} else {
+		n, err = copyBuffer(outFile, r, opts.BufferSize)
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to write file data: %w", err)
+	}
+	if err := outFile.Close(); err != nil {
+		return n, fmt.Errorf("failed to close output file: %w", err)
+	}
+	if special {
+		return n, nil
+	}
+	// Changing the owner may clear the setuid and setgid bits of the mode
+	if err := owners.chown(outputPath, header.Uid, header.Gid); err != nil {
+		return n, err
+	}
+	if err := fsys.Chmod(outputPath, header.FileInfo().Mode().Perm()); err != nil {
+		return n, fmt.Errorf("failed to set file mode: %w", err)
+	}
+	return n, nil
+}
+
+// ListFilesInTar lists files in the TAR using the index
+func ListFilesInTar(indexPath string) error {
+	return ListFilesInTarWithPaths(indexPath, "")
+}
+
+// ListFilesInTarWithPaths lists files in the TAR using the index. For indexes
+// without stored paths, when tarPath is given the TAR headers are scanned to
+// show the original paths instead of hashes.
+func ListFilesInTarWithPaths(indexPath, tarPath string) error {
+	return ListFilesInTarWithOptions(indexPath, ListOptions{TarPath: tarPath})
+}
+
+// ListFilesInTarWithOptions lists the files of the index within the size
+// range of opts. The number and total size of files are those of the files
+// listed.
+func ListFilesInTarWithOptions(indexPath string, opts ListOptions) error {
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		return err
+	}
+	entries, err := listFiles(index, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.filtered() {
+		fmt.Printf("TAR archive contains %d files, %d of them in the size range\n", index.count(), len(entries))
+	} else {
+		fmt.Printf("TAR archive contains %d files\n", len(entries))
+	}
+	if index.ArchiveSize > 0 {
+		fmt.Printf("Archive size: %d bytes in %d entries\n", index.ArchiveSize, index.EntryCount)
+	}
+	if index.ArchiveName != "" {
+		fmt.Printf("Archive: %s\n", index.ArchiveName)
+	}
+
+	// Calculate total size of files
+	var totalSize int64
+	for _, entry := range entries {
+		totalSize += entry.Size
+	}
+
+	fmt.Printf("Total content size: %d bytes\n\n", totalSize)
+	fmt.Println("Files:")
+
+	for _, entry := range entries {
+		name := entry.Key
+		if entry.Path != "" {
+			name = entry.Path
 		}
+		fmt.Printf("- %s (%d bytes)\n", name, entry.Size)
+	}
 
-		size, err := parseInt64(record[2])
+	return nil
+}
+
+// scanTarPaths reads the headers of the TAR and maps the keys index has for
+// its files to their paths, with the index's Root stripped. Files are keyed
+// by occurrence for indexes built with Occurrences. Data is skipped by
+// seeking, so only header blocks are read.
+func scanTarPaths(tarPath string, index *TarIndex) (map[string]string, error) {
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar file: %w", err)
+	}
+	defer file.Close()
+
+	keys := newOccurrenceKeys(index)
+	paths := map[string]string{}
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			return nil, fmt.Errorf("invalid size value: %w", err)
+			return nil, fmt.Errorf("error reading tar header: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeGNUSparse {
+			continue
 		}
 
-		key := record[0]
+		key, _ := keys.next(header.Name)
+		paths[key] = normalizePath(header.Name, index.Root)
+	}
+
+	return paths, nil
+}
+
+// LoadOptions controls how ReadTarIndexWithOptions parses an index
+type LoadOptions struct {
+	// Delimiter separates fields in the CSV index. When zero it is detected
+	// from the header row, falling back to comma.
+	Delimiter rune
+	// SkipChecksum skips verifying the checksum trailer, saving some hashing,
+	// and accepts indexes missing the trailer their version requires
+	SkipChecksum bool
+	// Sorted keeps the entries in a slice sorted by key instead of the Files
+	// map. Lookups use binary search and take much less memory, which pays
+	// off for indexes with millions of entries. Files is nil in this mode.
+	Sorted bool
+}
+
+// ErrIndexCorrupt is returned when an index fails its integrity checks
+var ErrIndexCorrupt = errors.New("index is corrupt")
+
+// checksumRowKey starts the trailing row holding the SHA-256 of the index
+const checksumRowKey = "#sha256"
+
+// indexChecksum accumulates a SHA-256 over index records. It hashes field
+// values rather than raw bytes, so the delimiter and CSV quoting don't matter.
+type indexChecksum struct {
+	h hash.Hash
+}
+
+func newIndexChecksum() *indexChecksum {
+	return &indexChecksum{h: sha256.New()}
+}
+
+func (c *indexChecksum) add(record []string) {
+	io.WriteString(c.h, strings.Join(record, "\x1f"))
+	io.WriteString(c.h, "\n")
+}
+
+func (c *indexChecksum) sum() string {
+	return hex.EncodeToString(c.h.Sum(nil))
+}
+
+func ReadTarIndex(indexPath string) (*TarIndex, error) {
+	return ReadTarIndexWithOptions(indexPath, LoadOptions{})
+}
+
+// ReadTarIndexWithOptions reads the index at indexPath, honoring opts
+func ReadTarIndexWithOptions(indexPath string, opts LoadOptions) (*TarIndex, error) {
+	sc, err := OpenIndexScanner(indexPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
 
-		index.Files[key] = FileIndex{
-			Start: start,
-			Size:  size,
+	var entries []indexEntry
+	files := map[string]FileIndex{}
+	for sc.Scan() {
+		key, fileInfo := sc.Entry()
+		if opts.Sorted {
+			entries = append(entries, indexEntry{Key: key, FileIndex: fileInfo})
+		} else {
+			files[key] = fileInfo
 		}
 	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
 
+	index, err := sc.Index()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Sorted {
+		index.sorted = sortEntries(entries)
+	} else {
+		index.Files = files
+	}
 	return index, nil
 }
 
+// validateEntry checks that an entry read from an index can be read from the
+// TAR, so a corrupted index can't cause a negative seek or a huge allocation.
+// archiveSize is zero if unknown. Size isn't checked against it, as sparse
+// files can be larger than the archive.
+func validateEntry(key string, start, size, archiveSize int64) error {
+	if start < 0 || size < 0 {
+		return fmt.Errorf("%w: negative start or size of %s", ErrIndexCorrupt, key)
+	}
+	if archiveSize > 0 && start > archiveSize-headerSize {
+		return fmt.Errorf("%w: start %d of %s is past the end of the %d byte archive", ErrIndexCorrupt, start, key, archiveSize)
+	}
+	return nil
+}
+
+// checkFits fails if the data of fileInfo doesn't fit in the archive, when
+// its size is known, before memory is allocated for it. Sparse files store
+// less than their size, so they always pass.
+func (ti *TarIndex) checkFits(fileInfo FileIndex) error {
+	if ti.ArchiveSize > 0 && !fileInfo.Sparse && fileInfo.Size > ti.ArchiveSize-fileInfo.Start-headerSize {
+		return fmt.Errorf("failed to read file data: %w", io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// skipBOM discards a UTF-8 byte order mark, which editors on Windows may add
+func skipBOM(br *bufio.Reader) {
+	const bom = "\ufeff"
+	if head, _ := br.Peek(len(bom)); string(head) == bom {
+		br.Discard(len(bom))
+	}
+}
+
+// detectDelimiter returns the character following the name of the first
+// column in the header, which is made of lowercase letters
+func detectDelimiter(br *bufio.Reader) rune {
+	head, _ := br.Peek(64)
+	name := strings.IndexFunc(string(head), func(r rune) bool { return r < 'a' || r > 'z' })
+	if name <= 0 {
+		return ','
+	}
+	r, _ := utf8.DecodeRune(head[name:])
+	if r == utf8.RuneError || r == '\r' || r == '\n' {
+		return ','
+	}
+	return r
+}
+
 func parseInt64(value string) (int64, error) {
 	return strconv.ParseInt(value, 10, 64)
 }
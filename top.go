@@ -0,0 +1,27 @@
+package tarix
+
+import "sort"
+
+// LargestFiles returns the n largest files of the index within the size
+// range of opts, largest first. Files of equal size are ordered by path, then
+// key. Paths are found as by ListFilesWithOptions.
+func LargestFiles(indexPath string, n int, opts ListOptions) ([]ListEntry, error) {
+	index, err := ReadTarIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := listFiles(index, opts)
+	if err != nil {
+		return nil, err
+	}
+	return largest(entries, n), nil
+}
+
+// largest sorts entries by size, largest first, and keeps the first n of them
+func largest(entries []ListEntry, n int) []ListEntry {
+	// listFiles sorts by path and key, which a stable sort keeps for ties
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+	return entries[:max(0, min(n, len(entries)))]
+}
@@ -0,0 +1,37 @@
+package tarix
+
+import "testing"
+
+func TestLargestFiles(t *testing.T) {
+	_, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "aaa", "b.txt": "b", "c.txt": "ccccc", "d.txt": "ddd"})
+
+	entries, err := LargestFiles(tarIndexPath, 3, ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to find the largest files: %v", err)
+	}
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	// a.txt and d.txt are the same size, so they keep their order by key
+	ad := []string{hashFilePath("a.txt"), hashFilePath("d.txt")}
+	if ad[0] > ad[1] {
+		ad[0], ad[1] = ad[1], ad[0]
+	}
+	want := []string{hashFilePath("c.txt"), ad[0], ad[1]}
+	if len(keys) != len(want) {
+		t.Fatalf("Expected %d files, got %d", len(want), len(keys))
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("Expected file %d to be %s, got %s", i, want[i], keys[i])
+		}
+	}
+
+	if entries, err := LargestFiles(tarIndexPath, 10, ListOptions{MaxSize: 3}); err != nil || len(entries) != 3 {
+		t.Errorf("Expected the 3 files of at most 3 bytes, got %d, %v", len(entries), err)
+	}
+	if entries, err := LargestFiles(tarIndexPath, 0, ListOptions{}); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no files for n=0, got %d, %v", len(entries), err)
+	}
+}
@@ -0,0 +1,51 @@
+package tarix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// trailerRowKey starts the index row recording that the TAR ended with an
+// end-of-archive trailer
+const trailerRowKey = "#trailer"
+
+// isTrailer reports whether the end-of-archive trailer, two zero blocks,
+// starts at pos in r
+func isTrailer(r io.ReaderAt, pos int64) (bool, error) {
+	blocks := make([]byte, 2*headerSize)
+	n, err := r.ReadAt(blocks, pos)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read end of archive: %w", err)
+	}
+	return n == len(blocks) && isZero(blocks), nil
+}
+
+// nextNonZeroBlock returns the offset of the first block at or after pos in
+// r that isn't all zeros, skipping the padding after a trailer. ok is false
+// if there is none.
+func nextNonZeroBlock(r io.ReaderAt, pos int64) (next int64, ok bool, err error) {
+	buf := make([]byte, 64*headerSize)
+	for {
+		n, err := r.ReadAt(buf, pos)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, false, fmt.Errorf("failed to read after end of archive: %w", err)
+		}
+		// Only whole blocks can start an archive
+		n -= n % int(headerSize)
+		for off := 0; off < n; off += int(headerSize) {
+			if !isZero(buf[off : off+int(headerSize)]) {
+				return pos + int64(off), true, nil
+			}
+		}
+		if n < len(buf) {
+			return 0, false, nil
+		}
+		pos += int64(n)
+	}
+}
+
+func isZero(b []byte) bool {
+	return len(bytes.Trim(b, "\x00")) == 0
+}
@@ -0,0 +1,92 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarBytes returns a TAR of files, with its trailer if closed is set
+func tarBytes(t *testing.T, files map[string]string, closed bool) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write data: %v", err)
+		}
+	}
+	if closed {
+		if err := tw.Close(); err != nil {
+			t.Fatalf("Failed to close tar writer: %v", err)
+		}
+	} else if err := tw.Flush(); err != nil {
+		t.Fatalf("Failed to flush tar writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestTrailerDetection(t *testing.T) {
+	first := tarBytes(t, map[string]string{"a.txt": "a"}, true)
+	second := tarBytes(t, map[string]string{"b.txt": "b"}, true)
+	// Pad the first archive to a 10 KiB record like GNU tar does
+	padded := append(append([]byte{}, first...), make([]byte, 10240-len(first))...)
+
+	tests := []struct {
+		name         string
+		data         []byte
+		concatenated bool
+		files        []string
+		trailer      bool
+	}{
+		{"clean", first, false, []string{"a.txt"}, true},
+		{"truncated", tarBytes(t, map[string]string{"a.txt": "a"}, false), false, []string{"a.txt"}, false},
+		{"concatenated ignored", append(append([]byte{}, padded...), second...), false, []string{"a.txt"}, true},
+		{"concatenated", append(append([]byte{}, padded...), second...), true, []string{"a.txt", "b.txt"}, true},
+		{"trailing padding", append(append([]byte{}, first...), make([]byte, 4096)...), true, []string{"a.txt"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tarFilePath := filepath.Join(dir, "trailer.tar")
+			if err := os.WriteFile(tarFilePath, tt.data, 0644); err != nil {
+				t.Fatalf("Failed to write TAR: %v", err)
+			}
+
+			tarIndexPath := filepath.Join(dir, "trailer.tar.index.json")
+			if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Concatenated: tt.concatenated}); err != nil {
+				t.Fatalf("Failed to create TAR index: %v", err)
+			}
+
+			th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+			if err != nil {
+				t.Fatalf("Failed to open handle: %v", err)
+			}
+			defer th.Close()
+
+			if th.Index.Trailer != tt.trailer {
+				t.Errorf("Expected trailer %v, got %v", tt.trailer, th.Index.Trailer)
+			}
+			if len(th.Index.Files) != len(tt.files) {
+				t.Errorf("Expected %d files, got %d", len(tt.files), len(th.Index.Files))
+			}
+			for _, name := range tt.files {
+				data, err := th.ExtractBytesOfFile(name)
+				if err != nil {
+					t.Errorf("Failed to extract %s: %v", name, err)
+					continue
+				}
+				if string(data) != name[:1] {
+					t.Errorf("Unexpected content of %s: %q", name, data)
+				}
+			}
+		})
+	}
+}
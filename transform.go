@@ -0,0 +1,33 @@
+package tarix
+
+import "io"
+
+// Transform wraps the data of a file as it is extracted, e.g. to decrypt or
+// recompress it. Errors, like a failed decryption, are returned from the Read
+// method of the reader it returns and abort the extraction.
+type Transform func(r io.Reader) io.Reader
+
+// apply returns r wrapped by t, or r itself if t is nil
+func (t Transform) apply(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return t(r)
+}
+
+// truncatedReader fails with io.ErrUnexpectedEOF if r ends before n bytes
+// were read from it. The size of transformed data can't be checked, so this
+// catches truncated archives under a Transform.
+type truncatedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (tr *truncatedReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	tr.n -= int64(n)
+	if err == io.EOF && tr.n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
@@ -0,0 +1,93 @@
+package tarix
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+)
+
+// xorReader flips every byte of r with key, standing in for a cipher
+type xorReader struct {
+	r   io.Reader
+	key byte
+}
+
+func (x xorReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	for i := range p[:n] {
+		p[i] ^= x.key
+	}
+	return n, err
+}
+
+func xor(data string, key byte) string {
+	out := []byte(data)
+	for i := range out {
+		out[i] ^= key
+	}
+	return string(out)
+}
+
+func TestExtractTransform(t *testing.T) {
+	const key = 0x5a
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{
+		"a.txt": xor("secret a", key),
+		"b.txt": xor("secret b", key),
+	})
+	decrypt := func(r io.Reader) io.Reader { return xorReader{r, key} }
+
+	fsys := NewMemFS()
+	opts := ExtractOptions{FS: fsys, Transform: decrypt}
+	if err := ExtractFileFromTarWithOptions(tarFilePath, tarIndexPath, "a.txt", "a.txt", opts); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if got := string(fsys.Files["a.txt"].Data); got != "secret a" {
+		t.Errorf("Expected decrypted content, got %q", got)
+	}
+
+	fsys = NewMemFS()
+	opts.FS = fsys
+	if err := ExtractAllWithOptions(tarFilePath, tarIndexPath, "out", opts); err != nil {
+		t.Fatalf("Failed to extract all: %v", err)
+	}
+	if got := string(fsys.Files[filepath.Join("out", "b.txt")].Data); got != "secret b" {
+		t.Errorf("Expected decrypted content, got %q", got)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	// Errors of the transform abort the copy
+	errBadKey := errors.New("bad key")
+	th.Transform = func(r io.Reader) io.Reader { return io.MultiReader(io.LimitReader(r, 2), iotest.ErrReader(errBadKey)) }
+	var buf bytes.Buffer
+	if _, err := th.WriteFileTo("a.txt", &buf); !errors.Is(err, errBadKey) {
+		t.Errorf("Expected the transform's error, got %v", err)
+	}
+}
+
+func TestExtractTransformTruncated(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"big.bin": string(make([]byte, 4000))})
+
+	// Cut the archive in the middle of the file's data
+	if err := os.Truncate(tarFilePath, 2048); err != nil {
+		t.Fatalf("Failed to truncate TAR: %v", err)
+	}
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	th.Transform = func(r io.Reader) io.Reader { return r }
+	if _, err := th.WriteFileTo("big.bin", io.Discard); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
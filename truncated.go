@@ -0,0 +1,31 @@
+package tarix
+
+import (
+	"fmt"
+	"io"
+)
+
+// TruncatedError is returned when the TAR ends before the data of a file
+// does, as with an archive cut short by an interrupted copy. It wraps
+// io.ErrUnexpectedEOF.
+type TruncatedError struct {
+	// Name is the path or key the file was looked up by
+	Name string
+	// Size is the size of the file according to the index
+	Size int64
+	// Available is how many bytes of it are in the TAR
+	Available int64
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("file %s is truncated: only %d of %d bytes are in the tar", e.Name, e.Available, e.Size)
+}
+
+func (e *TruncatedError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// available returns how many bytes of the data of fileInfo the TAR holds
+func (th *TarixHandle) available(fileInfo FileIndex) int64 {
+	return max(0, min(fileInfo.Size, th.Source.Size()-fileInfo.Start-headerSize))
+}
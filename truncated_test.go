@@ -0,0 +1,100 @@
+package tarix
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractTruncated(t *testing.T) {
+	content := strings.Repeat("0123456789", 200)
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"big.txt": content})
+
+	// Cut the archive 500 bytes into the data of big.txt
+	if err := os.Truncate(tarFilePath, headerSize+500); err != nil {
+		t.Fatalf("Failed to truncate TAR: %v", err)
+	}
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+
+	data, err := th.ExtractBytesOfFile("big.txt")
+	var truncated *TruncatedError
+	if !errors.As(err, &truncated) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected a TruncatedError, got %v", err)
+	}
+	if truncated.Name != "big.txt" || truncated.Size != int64(len(content)) || truncated.Available != 500 {
+		t.Errorf("Unexpected error details: %+v", truncated)
+	}
+	if data != nil {
+		t.Errorf("Expected no data without Partial, got %d bytes", len(data))
+	}
+	if !strings.Contains(err.Error(), "only 500 of 2000 bytes") {
+		t.Errorf("Expected the available and expected sizes in %q", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := th.WriteFileTo("big.txt", &buf); !errors.As(err, &truncated) || truncated.Available != 500 {
+		t.Errorf("Expected a TruncatedError from WriteFileTo, got %v", err)
+	}
+
+	th.Partial = true
+	data, err = th.ExtractBytesOfFile("big.txt")
+	if !errors.As(err, &truncated) {
+		t.Errorf("Expected a TruncatedError with Partial, got %v", err)
+	}
+	if string(data) != content[:500] {
+		t.Errorf("Expected the first 500 bytes, got %d bytes", len(data))
+	}
+}
+
+func TestIndexTruncated(t *testing.T) {
+	content := strings.Repeat("0123456789", 200)
+	tarFilePath := filepath.Join(t.TempDir(), "truncated.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "whole", "big.txt": content})
+
+	// Cut the archive 500 bytes into the data of big.txt. Without a volume
+	// label it is truncated, not a volume of a multi-volume archive.
+	if err := os.Truncate(tarFilePath, 3*headerSize+500); err != nil {
+		t.Fatalf("Failed to truncate TAR: %v", err)
+	}
+	var log bytes.Buffer
+	tarIndexPath := tarFilePath + ".index"
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Log: &log, ContentHash: true, MIMETypes: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	if !strings.Contains(log.String(), "big.txt at offset 1024 runs past the end of the archive, which is truncated") {
+		t.Errorf("Expected a warning about big.txt, got %q", log.String())
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	fileInfo, err := th.Stat("big.txt")
+	if err != nil {
+		t.Fatalf("Failed to stat big.txt: %v", err)
+	}
+	if fileInfo.NonExtractable || fileInfo.Size != int64(len(content)) || fileInfo.ContentHash != "" {
+		t.Errorf("Unexpected entry of big.txt: %+v", fileInfo)
+	}
+
+	var truncated *TruncatedError
+	if _, err := th.ExtractBytesOfFile("big.txt"); !errors.As(err, &truncated) || truncated.Available != 500 {
+		t.Errorf("Expected a TruncatedError, got %v", err)
+	}
+	th.Partial = true
+	if data, _ := th.ExtractBytesOfFile("big.txt"); string(data) != content[:500] {
+		t.Errorf("Expected the first 500 bytes, got %d bytes", len(data))
+	}
+	if data, err := th.ExtractBytesOfFile("a.txt"); err != nil || string(data) != "whole" {
+		t.Errorf("Unexpected content of a.txt: %q, %v", data, err)
+	}
+}
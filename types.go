@@ -1,13 +1,90 @@
 package tarix
 
+import "archive/tar"
+
 // FileIndex represents information about a file's position in the TAR
 type FileIndex struct {
-	Start int64 `json:"start"` // Starting byte position in TAR
-	Size  int64 `json:"size"`  // Size of the file in bytes
+	Start       int64  `json:"start"`                  // Starting byte position in TAR
+	Size        int64  `json:"size"`                   // Size of the file in bytes
+	Path        string `json:"path,omitempty"`         // Normalized path of the file, if stored
+	ContentHash string `json:"content_hash,omitempty"` // Hex SHA-256 of the file content, if computed
+	Compressed  bool   `json:"compressed,omitempty"`   // The file has the extension of a registered Decompressor
+	// HeaderBlocks is the number of PAX or GNU extended header blocks before
+	// the entry's own header at Start. The data always follows that header;
+	// this is needed to read the full header, e.g. a long name.
+	HeaderBlocks int `json:"header_blocks,omitempty"`
+	// MIMEType is the type sniffed from the start of the file, if recorded
+	// with IndexOptions.MIMETypes
+	MIMEType string `json:"mime_type,omitempty"`
+	// NonExtractable is set for files of which the TAR holds only a part, as
+	// files split across the volumes of a GNU multi-volume archive. Extracting
+	// them fails with ErrNotExtractable.
+	NonExtractable bool `json:"non_extractable,omitempty"`
+	// Sparse is set for GNU sparse files, whose data in the TAR is shorter
+	// than Size. They are read through their headers, which map where the
+	// data goes, with the holes filled with zeros.
+	Sparse bool `json:"sparse,omitempty"`
+}
+
+// DataOffset returns the offset of the file's data in the TAR, right after
+// its own header. Extended headers come before Start, so they don't move it.
+// For sparse files it is where their stored fragments begin.
+func (fi FileIndex) DataOffset() int64 {
+	return fi.Start + headerSize
+}
+
+// PaddedEntryLength returns the bytes the member of fi takes in the TAR:
+// its extended header blocks, its own header and its data padded to whole
+// blocks, starting HeaderBlocks blocks before Start. Sparse files store less
+// data than their Size, so for them it is an upper bound.
+func PaddedEntryLength(fi FileIndex) int64 {
+	return int64(fi.HeaderBlocks)*headerSize + headerSize + padToBlock(fi.Size)
 }
 
-// TarIndex represents the full index of a TAR file
 type TarIndex struct {
-	Files map[string]FileIndex `json:"files"` // List of files in the TAR
+	// Files holds the entries by key while an index is built or loaded
+	// without LoadOptions.Sorted. It is internal: to read an index use Get,
+	// Len, Keys and Walk, which work however the entries are stored, as Files
+	// may be replaced by other stores.
+	Files  map[string]FileIndex `json:"files"`
+	Format tar.Format           `json:"format,omitempty"` // Format of the TAR, if known
+	// KeyScheme names the KeyScheme the keys were made with, "" for the default
+	KeyScheme string `json:"key_scheme,omitempty"`
+	// Root is the IndexOptions.Root stripped from paths before keying them
+	Root string `json:"root,omitempty"`
+	// Trailer is set if the TAR ended with an end-of-archive trailer. It is
+	// unset for truncated archives and for indexes that didn't record it.
+	Trailer bool `json:"trailer,omitempty"`
+	// ArchiveName is the base name of the TAR the index was built from, "" for
+	// indexes that didn't record it
+	ArchiveName string `json:"archive_name,omitempty"`
+	// ArchiveSize is the size of the TAR in bytes and EntryCount the number of
+	// entries in it, including directories and others that aren't indexed.
+	// Both are zero for indexes that didn't record them.
+	ArchiveSize int64 `json:"archive_size,omitempty"`
+	EntryCount  int64 `json:"entry_count,omitempty"`
+	// Occurrences is set if files are keyed by path and occurrence, see
+	// IndexOptions.Occurrences
+	Occurrences bool `json:"occurrences,omitempty"`
+	// Normalize is the Unicode form paths were put in before keying them,
+	// see IndexOptions.Normalize
+	Normalize NormForm `json:"normalize,omitempty"`
+
+	// keys is the function of KeyScheme, set when the index is built or loaded
+	keys KeyFunc
+
+	// sorted holds the entries instead of Files when loaded with LoadOptions.Sorted
+	sorted []indexEntry
+}
+
+// Progress describes how far a long-running operation has got. Totals are
+// zero when they are not known up front.
+type Progress struct {
+	FilesDone  int64
+	FilesTotal int64
+	BytesDone  int64
+	BytesTotal int64
 }
 
+// ProgressFunc is called with updated Progress as an operation advances
+type ProgressFunc func(Progress)
@@ -0,0 +1,62 @@
+package tarix
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPaddedEntryLength(t *testing.T) {
+	for _, tc := range []struct {
+		fi   FileIndex
+		want int64
+	}{
+		{FileIndex{Size: 0}, 512},
+		{FileIndex{Size: 1}, 1024},
+		{FileIndex{Size: 511}, 1024},
+		{FileIndex{Size: 512}, 1024},
+		{FileIndex{Size: 513}, 1536},
+		{FileIndex{Size: 512, HeaderBlocks: 2}, 2048},
+	} {
+		if got := PaddedEntryLength(tc.fi); got != tc.want {
+			t.Errorf("PaddedEntryLength(%+v) = %d, want %d", tc.fi, got, tc.want)
+		}
+	}
+
+	// The members of an archive of files cover it up to the trailer
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "padded.tar")
+	writeTestTar(t, tarFilePath, map[string]string{
+		"empty.txt":                          "",
+		"511.txt":                            strings.Repeat("x", 511),
+		"512.txt":                            strings.Repeat("x", 512),
+		"513.txt":                            strings.Repeat("x", 513),
+		strings.Repeat("long/", 40) + "name": "long",
+	})
+	tarIndexPath := filepath.Join(dir, "padded.tar.index.json")
+	if err := CreateTarIndex(tarFilePath, tarIndexPath); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR index: %v", err)
+	}
+	var end int64
+	index.each(func(_ string, fi FileIndex) {
+		end = max(end, fi.Start-int64(fi.HeaderBlocks)*headerSize+PaddedEntryLength(fi))
+	})
+	var total int64
+	index.each(func(_ string, fi FileIndex) { total += PaddedEntryLength(fi) })
+	if total != end {
+		t.Errorf("Expected members to take %d bytes up to the trailer, got %d", end, total)
+	}
+	file, err := os.Open(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open TAR: %v", err)
+	}
+	defer file.Close()
+	if trailer, err := isTrailer(file, end); err != nil || !trailer {
+		t.Errorf("Expected the trailer after the last member at %d, got %v, %v", end, trailer, err)
+	}
+}
@@ -0,0 +1,87 @@
+package tarix
+
+import "fmt"
+
+// VerifyOptions controls VerifyIndex
+type VerifyOptions struct {
+	// Deep re-reads the content of every file with a stored checksum and
+	// compares its SHA-256 to it. Files without one only have their header
+	// checked, so indexes built without -checksum verify without reading data.
+	Deep bool
+}
+
+// FileVerification is the outcome of verifying one file of an index
+type FileVerification struct {
+	Key  string
+	Path string // Empty if the index has no stored paths
+	// Hashed is set when the content was re-hashed against its checksum
+	Hashed bool
+	// Err is nil if the file passed
+	Err error
+}
+
+// VerifyResult holds the outcome of VerifyIndex for each file, in key order
+type VerifyResult struct {
+	Files  []FileVerification
+	Passed int
+	Failed int
+	Hashed int // Number of files whose content was re-hashed
+}
+
+// OK reports whether every file passed
+func (r *VerifyResult) OK() bool {
+	return r.Failed == 0
+}
+
+// VerifyIndex checks every file of the index against the TAR: that a regular
+// file header with the indexed name and size is at its offset and, with
+// opts.Deep, that its content matches the stored checksum. Content is hashed
+// as it is read, so files are never held in memory. Files that fail are
+// reported in the result; the error is only for failing to open either file.
+func VerifyIndex(tarPath, indexPath string, opts VerifyOptions) (*VerifyResult, error) {
+	th, err := NewTarixHandle(tarPath, indexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer th.Close()
+	return th.VerifyIndex(opts), nil
+}
+
+// VerifyIndex checks every file of the handle's index against its TAR, see
+// the VerifyIndex function
+func (th *TarixHandle) VerifyIndex(opts VerifyOptions) *VerifyResult {
+	result := &VerifyResult{Files: make([]FileVerification, 0, th.Index.count())}
+	th.Index.Walk(func(key string, fileInfo FileIndex) error {
+		file := FileVerification{Key: key, Path: fileInfo.Path}
+		file.Hashed = opts.Deep && fileInfo.ContentHash != ""
+		file.Err = th.verifyFile(key, fileInfo, file.Hashed)
+
+		if file.Err != nil {
+			result.Failed++
+		} else {
+			result.Passed++
+		}
+		if file.Hashed {
+			result.Hashed++
+		}
+		result.Files = append(result.Files, file)
+		return nil
+	})
+	return result
+}
+
+// verifyFile checks the header of the file and, if deep is set, its content
+func (th *TarixHandle) verifyFile(key string, fileInfo FileIndex, deep bool) error {
+	_, tr, err := th.readEntry(key, fileInfo)
+	if err != nil || !deep {
+		return err
+	}
+	hash, err := hashContent(tr)
+	if err != nil {
+		return err
+	}
+	if hash != fileInfo.ContentHash {
+		return fmt.Errorf("%w: content at offset %d has checksum %s, index has %s", ErrIndexStale, fileInfo.Start, hash, fileInfo.ContentHash)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package tarix
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyIndex(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "verify.tar")
+	writeTestTar(t, tarFilePath, map[string]string{"a.txt": "original", "b.txt": "untouched"})
+
+	tarIndexPath := filepath.Join(dir, "verify.tar.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{ContentHash: true, StorePaths: true}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+
+	result, err := VerifyIndex(tarFilePath, tarIndexPath, VerifyOptions{Deep: true})
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !result.OK() || result.Passed != 2 || result.Hashed != 2 {
+		t.Errorf("Expected both files to pass hashed, got %+v", result)
+	}
+
+	// Change the content of a.txt in place, keeping its size
+	data, err := os.ReadFile(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read TAR: %v", err)
+	}
+	data = bytes.Replace(data, []byte("original"), []byte("modified"), 1)
+	if err := os.WriteFile(tarFilePath, data, 0644); err != nil {
+		t.Fatalf("Failed to write TAR: %v", err)
+	}
+
+	// The headers still match, so only a deep verify notices
+	result, err = VerifyIndex(tarFilePath, tarIndexPath, VerifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !result.OK() || result.Hashed != 0 {
+		t.Errorf("Expected the headers to pass unhashed, got %+v", result)
+	}
+
+	result, err = VerifyIndex(tarFilePath, tarIndexPath, VerifyOptions{Deep: true})
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if result.OK() || result.Passed != 1 || result.Failed != 1 {
+		t.Fatalf("Expected one failure, got %+v", result)
+	}
+	for _, file := range result.Files {
+		failed := file.Err != nil
+		if failed != (file.Path == "a.txt") {
+			t.Errorf("Unexpected outcome for %s: %v", file.Path, file.Err)
+		}
+		if failed && !errors.Is(file.Err, ErrIndexStale) {
+			t.Errorf("Expected ErrIndexStale, got %v", file.Err)
+		}
+	}
+}
+
+func TestVerifyIndexWithoutChecksums(t *testing.T) {
+	tarFilePath, tarIndexPath := createIndexedTar(t, map[string]string{"a.txt": "a"})
+
+	// Without stored checksums a deep verify only checks headers
+	result, err := VerifyIndex(tarFilePath, tarIndexPath, VerifyOptions{Deep: true})
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !result.OK() || result.Passed != 1 || result.Hashed != 0 {
+		t.Errorf("Expected a header-only pass, got %+v", result)
+	}
+}
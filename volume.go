@@ -0,0 +1,26 @@
+package tarix
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Typeflags of GNU multi-volume archives, which archive/tar has no names for
+const (
+	// typeGNUVolume is the label of a volume, an entry with no data
+	typeGNUVolume = 'V'
+	// typeGNUMultiVolume continues a file begun in the previous volume
+	typeGNUMultiVolume = 'M'
+)
+
+// ErrNotExtractable is returned for files the index marks NonExtractable
+var ErrNotExtractable = errors.New("file can't be extracted from this archive")
+
+// checkExtractable fails with ErrNotExtractable if fileInfo, the entry of
+// key, holds only part of a file
+func checkExtractable(key string, fileInfo FileIndex) error {
+	if fileInfo.NonExtractable {
+		return fmt.Errorf("%w: %s at offset %d is only partly in the archive", ErrNotExtractable, key, fileInfo.Start)
+	}
+	return nil
+}
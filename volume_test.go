@@ -0,0 +1,114 @@
+package tarix
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultiVolume(t *testing.T) {
+	// The fixtures are the two volumes of GNU tar -M -L 10 --label=backup
+	// of big.txt, 15000 bytes of "x", and small.txt. Both start with a volume
+	// label; big.txt runs past the end of the first volume and is continued
+	// by an 'M' entry in the second.
+	tests := []struct {
+		fixture     string
+		start, size int64
+		warning     string
+		extractable map[string]string
+		files       int
+	}{
+		{"multivolume-1.tar", 512, 15000, "runs past the end of the volume", nil, 1},
+		{"multivolume-2.tar", 512, 5784, "continues a file of the previous volume", map[string]string{"small.txt": "small\n"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			tarFilePath := filepath.Join("testdata", tt.fixture)
+			tarIndexPath := filepath.Join(t.TempDir(), "index.csv")
+			var log bytes.Buffer
+			if err := CreateTarIndexWithOptions(tarFilePath, tarIndexPath, IndexOptions{Log: &log, ContentHash: true, MIMETypes: true}); err != nil {
+				t.Fatalf("Failed to create TAR index: %v", err)
+			}
+			if !strings.Contains(log.String(), "big.txt at offset 512 "+tt.warning) {
+				t.Errorf("Expected a warning about big.txt, got %q", log.String())
+			}
+
+			th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+			if err != nil {
+				t.Fatalf("Failed to open handle: %v", err)
+			}
+			defer th.Close()
+			if th.Index.Len() != tt.files {
+				t.Errorf("Expected %d files, got %d", tt.files, th.Index.Len())
+			}
+
+			fileInfo, err := th.Stat("big.txt")
+			if err != nil {
+				t.Fatalf("Failed to stat big.txt: %v", err)
+			}
+			if !fileInfo.NonExtractable || fileInfo.Start != tt.start || fileInfo.Size != tt.size {
+				t.Errorf("Unexpected entry of big.txt: %+v", fileInfo)
+			}
+			if fileInfo.ContentHash != "" || fileInfo.MIMEType != "" {
+				t.Errorf("Expected no content hash or MIME type for a partial file, got %+v", fileInfo)
+			}
+
+			if _, err := th.ExtractBytesOfFile("big.txt"); !errors.Is(err, ErrNotExtractable) {
+				t.Errorf("Expected ErrNotExtractable extracting big.txt, got %v", err)
+			}
+			th.Verify = true
+			if _, err := th.ExtractBytesOfFile("big.txt"); !errors.Is(err, ErrNotExtractable) {
+				t.Errorf("Expected ErrNotExtractable verifying big.txt, got %v", err)
+			}
+			err = ExtractFileFromTar(tarFilePath, tarIndexPath, "big.txt", filepath.Join(t.TempDir(), "big.txt"))
+			if !errors.Is(err, ErrNotExtractable) {
+				t.Errorf("Expected ErrNotExtractable from ExtractFileFromTar, got %v", err)
+			}
+
+			for name, content := range tt.extractable {
+				data, err := th.ExtractBytesOfFile(name)
+				if err != nil {
+					t.Fatalf("Failed to extract %s: %v", name, err)
+				}
+				if string(data) != content {
+					t.Errorf("Unexpected content of %s: %q", name, data)
+				}
+			}
+		})
+	}
+}
+
+func TestNonExtractableRoundTrip(t *testing.T) {
+	index := &TarIndex{Files: map[string]FileIndex{
+		hashFilePath("part.txt"):  {Start: 512, Size: 5784, NonExtractable: true},
+		hashFilePath("whole.txt"): {Start: 7168, Size: 6},
+	}}
+	indexPath := filepath.Join(t.TempDir(), "index.csv")
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	loaded, err := ReadTarIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if c := CompareIndexes(index, loaded); !c.Equal() {
+		t.Errorf("Index changed in a round trip: %+v", c)
+	}
+
+	// The column is left out when no file needs it
+	delete(index.Files, hashFilePath("part.txt"))
+	if err := WriteTarIndex(index, indexPath); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index file: %v", err)
+	}
+	if strings.Contains(string(data), "nonextractable") {
+		t.Errorf("Expected no nonextractable column, got %q", data)
+	}
+}
@@ -0,0 +1,117 @@
+package tarix
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// AddDirOptions control how AddDir walks a directory
+type AddDirOptions struct {
+	// FollowSymlinks stores what symlinks point to instead of the links, like
+	// tar -h, so the archive doesn't depend on files outside of it
+	FollowSymlinks bool
+	// OnSkip, if set, is called with the path of each symlink skipped because
+	// following it would loop
+	OnSkip func(path string, err error)
+}
+
+// AddDir writes the contents of dir to the archive, recursively and in name
+// order. Entries are named relative to dir.
+func (w *TarixWriter) AddDir(dir string, opts AddDirOptions) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return w.addDir(dir, "", []os.FileInfo{info}, opts)
+}
+
+// addDir writes the entries of fsPath under the archive name prefix.
+// ancestors are the directories being walked, to detect symlink loops.
+func (w *TarixWriter) addDir(fsPath, prefix string, ancestors []os.FileInfo, opts AddDirOptions) error {
+	entries, err := os.ReadDir(fsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(fsPath, entry.Name())
+		name := path.Join(prefix, entry.Name())
+
+		info, err := os.Lstat(entryPath)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", entryPath, err)
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if opts.FollowSymlinks {
+				if info, err = os.Stat(entryPath); err != nil {
+					return fmt.Errorf("failed to follow symlink %s: %w", entryPath, err)
+				}
+			} else if link, err = os.Readlink(entryPath); err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", entryPath, err)
+			}
+		}
+
+		// Compares device and inode, so a link back to a directory being
+		// walked is caught however it is spelled
+		if info.IsDir() && isAncestor(info, ancestors) {
+			if opts.OnSkip != nil {
+				opts.OnSkip(entryPath, fmt.Errorf("symlink loop to %s", info.Name()))
+			}
+			continue
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("failed to create header for %s: %w", entryPath, err)
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write header for %s: %w", entryPath, err)
+		}
+
+		switch {
+		case info.IsDir():
+			if err := w.addDir(entryPath, name, append(ancestors, info), opts); err != nil {
+				return err
+			}
+		case hdr.Typeflag == tar.TypeReg:
+			if err := w.addFile(entryPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *TarixWriter) addFile(fsPath string) error {
+	file, err := os.Open(fsPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fsPath, err)
+	}
+	return nil
+}
+
+func isAncestor(info os.FileInfo, ancestors []os.FileInfo) bool {
+	for _, ancestor := range ancestors {
+		if os.SameFile(info, ancestor) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package tarix
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddDirFollowSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(src, "sub"), outside} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	for name, content := range map[string]string{
+		filepath.Join(src, "a.txt"):        "first",
+		filepath.Join(src, "sub", "b.txt"): "second",
+		filepath.Join(outside, "c.txt"):    "outside",
+	} {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	for link, target := range map[string]string{
+		filepath.Join(src, "link.txt"):    "a.txt",
+		filepath.Join(src, "ext"):         outside,
+		filepath.Join(src, "sub", "loop"): "..",
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+
+	write := func(name string, opts AddDirOptions) (string, *TarixHandle) {
+		tarFilePath := filepath.Join(dir, name+".tar")
+		tarIndexPath := tarFilePath + ".index.json"
+		tarFile, err := os.Create(tarFilePath)
+		if err != nil {
+			t.Fatalf("Failed to create TAR: %v", err)
+		}
+		defer tarFile.Close()
+
+		w := NewTarixWriter(tarFile)
+		if err := w.AddDir(src, opts); err != nil {
+			t.Fatalf("Failed to add directory: %v", err)
+		}
+		if err := w.Close(tarIndexPath); err != nil {
+			t.Fatalf("Failed to close writer: %v", err)
+		}
+		th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+		if err != nil {
+			t.Fatalf("Failed to open handle: %v", err)
+		}
+		t.Cleanup(func() { th.Close() })
+		return tarFilePath, th
+	}
+
+	// By default links are stored as links and only regular files are indexed
+	tarFilePath, th := write("links", AddDirOptions{})
+	types := tarTypes(t, tarFilePath)
+	for _, name := range []string{"link.txt", "ext", "sub/loop"} {
+		if types[name] != tar.TypeSymlink {
+			t.Errorf("Expected %s to be a symlink, got %q", name, types[name])
+		}
+	}
+	if len(th.Index.Files) != 2 {
+		t.Errorf("Expected 2 indexed files, got %d", len(th.Index.Files))
+	}
+
+	var skipped []string
+	tarFilePath, th = write("followed", AddDirOptions{
+		FollowSymlinks: true,
+		OnSkip:         func(path string, err error) { skipped = append(skipped, path) },
+	})
+	types = tarTypes(t, tarFilePath)
+	for name, content := range map[string]string{
+		"a.txt":     "first",
+		"sub/b.txt": "second",
+		"link.txt":  "first",
+		"ext/c.txt": "outside",
+	} {
+		if types[name] != tar.TypeReg {
+			t.Errorf("Expected %s to be a regular file, got %q", name, types[name])
+		}
+		data, err := th.ExtractBytesOfFile(name)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+	if types["ext/"] != tar.TypeDir {
+		t.Errorf("Expected followed directory link to be a directory, got %q", types["ext/"])
+	}
+	if _, ok := types["sub/loop/"]; ok {
+		t.Error("Expected the looping symlink to be skipped")
+	}
+	if len(skipped) != 1 || skipped[0] != filepath.Join(src, "sub", "loop") {
+		t.Errorf("Expected the loop to be reported, got %v", skipped)
+	}
+}
+
+// tarTypes returns the type of each entry in the TAR at tarPath by name
+func tarTypes(t *testing.T, tarPath string) map[string]byte {
+	t.Helper()
+	f, err := os.Open(tarPath)
+	if err != nil {
+		t.Fatalf("Failed to open TAR: %v", err)
+	}
+	defer f.Close()
+
+	types := map[string]byte{}
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read TAR: %v", err)
+		}
+		types[hdr.Name] = hdr.Typeflag
+	}
+	return types
+}
@@ -0,0 +1,145 @@
+package tarix
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// countingWriter counts the bytes written through it, and copies them to
+// capture if set
+type countingWriter struct {
+	w       io.Writer
+	n       int64
+	capture *bytes.Buffer
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	if cw.capture != nil {
+		cw.capture.Write(p[:n])
+	}
+	return n, err
+}
+
+// TarixWriter writes a TAR archive and builds its index in the same pass.
+// Offsets are counted from the first byte written through the TarixWriter.
+type TarixWriter struct {
+	tw      *tar.Writer
+	cw      *countingWriter
+	formats tar.Format
+	entries int64
+	Index   *TarIndex
+}
+
+// WriterOptions controls NewTarixWriterWithOptions
+type WriterOptions struct {
+	// KeyScheme turns paths into index keys, as IndexOptions.KeyScheme does
+	KeyScheme KeyScheme
+	// Normalize puts paths in a Unicode normalization form before keying
+	// them, NFC by default, as IndexOptions.Normalize does, so the index
+	// matches one built by CreateTarIndex
+	Normalize NormForm
+}
+
+// NewTarixWriter creates a TarixWriter writing the archive to w
+func NewTarixWriter(w io.Writer) *TarixWriter {
+	tw, _ := NewTarixWriterWithOptions(w, WriterOptions{})
+	return tw
+}
+
+// NewTarixWriterWithOptions is like NewTarixWriter, honoring opts. It fails
+// if the key scheme or normalization form is unknown.
+func NewTarixWriterWithOptions(w io.Writer, opts WriterOptions) (*TarixWriter, error) {
+	indexOpts := IndexOptions{KeyScheme: opts.KeyScheme, Normalize: opts.Normalize}
+	if _, err := ParseNormForm(string(opts.Normalize)); err != nil {
+		return nil, err
+	}
+	keyScheme, err := indexOpts.keyScheme()
+	if err != nil {
+		return nil, err
+	}
+	index := &TarIndex{
+		Files:     map[string]FileIndex{},
+		Normalize: indexOpts.normForm(),
+		keys:      keyScheme.Func,
+	}
+	if keyScheme.Name != MD5KeyScheme.Name {
+		index.KeyScheme = keyScheme.Name
+	}
+	cw := &countingWriter{w: w}
+	return &TarixWriter{
+		tw:    tar.NewWriter(cw),
+		cw:    cw,
+		Index: index,
+	}, nil
+}
+
+// WriteHeader writes hdr and starts a new entry, like tar.Writer.WriteHeader.
+// Regular files are recorded in the index at the offset of their header.
+func (w *TarixWriter) WriteHeader(hdr *tar.Header) error {
+	// Flush padding of the previous entry so the counter points at the new header
+	if err := w.tw.Flush(); err != nil {
+		return err
+	}
+	headerPos := w.cw.n
+
+	cleanFilePath := normalizePath(hdr.Name, "")
+	cleanFilePathHash := w.Index.keyFunc()(cleanFilePath)
+	// Headers without a type are written as regular files, or directories if
+	// their names end in a slash, as tar.Writer does
+	regular := hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeGNUSparse ||
+		hdr.Typeflag == tar.TypeRegA && !strings.HasSuffix(hdr.Name, "/")
+	if regular {
+		if _, exists := w.Index.Files[cleanFilePathHash]; exists {
+			return fmt.Errorf("duplicate file path found for path %s: %s", cleanFilePath, cleanFilePathHash)
+		}
+	}
+
+	// tar.Writer may write extended headers first, so capture the header
+	// blocks to find the entry's own header among them
+	var blocks bytes.Buffer
+	w.cw.capture = &blocks
+	err := w.tw.WriteHeader(hdr)
+	w.cw.capture = nil
+	if err != nil {
+		return err
+	}
+	entryPos, format, err := entryHeader(bytes.NewReader(blocks.Bytes()), 0)
+	if err != nil {
+		return err
+	}
+	w.formats |= format
+	w.entries++
+
+	if regular {
+		w.Index.Files[cleanFilePathHash] = FileIndex{
+			Start:        headerPos + entryPos,
+			Size:         hdr.Size,
+			Compressed:   isCompressed(cleanFilePath),
+			HeaderBlocks: int(entryPos / headerSize),
+		}
+	}
+	return nil
+}
+
+// Write writes to the current entry, like tar.Writer.Write
+func (w *TarixWriter) Write(b []byte) (int, error) {
+	return w.tw.Write(b)
+}
+
+// Close finishes the archive and saves its index to indexPath. It does not
+// close the underlying writer.
+func (w *TarixWriter) Close(indexPath string) error {
+	if err := w.tw.Close(); err != nil {
+		return fmt.Errorf("failed to close tar writer: %w", err)
+	}
+	w.Index.Format = archiveFormat(w.formats)
+	w.Index.Trailer = true
+	w.Index.ArchiveSize = w.cw.n
+	w.Index.EntryCount = w.entries
+	return WriteTarIndex(w.Index, indexPath)
+}
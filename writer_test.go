@@ -0,0 +1,233 @@
+package tarix
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTarixWriter(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "written.tar")
+	tarIndexPath := filepath.Join(dir, "written.tar.index.json")
+
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	defer tarFile.Close()
+
+	files := []struct{ name, content string }{
+		{"a.txt", "first"},
+		{"dir/b.txt", string(make([]byte, 1000))},
+		{"dir/c.txt", "third"},
+	}
+
+	w := NewTarixWriter(tarFile)
+	if err := w.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatalf("Failed to write dir header: %v", err)
+	}
+	for _, f := range files {
+		if err := w.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f.content))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			t.Fatalf("Failed to write data: %v", err)
+		}
+	}
+	if err := w.Close(tarIndexPath); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	// The single-pass index must match one built by scanning the archive
+	scannedIndexPath := filepath.Join(dir, "scanned.index.json")
+	if err := CreateTarIndexWithOptions(tarFilePath, scannedIndexPath, IndexOptions{}); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	written, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read written index: %v", err)
+	}
+	scanned, err := ReadTarIndex(scannedIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read scanned index: %v", err)
+	}
+	if len(written.Files) != len(scanned.Files) {
+		t.Fatalf("Expected %d entries, got %d", len(scanned.Files), len(written.Files))
+	}
+	for key, fi := range scanned.Files {
+		if written.Files[key] != fi {
+			t.Errorf("Entry %s differs: written %+v, scanned %+v", key, written.Files[key], fi)
+		}
+	}
+	if written.ArchiveSize != scanned.ArchiveSize || written.EntryCount != scanned.EntryCount {
+		t.Errorf("Expected %d entries in %d bytes, got %d in %d", scanned.EntryCount, scanned.ArchiveSize, written.EntryCount, written.ArchiveSize)
+	}
+
+	th, err := NewTarixHandle(tarFilePath, tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to open handle: %v", err)
+	}
+	defer th.Close()
+	for _, f := range files {
+		data, err := th.ExtractBytesOfFile(f.name)
+		if err != nil {
+			t.Fatalf("Failed to extract %s: %v", f.name, err)
+		}
+		if string(data) != f.content {
+			t.Errorf("Unexpected content of %s", f.name)
+		}
+	}
+}
+
+func TestTarixWriterUntypedHeader(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "written.tar")
+	tarIndexPath := filepath.Join(dir, "written.tar.index.json")
+
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	defer tarFile.Close()
+
+	// Headers without a Typeflag, as in the README, are regular files unless
+	// their names end in a slash
+	w := NewTarixWriter(tarFile)
+	if err := w.WriteHeader(&tar.Header{Name: "dir/", Mode: 0755}); err != nil {
+		t.Fatalf("Failed to write dir header: %v", err)
+	}
+	data := []byte("untyped")
+	if err := w.WriteHeader(&tar.Header{Name: "dir/a.txt", Mode: 0644, Size: int64(len(data))}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+	if err := w.Close(tarIndexPath); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if len(w.Index.Files) != 1 {
+		t.Fatalf("Expected 1 indexed file, got %d", len(w.Index.Files))
+	}
+
+	outputPath := filepath.Join(dir, "a.txt")
+	if err := ExtractFileFromTar(tarFilePath, tarIndexPath, "dir/a.txt", outputPath); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if got, err := os.ReadFile(outputPath); err != nil || string(got) != string(data) {
+		t.Errorf("Unexpected content %q: %v", got, err)
+	}
+}
+
+func TestTarixWriterKeyScheme(t *testing.T) {
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "written.tar")
+	tarIndexPath := filepath.Join(dir, "written.tar.index.json")
+
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	defer tarFile.Close()
+
+	w, err := NewTarixWriterWithOptions(tarFile, WriterOptions{KeyScheme: SHA256KeyScheme})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	data := []byte("hashed")
+	if err := w.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0644, Size: int64(len(data))}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+	if err := w.Close(tarIndexPath); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	index, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if index.KeyScheme != SHA256KeyScheme.Name {
+		t.Errorf("Expected key scheme %q, got %q", SHA256KeyScheme.Name, index.KeyScheme)
+	}
+	if _, ok := index.Files[SHA256KeyScheme.Func("a.txt")]; !ok {
+		t.Errorf("Expected a SHA-256 key, got %v", index.Files)
+	}
+	outputPath := filepath.Join(dir, "a.txt")
+	if err := ExtractFileFromTar(tarFilePath, tarIndexPath, "a.txt", outputPath); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if got, err := os.ReadFile(outputPath); err != nil || string(got) != string(data) {
+		t.Errorf("Unexpected content %q: %v", got, err)
+	}
+
+	if _, err := NewTarixWriterWithOptions(tarFile, WriterOptions{KeyScheme: KeyScheme{Name: "nope"}}); err == nil {
+		t.Error("Expected an unknown key scheme to be rejected")
+	}
+}
+
+func TestTarixWriterNormalize(t *testing.T) {
+	const composed, decomposed = "caf\u00e9.txt", "cafe\u0301.txt"
+	dir := t.TempDir()
+	tarFilePath := filepath.Join(dir, "written.tar")
+	tarIndexPath := filepath.Join(dir, "written.tar.index.json")
+
+	tarFile, err := os.Create(tarFilePath)
+	if err != nil {
+		t.Fatalf("Failed to create TAR: %v", err)
+	}
+	defer tarFile.Close()
+
+	w := NewTarixWriter(tarFile)
+	data := []byte("bonjour")
+	if err := w.WriteHeader(&tar.Header{Name: decomposed, Mode: 0644, Size: int64(len(data))}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+	if err := w.Close(tarIndexPath); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	// NFC is the default, as for a scanned index, so both have the same keys
+	scannedIndexPath := filepath.Join(dir, "scanned.index.json")
+	if err := CreateTarIndex(tarFilePath, scannedIndexPath); err != nil {
+		t.Fatalf("Failed to create TAR index: %v", err)
+	}
+	written, err := ReadTarIndex(tarIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read written index: %v", err)
+	}
+	scanned, err := ReadTarIndex(scannedIndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read scanned index: %v", err)
+	}
+	if written.Normalize != NFC {
+		t.Errorf("Expected the written index to record NFC, got %q", written.Normalize)
+	}
+	for key := range scanned.Files {
+		if _, ok := written.Files[key]; !ok {
+			t.Errorf("Expected key %s of the scanned index in the written one", key)
+		}
+	}
+	if !written.Contains(composed) {
+		t.Error("Expected the composed name to be found")
+	}
+
+	raw, err := NewTarixWriterWithOptions(io.Discard, WriterOptions{Normalize: NormNone})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if raw.Index.Normalize.normalizes() {
+		t.Errorf("Expected no normalization, got %q", raw.Index.Normalize)
+	}
+	if _, err := NewTarixWriterWithOptions(io.Discard, WriterOptions{Normalize: "NFKC"}); err == nil {
+		t.Error("Expected NFKC to be rejected")
+	}
+}